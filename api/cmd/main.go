@@ -5,19 +5,17 @@ import (
 
 	"context"
 
+	"github.com/cameronsralla/culdechat/config"
 	"github.com/cameronsralla/culdechat/connectors/postgres"
+	"github.com/cameronsralla/culdechat/middleware"
 	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/models/memory"
 	"github.com/cameronsralla/culdechat/routes"
+	"github.com/cameronsralla/culdechat/services"
 	"github.com/cameronsralla/culdechat/utils"
 )
 
 func main() {
-	// Load .env from repository root before anything else
-	if _, err := utils.LoadRootDotEnv(); err != nil {
-		// Non-fatal: continue even if no .env was found
-		log.Printf("warning: %v", err)
-	}
-
 	_, closer, err := utils.Init()
 	if err != nil {
 		log.Fatalf("logger init failed: %v", err)
@@ -28,18 +26,36 @@ func main() {
 		}
 	}()
 
+	// Resolve configuration (env, .env, optional CONFIG_FILE) and fail fast on invalid values
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
 	// Initialize Postgres connection pool and ensure core tables
 	ctx := context.Background()
-	if _, err := postgres.Initialize(ctx); err != nil {
+	pool, err := postgres.Connect(ctx, cfg.Database)
+	if err != nil {
 		log.Fatalf("postgres init failed: %v", err)
 	}
-	if err := models.EnsureUsersTable(ctx); err != nil {
-		log.Fatalf("ensure users table failed: %v", err)
+	defer pool.Close()
+	if err := models.EnsureSchema(ctx, pool); err != nil {
+		log.Fatalf("ensure schema failed: %v", err)
+	}
+
+	// Wire repositories and services explicitly
+	tokens := utils.NewTokenIssuer(cfg.JWT)
+	repos := models.NewPgRepos(pool)
+	if cfg.RateLimit.Store == config.RateLimitStoreMemory {
+		repos.RateLimits = memory.NewRateLimitRepo()
 	}
+	svcs := services.New(repos, tokens, cfg)
+	go svcs.Retention.Run(ctx)
+	limiter := middleware.NewRateLimiter(repos.RateLimits, cfg.RateLimit.Enabled)
 
-	router := routes.NewRouter()
+	router := routes.NewRouter(routes.Deps{Config: cfg, Tokens: tokens, Services: svcs, RateLimiter: limiter})
 
-	if err := router.Run(":8080"); err != nil {
+	if err := router.Run(cfg.Addr()); err != nil {
 		log.Fatalf("failed to start server: %v", err)
 	}
 }
@@ -4,16 +4,13 @@ import (
 	"context"
 	"log"
 
+	"github.com/cameronsralla/culdechat/config"
 	"github.com/cameronsralla/culdechat/connectors/postgres"
 	"github.com/cameronsralla/culdechat/models"
 	"github.com/cameronsralla/culdechat/utils"
 )
 
 func main() {
-	if _, err := utils.LoadRootDotEnv(); err != nil {
-		log.Printf("warning: %v", err)
-	}
-
 	_, closer, err := utils.Init()
 	if err != nil {
 		log.Fatalf("logger init failed: %v", err)
@@ -24,13 +21,20 @@ func main() {
 		}
 	}()
 
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
 	ctx := context.Background()
-	if _, err := postgres.Initialize(ctx); err != nil {
+	pool, err := postgres.Connect(ctx, cfg.Database)
+	if err != nil {
 		log.Fatalf("postgres init failed: %v", err)
 	}
+	defer pool.Close()
 
-	if err := models.EnsureUsersTable(ctx); err != nil {
-		log.Fatalf("ensure users table failed: %v", err)
+	if err := models.EnsureSchema(ctx, pool); err != nil {
+		log.Fatalf("ensure schema failed: %v", err)
 	}
 
 	utils.Infof("database migrations completed successfully")
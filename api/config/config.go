@@ -0,0 +1,367 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// InsecureDevJWTSecret is the signing secret used when JWT_SECRET is unset in development.
+const InsecureDevJWTSecret = "dev-insecure-secret-change-me"
+
+// minJWTSecretLength is the minimum secret size accepted outside development.
+const minJWTSecretLength = 32
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Rate limit stores. Postgres shares buckets across instances; memory is per process.
+const (
+	RateLimitStorePostgres = "postgres"
+	RateLimitStoreMemory   = "memory"
+)
+
+// Retention modes for accounts past their deletion grace period.
+const (
+	RetentionAnonymize = "anonymize"
+	RetentionDelete    = "delete"
+)
+
+// Config is the fully resolved application configuration.
+type Config struct {
+	Env          string             `yaml:"env"`
+	HTTP         HTTPConfig         `yaml:"http"`
+	Database     DatabaseConfig     `yaml:"database"`
+	JWT          utils.JWTConfig    `yaml:"jwt"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
+	Lockout      LockoutPolicy      `yaml:"lockout"`
+	Moderation   ModerationConfig   `yaml:"moderation"`
+	Access       AccessConfig       `yaml:"access"`
+	Retention    RetentionConfig    `yaml:"retention"`
+	Registration RegistrationConfig `yaml:"registration"`
+}
+
+// HTTPConfig holds settings for the HTTP listener.
+type HTTPConfig struct {
+	Port int `yaml:"port"`
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header names the client. Empty trusts none, so
+	// the client address is always the connection's peer.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// DatabaseConfig holds Postgres connection settings.
+type DatabaseConfig struct {
+	URL      string        `yaml:"url"`
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	Name     string        `yaml:"name"`
+	User     string        `yaml:"user"`
+	Password string        `yaml:"password"`
+	SSLMode  string        `yaml:"sslmode"`
+	MinConns int32         `yaml:"min_conns"`
+	MaxConns int32         `yaml:"max_conns"`
+	Timeout  time.Duration `yaml:"connect_timeout"`
+}
+
+// RateLimitConfig holds the per-route request budgets. Login and register are
+// keyed by client IP; post and comment creation by user.
+type RateLimitConfig struct {
+	Enabled  bool            `yaml:"enabled"`
+	Store    string          `yaml:"store"`
+	Login    RateLimitPolicy `yaml:"login"`
+	Register RateLimitPolicy `yaml:"register"`
+	Posts    RateLimitPolicy `yaml:"posts"`
+	Comments RateLimitPolicy `yaml:"comments"`
+}
+
+// RateLimitPolicy allows bursts of up to Requests, refilled evenly over Window.
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// LockoutPolicy locks an account for Base after Threshold consecutive failed
+// logins, doubling with each further failure up to Max. A zero Threshold disables lockout.
+type LockoutPolicy struct {
+	Threshold int           `yaml:"threshold"`
+	Base      time.Duration `yaml:"base"`
+	Max       time.Duration `yaml:"max"`
+}
+
+// ModerationConfig tunes the report queue.
+type ModerationConfig struct {
+	// AutoHideThreshold hides a post, comment or profile once this many distinct
+	// residents have unresolved reports against it. Zero disables auto-hiding.
+	AutoHideThreshold int `yaml:"auto_hide_threshold"`
+}
+
+// RetentionConfig controls what happens to accounts residents delete.
+type RetentionConfig struct {
+	// DeletionGrace is how long a deleted account can still be restored by logging in.
+	DeletionGrace time.Duration `yaml:"deletion_grace"`
+	// Mode is RetentionAnonymize (keep content, scrub the account) or
+	// RetentionDelete (remove the account and everything it posted).
+	Mode string `yaml:"mode"`
+	// Interval is how often the retention worker looks for accounts to purge.
+	Interval time.Duration `yaml:"interval"`
+	// ExportTTL is how long a finished data export can be downloaded.
+	ExportTTL time.Duration `yaml:"export_ttl"`
+	// ExportTimeout is how long an export may stay pending before it is
+	// presumed lost, e.g. to a restart mid-build, and marked failed.
+	ExportTimeout time.Duration `yaml:"export_timeout"`
+}
+
+// RegistrationConfig controls how new residents sign up.
+type RegistrationConfig struct {
+	// RequireInvite makes registration need an admin-issued invite code.
+	RequireInvite bool `yaml:"require_invite"`
+	// InviteLinkBase is prefixed to invite codes to form the link encoded in
+	// invite QR codes, e.g. "https://chat.example.com/join/".
+	InviteLinkBase string `yaml:"invite_link_base"`
+	// BootstrapAdminEmail may register without an invite while the default
+	// community has no admin, and becomes its admin (the first operator).
+	BootstrapAdminEmail string `yaml:"bootstrap_admin_email"`
+}
+
+// AccessConfig controls which API routes may be called without a session.
+type AccessConfig struct {
+	// PublicRoutes lists the routes anonymous callers may use, each written as
+	// "METHOD /api/path" with the route's registered pattern. Board content
+	// reads on the list only return boards marked public_read.
+	PublicRoutes []string `yaml:"public_routes"`
+}
+
+// DefaultPublicRoutes are the routes open without a session unless the
+// configuration replaces the list.
+var DefaultPublicRoutes = []string{
+	"GET /api/health",
+	"GET /api/communities/current",
+	"POST /api/auth/register",
+	"POST /api/auth/login",
+	"GET /api/boards",
+	"GET /api/posts/board/:board_id",
+	"GET /api/posts/bulletins",
+	"GET /api/comments/post/:post_id",
+}
+
+// Default returns the built-in defaults before any file or env overrides.
+func Default() Config {
+	return Config{
+		Env:  EnvProduction,
+		HTTP: HTTPConfig{Port: 8080},
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			Name:     "culdechat",
+			User:     "postgres",
+			SSLMode:  "disable",
+			MinConns: 0,
+			MaxConns: 10,
+			Timeout:  5 * time.Second,
+		},
+		JWT: utils.JWTConfig{
+			Issuer:         "culdechat",
+			AccessTokenTTL: time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  true,
+			Store:    RateLimitStorePostgres,
+			Login:    RateLimitPolicy{Requests: 10, Window: time.Minute},
+			Register: RateLimitPolicy{Requests: 5, Window: time.Hour},
+			Posts:    RateLimitPolicy{Requests: 20, Window: 10 * time.Minute},
+			Comments: RateLimitPolicy{Requests: 60, Window: 10 * time.Minute},
+		},
+		Lockout: LockoutPolicy{
+			Threshold: 5,
+			Base:      time.Minute,
+			Max:       time.Hour,
+		},
+		Moderation: ModerationConfig{AutoHideThreshold: 3},
+		Access:     AccessConfig{PublicRoutes: append([]string(nil), DefaultPublicRoutes...)},
+		Retention: RetentionConfig{
+			DeletionGrace: 30 * 24 * time.Hour,
+			Mode:          RetentionAnonymize,
+			Interval:      time.Hour,
+			ExportTTL:     7 * 24 * time.Hour,
+			ExportTimeout: time.Hour,
+		},
+		Registration: RegistrationConfig{
+			RequireInvite:  true,
+			InviteLinkBase: "culdechat://join/",
+		},
+	}
+}
+
+// Load resolves configuration once at startup. Sources are applied in order of
+// increasing precedence: built-in defaults, the YAML file named by CONFIG_FILE
+// (if any), then process environment (including the repository root .env).
+func Load() (*Config, error) {
+	if _, err := utils.LoadRootDotEnv(); err != nil {
+		// Non-fatal: continue even if no .env was found
+		utils.Warnf("%v", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// IsDevelopment reports whether the app runs in development mode.
+func (c *Config) IsDevelopment() bool { return c.Env == EnvDevelopment }
+
+// Addr returns the listen address for the HTTP server.
+func (c *Config) Addr() string { return fmt.Sprintf(":%d", c.HTTP.Port) }
+
+// Validate checks the configuration for inconsistent or insecure values. In
+// development an empty JWT secret is replaced by the insecure default.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Env {
+	case EnvDevelopment, EnvProduction:
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+
+	if c.HTTP.Port <= 0 || c.HTTP.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.HTTP.Port))
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("http.trusted_proxies entry %q must be an IP address or CIDR range", proxy))
+		}
+	}
+
+	if c.Database.URL == "" {
+		if c.Database.Host == "" || c.Database.Name == "" || c.Database.User == "" {
+			errs = append(errs, errors.New("PGHOST, PGDATABASE and PGUSER are required when DATABASE_URL is not set"))
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("PGPORT must be between 1 and 65535, got %d", c.Database.Port))
+		}
+	}
+	if c.Database.MaxConns <= 0 {
+		errs = append(errs, errors.New("PGPOOL_MAX_CONNS must be positive"))
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		errs = append(errs, errors.New("PGPOOL_MIN_CONNS must be between 0 and PGPOOL_MAX_CONNS"))
+	}
+	if c.Database.Timeout <= 0 {
+		errs = append(errs, errors.New("PGCONNECT_TIMEOUT must be positive"))
+	}
+
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("JWT_ISSUER must not be empty"))
+	}
+	if c.JWT.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("JWT_ACCESS_TTL_SECONDS must be positive"))
+	}
+	if c.IsDevelopment() {
+		if c.JWT.Secret == "" {
+			utils.Warnf("JWT_SECRET not set; using insecure default for development")
+			c.JWT.Secret = InsecureDevJWTSecret
+		}
+	} else {
+		switch {
+		case c.JWT.Secret == "" || c.JWT.Secret == InsecureDevJWTSecret:
+			errs = append(errs, errors.New("JWT_SECRET must be set outside development"))
+		case len(c.JWT.Secret) < minJWTSecretLength:
+			errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d bytes outside development", minJWTSecretLength))
+		}
+	}
+
+	switch c.RateLimit.Store {
+	case RateLimitStorePostgres, RateLimitStoreMemory:
+	default:
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE must be %q or %q, got %q", RateLimitStorePostgres, RateLimitStoreMemory, c.RateLimit.Store))
+	}
+	for name, p := range map[string]RateLimitPolicy{
+		"login": c.RateLimit.Login, "register": c.RateLimit.Register,
+		"posts": c.RateLimit.Posts, "comments": c.RateLimit.Comments,
+	} {
+		if p.Requests <= 0 || p.Window <= 0 {
+			errs = append(errs, fmt.Errorf("rate_limit.%s needs positive requests and window", name))
+		}
+	}
+
+	if c.Lockout.Threshold < 0 {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT_THRESHOLD must not be negative"))
+	}
+	if c.Lockout.Threshold > 0 && (c.Lockout.Base <= 0 || c.Lockout.Max < c.Lockout.Base) {
+		errs = append(errs, errors.New("lockout base must be positive and no greater than lockout max"))
+	}
+
+	if c.Moderation.AutoHideThreshold < 0 {
+		errs = append(errs, errors.New("MODERATION_AUTO_HIDE_THRESHOLD must not be negative"))
+	}
+
+	switch c.Retention.Mode {
+	case RetentionAnonymize, RetentionDelete:
+	default:
+		errs = append(errs, fmt.Errorf("RETENTION_MODE must be %q or %q, got %q", RetentionAnonymize, RetentionDelete, c.Retention.Mode))
+	}
+	if c.Retention.DeletionGrace < 0 {
+		errs = append(errs, errors.New("ACCOUNT_DELETION_GRACE_DAYS must not be negative"))
+	}
+	if c.Retention.Interval <= 0 {
+		errs = append(errs, errors.New("retention.interval must be positive"))
+	}
+	if c.Retention.ExportTTL <= 0 {
+		errs = append(errs, errors.New("retention.export_ttl must be positive"))
+	}
+	if c.Retention.ExportTimeout <= 0 {
+		errs = append(errs, errors.New("retention.export_timeout must be positive"))
+	}
+
+	if c.Registration.InviteLinkBase == "" {
+		errs = append(errs, errors.New("INVITE_LINK_BASE must not be empty"))
+	}
+
+	for _, route := range c.Access.PublicRoutes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || !validMethods[method] || !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("access.public_routes entry %q must look like \"GET /api/path\"", route))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var validMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envReader overlays environment variables onto config fields, collecting
+// parse errors rather than silently falling back to defaults.
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) string(key string, dst *string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func (r *envReader) bool(key string, dst *bool) {
+	if v := os.Getenv(key); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: invalid boolean %q", key, v))
+			return
+		}
+		*dst = b
+	}
+}
+
+func (r *envReader) int(key string, dst *int) {
+	if v := os.Getenv(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (r *envReader) int32(key string, dst *int32) {
+	if v := os.Getenv(key); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+			return
+		}
+		*dst = int32(n)
+	}
+}
+
+func (r *envReader) seconds(key string, dst *time.Duration) {
+	if v := os.Getenv(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: invalid number of seconds %q", key, v))
+			return
+		}
+		*dst = time.Duration(n) * time.Second
+	}
+}
+
+func (r *envReader) days(key string, dst *time.Duration) {
+	if v := os.Getenv(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s: invalid number of days %q", key, v))
+			return
+		}
+		*dst = time.Duration(n) * 24 * time.Hour
+	}
+}
+
+// list reads a comma-separated list, trimming blanks around each item.
+func (r *envReader) list(key string, dst *[]string) {
+	if v := os.Getenv(key); v != "" {
+		var out []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+		*dst = out
+	}
+}
+
+func applyEnv(cfg *Config) error {
+	r := &envReader{}
+
+	r.string("APP_ENV", &cfg.Env)
+	r.int("PORT", &cfg.HTTP.Port)
+	r.list("TRUSTED_PROXIES", &cfg.HTTP.TrustedProxies)
+
+	r.string("DATABASE_URL", &cfg.Database.URL)
+	r.string("PGHOST", &cfg.Database.Host)
+	r.int("PGPORT", &cfg.Database.Port)
+	r.string("PGDATABASE", &cfg.Database.Name)
+	r.string("PGUSER", &cfg.Database.User)
+	r.string("PGPASSWORD", &cfg.Database.Password)
+	r.string("PGSSLMODE", &cfg.Database.SSLMode)
+	r.int32("PGPOOL_MIN_CONNS", &cfg.Database.MinConns)
+	r.int32("PGPOOL_MAX_CONNS", &cfg.Database.MaxConns)
+	r.seconds("PGCONNECT_TIMEOUT", &cfg.Database.Timeout)
+
+	r.string("JWT_SECRET", &cfg.JWT.Secret)
+	r.string("JWT_ISSUER", &cfg.JWT.Issuer)
+	r.seconds("JWT_ACCESS_TTL_SECONDS", &cfg.JWT.AccessTokenTTL)
+
+	r.bool("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	r.string("RATE_LIMIT_STORE", &cfg.RateLimit.Store)
+	r.int("LOGIN_LOCKOUT_THRESHOLD", &cfg.Lockout.Threshold)
+	r.int("MODERATION_AUTO_HIDE_THRESHOLD", &cfg.Moderation.AutoHideThreshold)
+	r.list("PUBLIC_ROUTES", &cfg.Access.PublicRoutes)
+	r.days("ACCOUNT_DELETION_GRACE_DAYS", &cfg.Retention.DeletionGrace)
+	r.string("RETENTION_MODE", &cfg.Retention.Mode)
+	r.bool("REGISTRATION_REQUIRE_INVITE", &cfg.Registration.RequireInvite)
+	r.string("INVITE_LINK_BASE", &cfg.Registration.InviteLinkBase)
+	r.string("BOOTSTRAP_ADMIN_EMAIL", &cfg.Registration.BootstrapAdminEmail)
+
+	return errors.Join(r.errs...)
+}
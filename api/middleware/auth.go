@@ -1,30 +1,120 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
 	"strings"
 
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/services"
 	"github.com/cameronsralla/culdechat/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-// AuthRequired validates Authorization: Bearer <token> and sets user claims in context.
-func AuthRequired() gin.HandlerFunc {
+// AccountChecker reports whether a token's account may still use the API.
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, userID uuid.UUID, tokenVersion int) error
+}
+
+// CommunityResolver looks up the community named by a slug.
+type CommunityResolver interface {
+	ResolveCommunity(ctx context.Context, slug string) (uuid.UUID, error)
+}
+
+// CommunityHeader names the community of an anonymous request by slug.
+// Authenticated requests always use the community in their token.
+const CommunityHeader = "X-Community"
+
+// RequireSession validates Authorization: Bearer <token> on every route it
+// guards, checks the account is still active and sets user claims in context.
+// Routes in public, written "METHOD /path" with the registered pattern, may
+// also be called without a token; a bad token is rejected everywhere.
+// Every request is scoped to one community (see models.WithCommunity).
+func RequireSession(tokens *utils.TokenIssuer, accounts AccountChecker, communities CommunityResolver, public []string) gin.HandlerFunc {
+	open := make(map[string]bool, len(public))
+	for _, route := range public {
+		open[route] = true
+	}
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
+		if header == "" && open[c.Request.Method+" "+c.FullPath()] {
+			if !scopeAnonymous(c, communities) {
+				return
+			}
+			c.Next()
+			return
+		}
 		if header == "" || !strings.HasPrefix(header, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid authorization header"})
+			AbortWithError(c, services.UnauthorizedError("missing or invalid authorization header"))
 			return
 		}
-		token := strings.TrimPrefix(header, "Bearer ")
-		claims, err := utils.ParseAndValidateToken(token)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		if !authenticate(c, tokens, accounts, strings.TrimPrefix(header, "Bearer ")) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireUser rejects anonymous requests on routes that act for a user, even
+// when RequireSession's allow-list lets them through.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_id") == "" {
+			AbortWithError(c, services.UnauthorizedError("missing or invalid authorization header"))
 			return
 		}
-		// Stash claims for handlers
-		c.Set("user_id", claims.UserID)
-		c.Set("unit", claims.Unit)
 		c.Next()
 	}
 }
+
+// scopeAnonymous scopes an anonymous request to the community named by
+// CommunityHeader, or the default community without one.
+func scopeAnonymous(c *gin.Context, communities CommunityResolver) bool {
+	communityID := models.DefaultCommunityID
+	if slug := strings.TrimSpace(c.GetHeader(CommunityHeader)); slug != "" {
+		id, err := communities.ResolveCommunity(c.Request.Context(), slug)
+		if err != nil {
+			AbortWithError(c, err)
+			return false
+		}
+		communityID = id
+	}
+	setCommunity(c, communityID)
+	return true
+}
+
+// setCommunity limits the repository calls made for the request to communityID.
+func setCommunity(c *gin.Context, communityID uuid.UUID) {
+	c.Request = c.Request.WithContext(models.WithCommunity(c.Request.Context(), communityID))
+	c.Set("community_id", communityID.String())
+}
+
+// authenticate validates token and stashes its claims, aborting on failure.
+func authenticate(c *gin.Context, tokens *utils.TokenIssuer, accounts AccountChecker, token string) bool {
+	claims, err := tokens.ParseAndValidateToken(token)
+	if err != nil {
+		AbortWithError(c, services.UnauthorizedError("invalid token"))
+		return false
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		AbortWithError(c, services.UnauthorizedError("invalid token"))
+		return false
+	}
+	communityID := models.DefaultCommunityID
+	if claims.CommunityID != "" {
+		if communityID, err = uuid.Parse(claims.CommunityID); err != nil {
+			AbortWithError(c, services.UnauthorizedError("invalid token"))
+			return false
+		}
+	}
+	setCommunity(c, communityID)
+	if err := accounts.CheckAccount(c.Request.Context(), userID, claims.Version); err != nil {
+		AbortWithError(c, err)
+		return false
+	}
+	// Stash claims for handlers
+	c.Set("user_id", claims.UserID)
+	c.Set("unit", claims.Unit)
+	return true
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse is the body of every non-2xx API response.
+type ErrorResponse struct {
+	Code      services.ErrorCode `json:"code"`
+	Message   string             `json:"message"`
+	Details   map[string]string  `json:"details"`
+	RequestID string             `json:"request_id"`
+}
+
+var statusByCode = map[services.ErrorCode]int{
+	services.CodeValidation:   http.StatusBadRequest,
+	services.CodeUnauthorized: http.StatusUnauthorized,
+	services.CodeForbidden:    http.StatusForbidden,
+	services.CodeNotFound:     http.StatusNotFound,
+	services.CodeConflict:     http.StatusConflict,
+	services.CodeRateLimited:  http.StatusTooManyRequests,
+	services.CodeInternal:     http.StatusInternalServerError,
+}
+
+// ErrorHandler renders the last error attached with c.Error as an ErrorResponse.
+// Domain errors (*services.Error) map to their HTTP status; anything else is
+// logged and reported as an opaque internal error so raw DB errors never leak.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+
+		var domainErr *services.Error
+		if !errors.As(err, &domainErr) {
+			utils.Errorf("request_id=%s unhandled error on %s %s: %v", GetRequestID(c), c.Request.Method, c.Request.URL.Path, err)
+			domainErr = &services.Error{Code: services.CodeInternal, Message: "internal server error"}
+		}
+		writeError(c, domainErr)
+	}
+}
+
+// Recovery converts panics into an internal error envelope.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, rec any) {
+		utils.Errorf("request_id=%s panic on %s %s: %v", GetRequestID(c), c.Request.Method, c.Request.URL.Path, rec)
+		writeError(c, &services.Error{Code: services.CodeInternal, Message: "internal server error"})
+		c.Abort()
+	})
+}
+
+// AbortWithError attaches err and stops the handler chain; ErrorHandler renders it.
+func AbortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+func writeError(c *gin.Context, e *services.Error) {
+	status, ok := statusByCode[e.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+	c.JSON(status, ErrorResponse{
+		Code:      e.Code,
+		Message:   e.Message,
+		Details:   details,
+		RequestID: GetRequestID(c),
+	})
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// pruneInterval bounds how often idle buckets are cleaned up.
+const pruneInterval = 10 * time.Minute
+
+// KeyFunc identifies who a request is charged to.
+type KeyFunc func(c *gin.Context) string
+
+// ClientIPKey charges requests to the (IPv4-normalized) client address.
+func ClientIPKey(c *gin.Context) string {
+	return "ip:" + utils.NormalizeToIPv4(c.ClientIP())
+}
+
+// UserKey charges requests to the authenticated user, falling back to the
+// client address. Use it after RequireUser.
+func UserKey(c *gin.Context) string {
+	if id := c.GetString("user_id"); id != "" {
+		return "user:" + id
+	}
+	return ClientIPKey(c)
+}
+
+// RateLimiter enforces token bucket policies backed by a RateLimitRepo. A nil
+// or disabled RateLimiter lets every request through.
+type RateLimiter struct {
+	store   models.RateLimitRepo
+	enabled bool
+
+	mu        sync.Mutex
+	maxWindow time.Duration
+	lastPrune time.Time
+}
+
+// NewRateLimiter returns a RateLimiter storing buckets in store.
+func NewRateLimiter(store models.RateLimitRepo, enabled bool) *RateLimiter {
+	return &RateLimiter{store: store, enabled: enabled, lastPrune: time.Now()}
+}
+
+// Limit returns middleware that allows policy.Requests per policy.Window for
+// each key, named so that different routes keep separate buckets. Rejected
+// requests get a rate_limited error with Retry-After.
+func (l *RateLimiter) Limit(name string, policy config.RateLimitPolicy, key KeyFunc) gin.HandlerFunc {
+	if l == nil || !l.enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	l.mu.Lock()
+	if policy.Window > l.maxWindow {
+		l.maxWindow = policy.Window
+	}
+	l.mu.Unlock()
+
+	capacity := float64(policy.Requests)
+	perSecond := capacity / policy.Window.Seconds()
+
+	return func(c *gin.Context) {
+		l.maybePrune()
+
+		res, err := l.store.Take(c.Request.Context(), name+":"+key(c), capacity, perSecond)
+		if err != nil {
+			// Fail open: an unavailable limiter should not take the API down with it.
+			utils.Errorf("request_id=%s rate limit %s unavailable: %v", GetRequestID(c), name, err)
+			c.Next()
+			return
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(policy.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(res.Tokens)))))
+		if !res.Allowed {
+			wait := time.Duration((1 - res.Tokens) / perSecond * float64(time.Second))
+			AbortWithError(c, services.RateLimitedError("too many requests; try again later", wait))
+			return
+		}
+		c.Next()
+	}
+}
+
+// maybePrune deletes idle buckets in the background at most once per pruneInterval.
+// A bucket idle for longer than the longest window is full, so dropping it is harmless.
+func (l *RateLimiter) maybePrune() {
+	l.mu.Lock()
+	if time.Since(l.lastPrune) < pruneInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPrune = time.Now()
+	idle := l.maxWindow
+	l.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := l.store.Prune(ctx, idle); err != nil {
+			utils.Warnf("prune rate limit buckets: %v", err)
+		}
+	}()
+}
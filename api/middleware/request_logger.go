@@ -23,6 +23,6 @@ func RequestLogger() gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		utils.Infof("%s %s | status=%d ip=%s ua=%q latency=%s", method, path, status, clientIP, userAgent, latency)
+		utils.Infof("%s %s | status=%d ip=%s ua=%q latency=%s request_id=%s", method, path, status, clientIP, userAgent, latency, GetRequestID(c))
 	}
 }
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AccountAction is one suspension, ban or reinstatement applied to a user.
+type AccountAction struct {
+	ID      uuid.UUID
+	UserID  uuid.UUID
+	ActorID *uuid.UUID
+	// Action is "suspend", "ban" or "reinstate".
+	Action    string
+	Reason    string
+	Until     *time.Time
+	CreatedAt time.Time
+}
+
+// EnsureAccountActionsTable creates the account_actions history table.
+func EnsureAccountActionsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS account_actions (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL,
+	actor_id UUID NULL,
+	action VARCHAR NOT NULL,
+	reason TEXT NOT NULL,
+	until TIMESTAMPTZ NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	CONSTRAINT fk_account_actions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_account_actions_actor FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_actions_user ON account_actions (user_id, created_at DESC);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure account_actions table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgAccountActionRepo struct {
+	db DBTX
+}
+
+// NewPgAccountActionRepo returns a Postgres-backed AccountActionRepo.
+func NewPgAccountActionRepo(db DBTX) AccountActionRepo {
+	return &pgAccountActionRepo{db: db}
+}
+
+// Insert appends an entry to a user's account history. It returns
+// ErrInvalidReference when the user is not in the context's community.
+func (r *pgAccountActionRepo) Insert(ctx context.Context, a *AccountAction) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	q := `
+INSERT INTO account_actions (id, user_id, actor_id, action, reason, until)
+SELECT $1::uuid, $2::uuid, $3::uuid, $4::varchar, $5::text, $6::timestamptz
+WHERE ` + userInCommunitySQL("$2::uuid", "$7") + `
+RETURNING created_at;
+`
+	err := r.db.QueryRow(ctx, q, a.ID, a.UserID, a.ActorID, a.Action, a.Reason, a.Until, communityArg(ctx)).Scan(&a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+// ListByUser returns a user's account history, newest first.
+func (r *pgAccountActionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]AccountAction, error) {
+	q := `
+SELECT id, user_id, actor_id, action, reason, until, created_at
+FROM account_actions WHERE user_id = $1 AND ` + userInCommunitySQL("user_id", "$2") + `
+ORDER BY created_at DESC;
+`
+	rows, err := r.db.Query(ctx, q, userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []AccountAction
+	for rows.Next() {
+		var a AccountAction
+		if err := rows.Scan(&a.ID, &a.UserID, &a.ActorID, &a.Action, &a.Reason, &a.Until, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// AuditEvent records one privileged action. Events are never updated or deleted.
+type AuditEvent struct {
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	// ActorID is nil for actions the system takes on its own, e.g. auto-hiding.
+	ActorID    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   *uuid.UUID
+	// Details holds action-specific context such as a reason or the new value.
+	Details   map[string]string
+	CreatedAt time.Time
+}
+
+// AuditFilter narrows an audit log query. Zero values match everything;
+// From is inclusive and To exclusive.
+type AuditFilter struct {
+	ActorID    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+}
+
+// EnsureAuditTable creates the audit_events table. Actors are not foreign keys
+// so the log survives account deletion, and a trigger rejects updates and deletes.
+func EnsureAuditTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS audit_events (
+	id UUID PRIMARY KEY,
+	actor_id UUID NULL,
+	action VARCHAR NOT NULL,
+	target_type VARCHAR NOT NULL,
+	target_id UUID NULL,
+	details JSONB NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS community_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_community ON audit_events (community_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_type, target_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_events_append_only ON audit_events;
+CREATE TRIGGER trg_audit_events_append_only
+	BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure audit_events table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgAuditRepo struct {
+	db DBTX
+}
+
+// NewPgAuditRepo returns a Postgres-backed AuditRepo.
+func NewPgAuditRepo(db DBTX) AuditRepo {
+	return &pgAuditRepo{db: db}
+}
+
+// Insert appends an event to the context's community's audit log.
+func (r *pgAuditRepo) Insert(ctx context.Context, e *AuditEvent) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.Details == nil {
+		e.Details = map[string]string{}
+	}
+	e.CommunityID = insertCommunity(ctx)
+	const q = `
+INSERT INTO audit_events (id, community_id, actor_id, action, target_type, target_id, details)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING created_at;
+`
+	err := r.db.QueryRow(ctx, q, e.ID, e.CommunityID, e.ActorID, e.Action, e.TargetType, e.TargetID, e.Details).Scan(&e.CreatedAt)
+	return translateErr(err)
+}
+
+// List returns events matching f, newest first.
+func (r *pgAuditRepo) List(ctx context.Context, f AuditFilter) ([]AuditEvent, error) {
+	args := []any{communityArg(ctx)}
+	where := []string{communitySQL("community_id", "$1")}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorID != nil {
+		add("actor_id = $%d", *f.ActorID)
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.TargetType != "" {
+		add("target_type = $%d", f.TargetType)
+	}
+	if f.TargetID != nil {
+		add("target_id = $%d", *f.TargetID)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+
+	q := `SELECT id, community_id, actor_id, action, target_type, target_id, details, created_at FROM audit_events WHERE ` + strings.Join(where, " AND ")
+	q += ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		if err := rows.Scan(&e.ID, &e.CommunityID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Block kinds. A block hides both residents from each other; a mute only
+// hides the muted resident's posts from the one who muted them.
+const (
+	BlockKindBlock = "block"
+	BlockKindMute  = "mute"
+)
+
+// Block records that UserID blocked or muted TargetID. There is at most one
+// per pair; blocking a muted resident turns the mute into a block.
+type Block struct {
+	UserID    uuid.UUID
+	TargetID  uuid.UUID
+	Kind      string
+	CreatedAt time.Time
+}
+
+// EnsureBlocksTable creates the user_blocks table if it doesn't exist.
+func EnsureBlocksTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS user_blocks (
+	user_id UUID NOT NULL,
+	target_id UUID NOT NULL,
+	kind VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, target_id),
+	CONSTRAINT fk_user_blocks_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user_blocks_target FOREIGN KEY (target_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_target ON user_blocks (target_id);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure user_blocks table: %v", err)
+		return err
+	}
+	return nil
+}
+
+// hiddenFromSQL is a condition that is true when the author in authorCol is
+// hidden from the viewer in viewerParam: blocked by either side or, when
+// mutes is set, muted by the viewer. A NULL viewer hides nothing.
+func hiddenFromSQL(authorCol, viewerParam string, mutes bool) string {
+	kinds := `ub.kind = 'block'`
+	if mutes {
+		kinds = `TRUE`
+	}
+	return fmt.Sprintf(`EXISTS (
+	SELECT 1 FROM user_blocks ub
+	WHERE (ub.user_id = %[2]s AND ub.target_id = %[1]s AND %[3]s)
+	   OR (ub.user_id = %[1]s AND ub.target_id = %[2]s AND ub.kind = 'block')
+)`, authorCol, viewerParam, kinds)
+}
+
+type pgBlockRepo struct {
+	db DBTX
+}
+
+// NewPgBlockRepo returns a Postgres-backed BlockRepo.
+func NewPgBlockRepo(db DBTX) BlockRepo {
+	return &pgBlockRepo{db: db}
+}
+
+// Upsert records a block or mute, replacing any earlier one for the pair. It
+// returns ErrInvalidReference unless both residents are in the context's community.
+func (r *pgBlockRepo) Upsert(ctx context.Context, b *Block) error {
+	q := `
+INSERT INTO user_blocks (user_id, target_id, kind)
+SELECT $1::uuid, $2::uuid, $3::varchar
+WHERE ` + userInCommunitySQL("$1::uuid", "$4") + ` AND ` + userInCommunitySQL("$2::uuid", "$4") + `
+ON CONFLICT (user_id, target_id) DO UPDATE SET kind = EXCLUDED.kind, created_at = NOW()
+RETURNING created_at;
+`
+	err := r.db.QueryRow(ctx, q, b.UserID, b.TargetID, b.Kind, communityArg(ctx)).Scan(&b.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+// Delete removes the block or mute of kind and reports whether one existed.
+func (r *pgBlockRepo) Delete(ctx context.Context, userID, targetID uuid.UUID, kind string) (bool, error) {
+	q := `DELETE FROM user_blocks WHERE user_id = $1 AND target_id = $2 AND kind = $3 AND ` + userInCommunitySQL("user_id", "$4") + `;`
+	tag, err := r.db.Exec(ctx, q, userID, targetID, kind, communityArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListByUser returns the residents userID blocked or muted, newest first.
+func (r *pgBlockRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]Block, error) {
+	q := `
+SELECT user_id, target_id, kind, created_at
+FROM user_blocks WHERE user_id = $1 AND ` + userInCommunitySQL("user_id", "$2") + `
+ORDER BY created_at DESC, target_id ASC;
+`
+	rows, err := r.db.Query(ctx, q, userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Block
+	for rows.Next() {
+		var b Block
+		if err := rows.Scan(&b.UserID, &b.TargetID, &b.Kind, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
@@ -5,73 +5,113 @@ import (
 	"errors"
 	"time"
 
-	"github.com/cameronsralla/culdechat/connectors/postgres"
 	"github.com/cameronsralla/culdechat/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Board visibility levels. Public boards are open to every resident,
+// members-only boards to staff and approved members, and announcement boards
+// are readable by everyone but only staff may post to them.
+const (
+	BoardPublic       = "public"
+	BoardMembers      = "members"
+	BoardAnnouncement = "announcement"
 )
 
 // Board represents the boards table.
 type Board struct {
 	ID          uuid.UUID
+	CommunityID uuid.UUID
 	Name        string
 	Description *string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// SortOrder positions the board in the list; ties fall back to newest first.
+	SortOrder int
+	// Visibility is one of BoardPublic, BoardMembers or BoardAnnouncement.
+	Visibility string
+	// PublicRead lets anonymous callers read the board when board reads are on
+	// the public route allow-list. Members-only boards are never public.
+	PublicRead bool
+	// ArchivedAt makes the board read-only while set.
+	ArchivedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 // EnsureBoardsTable creates the boards table if it doesn't exist.
-func EnsureBoardsTable(ctx context.Context) error {
+func EnsureBoardsTable(ctx context.Context, db DBTX) error {
 	const ddl = `
 CREATE TABLE IF NOT EXISTS boards (
 	id UUID PRIMARY KEY,
-	name VARCHAR NOT NULL UNIQUE,
+	name VARCHAR NOT NULL,
 	description VARCHAR NULL,
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	visibility VARCHAR NOT NULL DEFAULT 'public',
+	public_read BOOLEAN NOT NULL DEFAULT FALSE,
+	archived_at TIMESTAMPTZ NULL,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ NULL;
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS visibility VARCHAR NOT NULL DEFAULT 'public';
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS public_read BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS community_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES communities(id);
+
+-- Board names are unique per community rather than globally.
+ALTER TABLE boards DROP CONSTRAINT IF EXISTS boards_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_boards_community_name ON boards (community_id, name);
 CREATE INDEX IF NOT EXISTS idx_boards_name ON boards (name);
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
-	}
-	if _, err := p.Exec(ctx, ddl); err != nil {
+	if _, err := db.Exec(ctx, ddl); err != nil {
 		utils.Errorf("failed to ensure boards table: %v", err)
 		return err
 	}
 	return nil
 }
 
-// InsertBoard inserts a new board.
-func InsertBoard(ctx context.Context, b *Board) error {
+type pgBoardRepo struct {
+	db DBTX
+}
+
+// NewPgBoardRepo returns a Postgres-backed BoardRepo.
+func NewPgBoardRepo(db DBTX) BoardRepo {
+	return &pgBoardRepo{db: db}
+}
+
+const boardColumns = `id, community_id, name, description, sort_order, visibility, public_read, archived_at, created_at, updated_at`
+
+func scanBoard(row pgx.Row) (*Board, error) {
+	var b Board
+	if err := row.Scan(&b.ID, &b.CommunityID, &b.Name, &b.Description, &b.SortOrder, &b.Visibility, &b.PublicRead, &b.ArchivedAt, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Insert inserts a new board into the context's community.
+func (r *pgBoardRepo) Insert(ctx context.Context, b *Board) error {
 	if b.ID == uuid.Nil {
 		b.ID = uuid.New()
 	}
+	b.CommunityID = insertCommunity(ctx)
 	const q = `
-INSERT INTO boards (id, name, description)
-VALUES ($1, $2, $3)
+INSERT INTO boards (id, community_id, name, description, sort_order, visibility, public_read)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING created_at, updated_at;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
+	if b.Visibility == "" {
+		b.Visibility = BoardPublic
 	}
-	return p.QueryRow(ctx, q, b.ID, b.Name, b.Description).Scan(&b.CreatedAt, &b.UpdatedAt)
+	err := r.db.QueryRow(ctx, q, b.ID, b.CommunityID, b.Name, b.Description, b.SortOrder, b.Visibility, b.PublicRead).Scan(&b.CreatedAt, &b.UpdatedAt)
+	return translateErr(err)
 }
 
-// ListBoards returns all boards, newest first.
-func ListBoards(ctx context.Context) ([]Board, error) {
-	const q = `
-SELECT id, name, description, created_at, updated_at
-FROM boards
-ORDER BY created_at DESC;
-`
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
-	}
-	rows, err := p.Query(ctx, q)
+// List returns all boards in sort order, newest first within the same position.
+func (r *pgBoardRepo) List(ctx context.Context) ([]Board, error) {
+	q := `SELECT ` + boardColumns + ` FROM boards WHERE ` + communitySQL("community_id", "$1") + ` ORDER BY sort_order ASC, created_at DESC, id DESC;`
+	rows, err := r.db.Query(ctx, q, communityArg(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -79,32 +119,50 @@ ORDER BY created_at DESC;
 
 	var out []Board
 	for rows.Next() {
-		var b Board
-		var desc *string
-		if err := rows.Scan(&b.ID, &b.Name, &desc, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		b, err := scanBoard(rows)
+		if err != nil {
 			return nil, err
 		}
-		b.Description = desc
-		out = append(out, b)
+		out = append(out, *b)
 	}
 	return out, rows.Err()
 }
 
-// GetBoardByID fetches a board by id.
-func GetBoardByID(ctx context.Context, id uuid.UUID) (*Board, error) {
-	const q = `
-SELECT id, name, description, created_at, updated_at
-FROM boards WHERE id = $1 LIMIT 1;
-`
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
+// GetByID fetches a board by id.
+func (r *pgBoardRepo) GetByID(ctx context.Context, id uuid.UUID) (*Board, error) {
+	q := `SELECT ` + boardColumns + ` FROM boards WHERE id = $1 AND ` + communitySQL("community_id", "$2") + ` LIMIT 1;`
+	b, err := scanBoard(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-	var b Board
-	var desc *string
-	if err := p.QueryRow(ctx, q, id).Scan(&b.ID, &b.Name, &desc, &b.CreatedAt, &b.UpdatedAt); err != nil {
-		return nil, err
-	}
-	b.Description = desc
-	return &b, nil
+	return b, err
+}
+
+// Update saves the name, description, visibility, public read and archive state and bumps updated_at.
+func (r *pgBoardRepo) Update(ctx context.Context, b *Board) error {
+	q := `
+UPDATE boards SET name = $2, description = $3, visibility = $4, public_read = $5, archived_at = $6, updated_at = NOW()
+WHERE id = $1 AND ` + communitySQL("community_id", "$7") + `
+RETURNING updated_at;
+`
+	err := r.db.QueryRow(ctx, q, b.ID, b.Name, b.Description, b.Visibility, b.PublicRead, b.ArchivedAt, communityArg(ctx)).Scan(&b.UpdatedAt)
+	return translateErr(err)
+}
+
+// Reorder gives the listed boards positions 1..n in the order given.
+func (r *pgBoardRepo) Reorder(ctx context.Context, ids []uuid.UUID) error {
+	q := `
+UPDATE boards SET sort_order = o.position, updated_at = NOW()
+FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, position)
+WHERE boards.id = o.id AND ` + communitySQL("boards.community_id", "$2") + `;
+`
+	_, err := r.db.Exec(ctx, q, ids, communityArg(ctx))
+	return err
+}
+
+// Delete removes a board; its remaining posts are deleted by cascade.
+func (r *pgBoardRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	q := `DELETE FROM boards WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	_, err := r.db.Exec(ctx, q, id, communityArg(ctx))
+	return err
 }
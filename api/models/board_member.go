@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Board membership statuses. Residents who ask to join a members-only board
+// wait as pending until staff approve them; staff invitations are approved at once.
+const (
+	MemberPending  = "pending"
+	MemberApproved = "approved"
+)
+
+// BoardMember grants a resident access to a members-only board.
+type BoardMember struct {
+	BoardID uuid.UUID
+	UserID  uuid.UUID
+	Status  string
+	// InvitedBy is the staff member who invited or approved the resident.
+	InvitedBy *uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// EnsureBoardMembersTable creates the board_members table.
+func EnsureBoardMembersTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS board_members (
+	board_id UUID NOT NULL,
+	user_id UUID NOT NULL,
+	status VARCHAR NOT NULL DEFAULT 'pending',
+	invited_by UUID NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (board_id, user_id),
+	CONSTRAINT fk_board_members_board FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+	CONSTRAINT fk_board_members_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_board_members_invited_by FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_board_members_user ON board_members (user_id);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure board_members table: %v", err)
+		return err
+	}
+	return nil
+}
+
+// readableBoardSQL is a condition that is true when the signed-in viewer in
+// viewerParam may read the board in boardCol. It mirrors the service layer's
+// BoardAccess: members-only boards need staff or an approved membership.
+func readableBoardSQL(boardCol, viewerParam string) string {
+	return fmt.Sprintf(`EXISTS (
+	SELECT 1 FROM boards rb
+	WHERE rb.id = %[1]s AND (
+		rb.visibility <> 'members'
+		OR EXISTS (SELECT 1 FROM users ru WHERE ru.id = %[2]s AND (ru.is_admin OR ru.is_moderator))
+		OR EXISTS (SELECT 1 FROM board_members rm WHERE rm.board_id = rb.id AND rm.user_id = %[2]s AND rm.status = 'approved')
+	)
+)`, boardCol, viewerParam)
+}
+
+type pgBoardMemberRepo struct {
+	db DBTX
+}
+
+// NewPgBoardMemberRepo returns a Postgres-backed BoardMemberRepo.
+func NewPgBoardMemberRepo(db DBTX) BoardMemberRepo {
+	return &pgBoardMemberRepo{db: db}
+}
+
+const boardMemberColumns = `board_id, user_id, status, invited_by, created_at, updated_at`
+
+func scanBoardMember(row pgx.Row) (*BoardMember, error) {
+	var m BoardMember
+	if err := row.Scan(&m.BoardID, &m.UserID, &m.Status, &m.InvitedBy, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Upsert adds a membership or replaces the status of an existing one. It
+// returns ErrInvalidReference unless both the board and the user are in the
+// context's community.
+func (r *pgBoardMemberRepo) Upsert(ctx context.Context, m *BoardMember) error {
+	q := `
+INSERT INTO board_members (board_id, user_id, status, invited_by)
+SELECT $1::uuid, $2::uuid, $3::varchar, $4::uuid
+WHERE ` + boardInCommunitySQL("$1::uuid", "$5") + ` AND ` + userInCommunitySQL("$2::uuid", "$5") + `
+ON CONFLICT (board_id, user_id)
+DO UPDATE SET status = EXCLUDED.status, invited_by = EXCLUDED.invited_by, updated_at = NOW()
+RETURNING created_at, updated_at;
+`
+	err := r.db.QueryRow(ctx, q, m.BoardID, m.UserID, m.Status, m.InvitedBy, communityArg(ctx)).Scan(&m.CreatedAt, &m.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+// Get fetches one membership on a board in the context's community.
+func (r *pgBoardMemberRepo) Get(ctx context.Context, boardID, userID uuid.UUID) (*BoardMember, error) {
+	q := `SELECT ` + boardMemberColumns + ` FROM board_members WHERE board_id = $1 AND user_id = $2 AND ` + boardInCommunitySQL("board_id", "$3") + `;`
+	m, err := scanBoardMember(r.db.QueryRow(ctx, q, boardID, userID, communityArg(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// ListByBoard returns a board's members and pending requests, oldest first.
+func (r *pgBoardMemberRepo) ListByBoard(ctx context.Context, boardID uuid.UUID) ([]BoardMember, error) {
+	q := `SELECT ` + boardMemberColumns + ` FROM board_members WHERE board_id = $1 AND ` + boardInCommunitySQL("board_id", "$2") + ` ORDER BY created_at ASC;`
+	return r.list(ctx, q, boardID, communityArg(ctx))
+}
+
+// ListByUser returns every membership and pending request a user has on
+// boards in the context's community.
+func (r *pgBoardMemberRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]BoardMember, error) {
+	q := `SELECT ` + boardMemberColumns + ` FROM board_members WHERE user_id = $1 AND ` + boardInCommunitySQL("board_id", "$2") + `;`
+	return r.list(ctx, q, userID, communityArg(ctx))
+}
+
+func (r *pgBoardMemberRepo) list(ctx context.Context, q string, args ...any) ([]BoardMember, error) {
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BoardMember
+	for rows.Next() {
+		m, err := scanBoardMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *m)
+	}
+	return out, rows.Err()
+}
+
+// Delete removes a membership or pending request.
+func (r *pgBoardMemberRepo) Delete(ctx context.Context, boardID, userID uuid.UUID) error {
+	q := `DELETE FROM board_members WHERE board_id = $1 AND user_id = $2 AND ` + boardInCommunitySQL("board_id", "$3") + `;`
+	_, err := r.db.Exec(ctx, q, boardID, userID, communityArg(ctx))
+	return err
+}
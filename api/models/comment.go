@@ -5,29 +5,32 @@ import (
 	"errors"
 	"time"
 
-	"github.com/cameronsralla/culdechat/connectors/postgres"
 	"github.com/cameronsralla/culdechat/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // Comment represents a comment on a post.
 type Comment struct {
-	ID        uuid.UUID
-	PostID    uuid.UUID
-	AuthorID  uuid.UUID
-	Content   string
+	ID       uuid.UUID
+	PostID   uuid.UUID
+	AuthorID uuid.UUID
+	Content  string
+	// HiddenAt is set when moderation hides the comment.
+	HiddenAt  *time.Time
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
 // EnsureCommentsTable creates the comments table if it doesn't exist.
-func EnsureCommentsTable(ctx context.Context) error {
+func EnsureCommentsTable(ctx context.Context, db DBTX) error {
 	const ddl = `
 CREATE TABLE IF NOT EXISTS comments (
 	id UUID PRIMARY KEY,
 	post_id UUID NOT NULL,
 	author_id UUID NOT NULL,
 	content TEXT NOT NULL,
+	hidden_at TIMESTAMPTZ NULL,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	CONSTRAINT fk_comments_post FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
@@ -35,47 +38,89 @@ CREATE TABLE IF NOT EXISTS comments (
 );
 
 CREATE INDEX IF NOT EXISTS idx_comments_post ON comments (post_id, created_at ASC);
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ NULL;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
-	}
-	if _, err := p.Exec(ctx, ddl); err != nil {
+	if _, err := db.Exec(ctx, ddl); err != nil {
 		utils.Errorf("failed to ensure comments table: %v", err)
 		return err
 	}
 	return nil
 }
 
-// InsertComment inserts a new comment.
-func InsertComment(ctx context.Context, cmt *Comment) error {
+type pgCommentRepo struct {
+	db DBTX
+}
+
+// NewPgCommentRepo returns a Postgres-backed CommentRepo.
+func NewPgCommentRepo(db DBTX) CommentRepo {
+	return &pgCommentRepo{db: db}
+}
+
+// Insert inserts a new comment. It returns ErrInvalidReference when the post
+// is not in the context's community.
+func (r *pgCommentRepo) Insert(ctx context.Context, cmt *Comment) error {
 	if cmt.ID == uuid.Nil {
 		cmt.ID = uuid.New()
 	}
-	const q = `
+	q := `
 INSERT INTO comments (id, post_id, author_id, content)
-VALUES ($1, $2, $3, $4)
+SELECT $1::uuid, $2::uuid, $3::uuid, $4::text
+WHERE ` + postInCommunitySQL("$2::uuid", "$5") + `
 RETURNING created_at, updated_at;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
+	err := r.db.QueryRow(ctx, q, cmt.ID, cmt.PostID, cmt.AuthorID, cmt.Content, communityArg(ctx)).Scan(&cmt.CreatedAt, &cmt.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+const commentColumns = `id, post_id, author_id, content, hidden_at, created_at, updated_at`
+
+func scanComment(row pgx.Row) (*Comment, error) {
+	var cmt Comment
+	if err := row.Scan(&cmt.ID, &cmt.PostID, &cmt.AuthorID, &cmt.Content, &cmt.HiddenAt, &cmt.CreatedAt, &cmt.UpdatedAt); err != nil {
+		return nil, err
 	}
-	return p.QueryRow(ctx, q, cmt.ID, cmt.PostID, cmt.AuthorID, cmt.Content).Scan(&cmt.CreatedAt, &cmt.UpdatedAt)
+	return &cmt, nil
 }
 
-// ListCommentsByPost returns comments for a post in chronological order.
-func ListCommentsByPost(ctx context.Context, postID uuid.UUID) ([]Comment, error) {
-	const q = `
-SELECT id, post_id, author_id, content, created_at, updated_at
-FROM comments WHERE post_id = $1
+// ListByPost returns visible comments for a post in chronological order,
+// without those by residents the viewer blocked or was blocked by.
+func (r *pgCommentRepo) ListByPost(ctx context.Context, postID uuid.UUID, viewerID *uuid.UUID) ([]Comment, error) {
+	q := `
+SELECT ` + commentColumns + `
+FROM comments WHERE post_id = $1 AND hidden_at IS NULL
+  AND ` + postInCommunitySQL("comments.post_id", "$3") + `
+  AND NOT ` + hiddenFromSQL("comments.author_id", "$2", false) + `
 ORDER BY created_at ASC;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
+	rows, err := r.db.Query(ctx, q, postID, viewerID, communityArg(ctx))
+	if err != nil {
+		return nil, err
 	}
-	rows, err := p.Query(ctx, q, postID)
+	defer rows.Close()
+
+	var out []Comment
+	for rows.Next() {
+		cmt, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *cmt)
+	}
+	return out, rows.Err()
+}
+
+// ListByAuthor returns every comment by the author, hidden ones included, newest first.
+func (r *pgCommentRepo) ListByAuthor(ctx context.Context, authorID uuid.UUID) ([]Comment, error) {
+	q := `
+SELECT ` + commentColumns + `
+FROM comments WHERE author_id = $1 AND ` + postInCommunitySQL("comments.post_id", "$2") + `
+ORDER BY created_at DESC;
+`
+	rows, err := r.db.Query(ctx, q, authorID, communityArg(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -83,11 +128,60 @@ ORDER BY created_at ASC;
 
 	var out []Comment
 	for rows.Next() {
-		var cmt Comment
-		if err := rows.Scan(&cmt.ID, &cmt.PostID, &cmt.AuthorID, &cmt.Content, &cmt.CreatedAt, &cmt.UpdatedAt); err != nil {
+		cmt, err := scanComment(rows)
+		if err != nil {
 			return nil, err
 		}
-		out = append(out, cmt)
+		out = append(out, *cmt)
 	}
 	return out, rows.Err()
 }
+
+// CountByPosts counts the comments per post that viewerID can see.
+func (r *pgCommentRepo) CountByPosts(ctx context.Context, postIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID]int, error) {
+	out := make(map[uuid.UUID]int)
+	if len(postIDs) == 0 {
+		return out, nil
+	}
+	q := `
+SELECT post_id, COUNT(*)
+FROM comments WHERE post_id = ANY($1::uuid[]) AND hidden_at IS NULL
+  AND NOT ` + hiddenFromSQL("comments.author_id", "$3", false) + `
+  AND ` + postInCommunitySQL("comments.post_id", "$2") + `
+GROUP BY post_id;
+`
+	rows, err := r.db.Query(ctx, q, uuidStrings(postIDs), communityArg(ctx), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
+
+// GetByID fetches a single comment by id, including hidden comments.
+func (r *pgCommentRepo) GetByID(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	q := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1 AND ` + postInCommunitySQL("comments.post_id", "$2") + ` LIMIT 1;`
+	cmt, err := scanComment(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return cmt, err
+}
+
+// SetHidden hides the comment or restores it.
+func (r *pgCommentRepo) SetHidden(ctx context.Context, id uuid.UUID, hidden bool) error {
+	q := `
+UPDATE comments SET hidden_at = CASE WHEN $2 THEN COALESCE(hidden_at, NOW()) ELSE NULL END
+WHERE id = $1 AND ` + postInCommunitySQL("comments.post_id", "$3") + `;
+`
+	_, err := r.db.Exec(ctx, q, id, hidden, communityArg(ctx))
+	return err
+}
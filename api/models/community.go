@@ -0,0 +1,184 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultCommunityID is the community every row created before tenancy
+// belongs to. Single-property deployments never need another one.
+var DefaultCommunityID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// DefaultCommunitySlug names the default community.
+const DefaultCommunitySlug = "default"
+
+// Community is one property. Residents, boards, posts, reports and audit
+// events belong to exactly one community and are never visible from another.
+type Community struct {
+	ID        uuid.UUID
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+}
+
+type communityCtxKey struct{}
+
+// communityScope is stored in a context; all is set for system-wide work.
+type communityScope struct {
+	id  uuid.UUID
+	all bool
+}
+
+// WithCommunity limits every repository call made with the returned context to community id.
+func WithCommunity(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, communityCtxKey{}, communityScope{id: id})
+}
+
+// AcrossCommunities lifts the community limit for system-wide work such as
+// looking up a login by email or the retention worker.
+func AcrossCommunities(ctx context.Context) context.Context {
+	return context.WithValue(ctx, communityCtxKey{}, communityScope{all: true})
+}
+
+// CommunityScope returns the community repository calls made with ctx are
+// limited to. ok is false for AcrossCommunities; contexts without a scope are
+// limited to the default community.
+func CommunityScope(ctx context.Context) (id uuid.UUID, ok bool) {
+	scope, set := ctx.Value(communityCtxKey{}).(communityScope)
+	if !set {
+		return DefaultCommunityID, true
+	}
+	return scope.id, !scope.all
+}
+
+// communityArg is the query argument for communitySQL: the scoped community, or nil for all.
+func communityArg(ctx context.Context) *uuid.UUID {
+	if id, ok := CommunityScope(ctx); ok {
+		return &id
+	}
+	return nil
+}
+
+// insertCommunity is the community new rows are created in. Writes made
+// across communities go to the default one.
+func insertCommunity(ctx context.Context) uuid.UUID {
+	id, _ := CommunityScope(ctx)
+	if id == uuid.Nil {
+		return DefaultCommunityID
+	}
+	return id
+}
+
+// communitySQL is a condition that limits col to the community in param, as
+// bound by communityArg. A NULL param matches every community.
+func communitySQL(col, param string) string {
+	return fmt.Sprintf(`(%[2]s::uuid IS NULL OR %[1]s = %[2]s::uuid)`, col, param)
+}
+
+// boardInCommunitySQL limits rows whose board is in boardCol to the community in param.
+func boardInCommunitySQL(boardCol, param string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM boards cb WHERE cb.id = %s AND %s)`, boardCol, communitySQL("cb.community_id", param))
+}
+
+// postInCommunitySQL limits rows whose post is in postCol to the community in param.
+func postInCommunitySQL(postCol, param string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM posts cp JOIN boards cb ON cb.id = cp.board_id WHERE cp.id = %s AND %s)`, postCol, communitySQL("cb.community_id", param))
+}
+
+// userInCommunitySQL limits rows whose user is in userCol to the community in param.
+func userInCommunitySQL(userCol, param string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM users cu WHERE cu.id = %s AND %s)`, userCol, communitySQL("cu.community_id", param))
+}
+
+// EnsureCommunitiesTable creates the communities table and the default community.
+func EnsureCommunitiesTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS communities (
+	id UUID PRIMARY KEY,
+	slug VARCHAR NOT NULL UNIQUE,
+	name VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure communities table: %v", err)
+		return err
+	}
+	const seed = `
+INSERT INTO communities (id, slug, name) VALUES ($1, $2, 'Default community')
+ON CONFLICT (id) DO NOTHING;
+`
+	if _, err := db.Exec(ctx, seed, DefaultCommunityID, DefaultCommunitySlug); err != nil {
+		utils.Errorf("failed to seed default community: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgCommunityRepo struct {
+	db DBTX
+}
+
+// NewPgCommunityRepo returns a Postgres-backed CommunityRepo.
+func NewPgCommunityRepo(db DBTX) CommunityRepo {
+	return &pgCommunityRepo{db: db}
+}
+
+func scanCommunity(row pgx.Row) (*Community, error) {
+	var c Community
+	err := row.Scan(&c.ID, &c.Slug, &c.Name, &c.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Insert creates a community; it returns ErrConflict when the slug is taken.
+func (r *pgCommunityRepo) Insert(ctx context.Context, c *Community) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	const q = `INSERT INTO communities (id, slug, name) VALUES ($1, $2, $3) RETURNING created_at;`
+	err := r.db.QueryRow(ctx, q, c.ID, c.Slug, c.Name).Scan(&c.CreatedAt)
+	return translateErr(err)
+}
+
+// GetByID fetches a community by id.
+func (r *pgCommunityRepo) GetByID(ctx context.Context, id uuid.UUID) (*Community, error) {
+	const q = `SELECT id, slug, name, created_at FROM communities WHERE id = $1;`
+	return scanCommunity(r.db.QueryRow(ctx, q, id))
+}
+
+// GetBySlug fetches a community by slug.
+func (r *pgCommunityRepo) GetBySlug(ctx context.Context, slug string) (*Community, error) {
+	const q = `SELECT id, slug, name, created_at FROM communities WHERE slug = $1;`
+	return scanCommunity(r.db.QueryRow(ctx, q, slug))
+}
+
+// List returns every community, oldest first.
+func (r *pgCommunityRepo) List(ctx context.Context) ([]Community, error) {
+	const q = `SELECT id, slug, name, created_at FROM communities ORDER BY created_at ASC, slug ASC;`
+	rows, err := r.db.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Community
+	for rows.Next() {
+		var c Community
+		if err := rows.Scan(&c.ID, &c.Slug, &c.Name, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Data export statuses. Exports are built in the background after the
+// resident asks for one.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// DataExport is a resident's request for a copy of their data. The archive
+// itself is loaded separately by DataExportRepo.Archive.
+type DataExport struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Status string
+	// Error explains why a failed export could not be built.
+	Error       *string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	// ExpiresAt is when a ready archive stops being downloadable.
+	ExpiresAt *time.Time
+	// DownloadedAt is when the resident first downloaded the archive; until
+	// then a ready export is announced on their account.
+	DownloadedAt *time.Time
+}
+
+// EnsureDataExportsTable creates the data_exports table if it doesn't exist.
+func EnsureDataExportsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS data_exports (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL,
+	status VARCHAR NOT NULL DEFAULT 'pending',
+	archive BYTEA NULL,
+	error TEXT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	completed_at TIMESTAMPTZ NULL,
+	expires_at TIMESTAMPTZ NULL,
+	CONSTRAINT fk_data_exports_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS downloaded_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user ON data_exports (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires ON data_exports (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Only one export per user may be pending; older duplicates from before the
+-- index existed are failed so it can be built.
+UPDATE data_exports d
+SET status = 'failed', error = 'superseded by a newer export', completed_at = NOW()
+WHERE d.status = 'pending' AND EXISTS (
+	SELECT 1 FROM data_exports n
+	WHERE n.user_id = d.user_id AND n.status = 'pending'
+	  AND (n.created_at, n.id) > (d.created_at, d.id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_data_exports_user_pending ON data_exports (user_id) WHERE status = 'pending';
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure data_exports table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgDataExportRepo struct {
+	db DBTX
+}
+
+// NewPgDataExportRepo returns a Postgres-backed DataExportRepo.
+func NewPgDataExportRepo(db DBTX) DataExportRepo {
+	return &pgDataExportRepo{db: db}
+}
+
+const dataExportColumns = `id, user_id, status, error, created_at, completed_at, expires_at, downloaded_at`
+
+func scanDataExport(row pgx.Row) (*DataExport, error) {
+	var e DataExport
+	if err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.Error, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt, &e.DownloadedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Insert records a new pending export. It returns ErrConflict when the user
+// already has a pending export and ErrInvalidReference when the user is not
+// in the context's community.
+func (r *pgDataExportRepo) Insert(ctx context.Context, e *DataExport) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	e.Status = ExportPending
+	q := `
+INSERT INTO data_exports (id, user_id, status)
+SELECT $1::uuid, $2::uuid, $3::varchar
+WHERE ` + userInCommunitySQL("$2::uuid", "$4") + `
+RETURNING created_at;
+`
+	err := r.db.QueryRow(ctx, q, e.ID, e.UserID, e.Status, communityArg(ctx)).Scan(&e.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+// GetByID fetches an export without its archive.
+func (r *pgDataExportRepo) GetByID(ctx context.Context, id uuid.UUID) (*DataExport, error) {
+	q := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1 AND ` + userInCommunitySQL("user_id", "$2") + `;`
+	e, err := scanDataExport(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// ListByUser returns a user's exports, newest first.
+func (r *pgDataExportRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]DataExport, error) {
+	q := `
+SELECT ` + dataExportColumns + `
+FROM data_exports WHERE user_id = $1 AND ` + userInCommunitySQL("user_id", "$2") + `
+ORDER BY created_at DESC, id ASC;
+`
+	rows, err := r.db.Query(ctx, q, userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DataExport
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *e)
+	}
+	return out, rows.Err()
+}
+
+// Complete stores the archive of a pending export and marks it ready until expiresAt.
+func (r *pgDataExportRepo) Complete(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error {
+	q := `
+UPDATE data_exports
+SET status = 'ready', archive = $2, completed_at = NOW(), expires_at = $3
+WHERE id = $1 AND status = 'pending' AND ` + userInCommunitySQL("user_id", "$4") + `;
+`
+	_, err := r.db.Exec(ctx, q, id, archive, expiresAt, communityArg(ctx))
+	return err
+}
+
+// Fail marks a pending export as failed with the given reason.
+func (r *pgDataExportRepo) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	q := `
+UPDATE data_exports
+SET status = 'failed', error = $2, completed_at = NOW()
+WHERE id = $1 AND status = 'pending' AND ` + userInCommunitySQL("user_id", "$3") + `;
+`
+	_, err := r.db.Exec(ctx, q, id, reason, communityArg(ctx))
+	return err
+}
+
+// FailStale marks every export pending since before as failed and returns how
+// many were marked.
+func (r *pgDataExportRepo) FailStale(ctx context.Context, before time.Time, reason string) (int, error) {
+	const q = `
+UPDATE data_exports
+SET status = 'failed', error = $2, completed_at = NOW()
+WHERE status = 'pending' AND created_at < $1;
+`
+	tag, err := r.db.Exec(ctx, q, before, reason)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// MarkDownloaded records the first download of a ready export.
+func (r *pgDataExportRepo) MarkDownloaded(ctx context.Context, id uuid.UUID) error {
+	q := `UPDATE data_exports SET downloaded_at = NOW() WHERE id = $1 AND downloaded_at IS NULL AND ` + userInCommunitySQL("user_id", "$2") + `;`
+	_, err := r.db.Exec(ctx, q, id, communityArg(ctx))
+	return err
+}
+
+// Archive returns the ZIP archive of a ready export, or nil if there is none.
+func (r *pgDataExportRepo) Archive(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	q := `SELECT archive FROM data_exports WHERE id = $1 AND status = 'ready' AND ` + userInCommunitySQL("user_id", "$2") + `;`
+	var archive []byte
+	err := r.db.QueryRow(ctx, q, id, communityArg(ctx)).Scan(&archive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return archive, err
+}
+
+// DeleteExpired removes exports whose download window closed before now and
+// returns how many were removed.
+func (r *pgDataExportRepo) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	const q = `DELETE FROM data_exports WHERE expires_at <= $1;`
+	tag, err := r.db.Exec(ctx, q, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -2,31 +2,163 @@ package models
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
 
-	"github.com/cameronsralla/culdechat/connectors/postgres"
+	"github.com/google/uuid"
 )
 
-// DirectoryUser is a lightweight projection for the public directory.
+// DirectoryViewer is the signed-in resident looking at other profiles.
+type DirectoryViewer struct {
+	ID    uuid.UUID
+	Floor string
+}
+
+// FloorOf derives a floor from a unit number: its leading digits without the
+// last two, so unit "1204" is on floor "12". Units that do not follow that
+// pattern have no floor and never match a floor audience.
+func FloorOf(unit string) string {
+	n := 0
+	for n < len(unit) && unit[n] >= '0' && unit[n] <= '9' {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return unit[:n-2]
+}
+
+// floorSQL mirrors FloorOf.
+const floorSQL = `substring(unit_number from '^([0-9]+)[0-9]{2}')`
+
+// CanSee reports whether viewer may see a field shared with audience on the
+// profile of ownerID in ownerUnit. Residents always see their own fields and
+// a nil viewer, who is signed out, sees none.
+func CanSee(audience string, ownerID uuid.UUID, ownerUnit string, viewer *DirectoryViewer) bool {
+	if viewer == nil {
+		return false
+	}
+	switch {
+	case viewer.ID == ownerID || audience == AudienceEveryone:
+		return true
+	case audience == AudienceFloor:
+		floor := FloorOf(ownerUnit)
+		return floor != "" && floor == viewer.Floor
+	}
+	return false
+}
+
+// canSeeSQL mirrors CanSee for one privacy field, with the viewer's id as $1
+// and floor as $2.
+func canSeeSQL(field, def string) string {
+	audience := fmt.Sprintf(`COALESCE(privacy->>'%s', '%s')`, field, def)
+	return fmt.Sprintf(`(id = $1 OR %[1]s = 'everyone' OR (%[1]s = 'floor' AND %[2]s = $2))`, audience, floorSQL)
+}
+
+// DirectoryUser is a lightweight projection for the directory. Services
+// apply Privacy before any field leaves the API.
 type DirectoryUser struct {
-	ID                string
+	ID                uuid.UUID
 	UnitNumber        string
+	DisplayName       *string
 	ProfilePictureURL *string
+	Bio               *string
+	ContactMethod     *string
+	Building          *string
+	Interests         []string
+	Privacy           PrivacySettings
+	// HouseholdID and HouseholdName are only set when the resident's
+	// household opted in to the directory.
+	HouseholdID   *uuid.UUID
+	HouseholdName *string
+}
+
+// DirectoryFilter narrows ListDirectory. Query matches the start of a unit
+// number or of any word in a display name, or a display name that is
+// similar (pg_trgm similarity of at least 0.3). Floor, Building and Interest
+// match exactly. Each criterion only matches fields the resident shares with
+// Viewer: name and unit by their own audience, floor and building by the
+// floor audience (or the unit's) and interests by the bio audience, so
+// searching cannot reveal hidden details. HouseholdID lists the members of a
+// household that opted in to the directory. Signed-out viewers match nothing,
+// and residents blocked by or blocking Viewer are left out.
+type DirectoryFilter struct {
+	Viewer      *DirectoryViewer
+	Query       string
+	Floor       string
+	Building    string
+	Interest    string
+	HouseholdID *uuid.UUID
+	Limit       int
+	Offset      int
+}
+
+// HasCriteria reports whether f filters on any profile field.
+func (f DirectoryFilter) HasCriteria() bool {
+	return f.Query != "" || f.Floor != "" || f.Building != "" || f.Interest != "" || f.HouseholdID != nil
 }
 
-// ListDirectoryUsers returns active users who opted-in to the directory.
-func ListDirectoryUsers(ctx context.Context) ([]DirectoryUser, error) {
-	const q = `
-SELECT id::text, unit_number, profile_picture_url
+// listedHouseholdSQL selects the user's household when it opted in to the directory.
+const listedHouseholdSQL = `FROM households h WHERE h.id = users.household_id AND h.directory_opt_in`
+
+// similarityThreshold matches pg_trgm's default for the % operator.
+const similarityThreshold = 0.3
+
+// ListDirectory returns active users who opted in to the directory, by unit,
+// or by name similarity first when searching.
+func (r *pgUserRepo) ListDirectory(ctx context.Context, f DirectoryFilter) ([]DirectoryUser, error) {
+	if f.Viewer == nil && f.HasCriteria() {
+		return nil, nil
+	}
+	viewer := DirectoryViewer{}
+	if f.Viewer != nil {
+		viewer = *f.Viewer
+	}
+	d := DefaultPrivacy
+	seesName := canSeeSQL("name", d.Name)
+	seesUnit := canSeeSQL("unit", d.Unit)
+	seesFloor := "(" + canSeeSQL("floor", d.Floor) + " OR " + seesUnit + ")"
+	seesBio := canSeeSQL("bio", d.Bio)
+
+	args := []any{viewer.ID, viewer.Floor}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{`is_directory_opt_in = TRUE`, `moderation_hidden = FALSE`, `status = 'active'`, communitySQL("community_id", arg(communityArg(ctx)))}
+	if f.Viewer != nil {
+		where = append(where, `NOT `+hiddenFromSQL("users.id", "$1", false))
+	}
+	order := `unit_number ASC, id ASC`
+	if f.Query != "" {
+		q := arg(strings.ToLower(f.Query))
+		prefix := arg(escapeLike(strings.ToLower(f.Query)) + "%")
+		name := `lower(display_name)`
+		where = append(where, fmt.Sprintf(
+			`((%s AND (%s LIKE %s OR %s LIKE '%% ' || %s OR %s %% %s)) OR (%s AND lower(unit_number) LIKE %s))`,
+			seesName, name, prefix, name, prefix, name, q, seesUnit, prefix))
+		order = fmt.Sprintf(`CASE WHEN %s THEN similarity(COALESCE(%s, ''), %s) ELSE 0 END DESC, `, seesName, name, q) + order
+	}
+	if f.Floor != "" {
+		where = append(where, fmt.Sprintf(`(%s AND %s = %s)`, seesFloor, floorSQL, arg(f.Floor)))
+	}
+	if f.Building != "" {
+		where = append(where, fmt.Sprintf(`(%s AND lower(building) = %s)`, seesFloor, arg(strings.ToLower(f.Building))))
+	}
+	if f.Interest != "" {
+		where = append(where, fmt.Sprintf(`(%s AND %s = ANY(interests))`, seesBio, arg(strings.ToLower(f.Interest))))
+	}
+	if f.HouseholdID != nil {
+		where = append(where, fmt.Sprintf(`household_id = %s AND EXISTS (SELECT 1 %s)`, arg(*f.HouseholdID), listedHouseholdSQL))
+	}
+	q := `
+SELECT id, unit_number, display_name, profile_picture_url, bio, contact_method, building, interests, privacy,
+       (SELECT h.id ` + listedHouseholdSQL + `), (SELECT h.name ` + listedHouseholdSQL + `)
 FROM users
-WHERE is_directory_opt_in = TRUE AND status = 'active'
-ORDER BY unit_number ASC;
-`
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
-	}
-	rows, err := p.Query(ctx, q)
+WHERE ` + strings.Join(where, " AND ") + `
+ORDER BY ` + order + `
+LIMIT ` + arg(f.Limit) + ` OFFSET ` + arg(f.Offset) + `;`
+	rows, err := r.db.Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,12 +166,51 @@ ORDER BY unit_number ASC;
 	var out []DirectoryUser
 	for rows.Next() {
 		var du DirectoryUser
-		var profile *string
-		if err := rows.Scan(&du.ID, &du.UnitNumber, &profile); err != nil {
+		if err := rows.Scan(&du.ID, &du.UnitNumber, &du.DisplayName, &du.ProfilePictureURL, &du.Bio, &du.ContactMethod, &du.Building, &du.Interests, &du.Privacy, &du.HouseholdID, &du.HouseholdName); err != nil {
 			return nil, err
 		}
-		du.ProfilePictureURL = profile
 		out = append(out, du)
 	}
 	return out, rows.Err()
 }
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Similarity computes pg_trgm's similarity between two strings: the share of
+// distinct trigrams they have in common, after lowercasing and splitting
+// into words padded with two leading spaces and one trailing space.
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// SimilarEnough mirrors the pg_trgm % operator at its default threshold.
+func SimilarEnough(a, b string) bool {
+	return Similarity(a, b) >= similarityThreshold
+}
+
+func trigrams(s string) map[string]bool {
+	out := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	for _, w := range words {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			out[string(padded[i:i+3])] = true
+		}
+	}
+	return out
+}
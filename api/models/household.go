@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Household groups the residents who share a unit. There is at most one per
+// unit number in a community; residents link to it through users.household_id.
+type Household struct {
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	UnitNumber  string
+	// UnitID links the household to the unit registry when its founder was verified.
+	UnitID *uuid.UUID
+	Name   *string
+	// DirectoryOptIn shows the household on its members' directory entries.
+	DirectoryOptIn bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// EnsureHouseholdsTable creates the households table.
+func EnsureHouseholdsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS households (
+	id UUID PRIMARY KEY,
+	community_id UUID NOT NULL REFERENCES communities(id),
+	unit_number VARCHAR NOT NULL,
+	unit_id UUID NULL REFERENCES units(id) ON DELETE SET NULL,
+	name VARCHAR NULL,
+	directory_opt_in BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_households_unit ON households (community_id, lower(unit_number));
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure households table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgHouseholdRepo struct {
+	db DBTX
+}
+
+// NewPgHouseholdRepo returns a Postgres-backed HouseholdRepo.
+func NewPgHouseholdRepo(db DBTX) HouseholdRepo {
+	return &pgHouseholdRepo{db: db}
+}
+
+const householdColumns = `id, community_id, unit_number, unit_id, name, directory_opt_in, created_at, updated_at`
+
+func scanHousehold(row pgx.Row) (*Household, error) {
+	var h Household
+	err := row.Scan(&h.ID, &h.CommunityID, &h.UnitNumber, &h.UnitID, &h.Name, &h.DirectoryOptIn, &h.CreatedAt, &h.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Insert creates a household in the context's community; it returns
+// ErrConflict when the unit already has one.
+func (r *pgHouseholdRepo) Insert(ctx context.Context, h *Household) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	h.CommunityID = insertCommunity(ctx)
+	const q = `
+INSERT INTO households (id, community_id, unit_number, unit_id, name, directory_opt_in)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING created_at, updated_at;
+`
+	err := r.db.QueryRow(ctx, q, h.ID, h.CommunityID, h.UnitNumber, h.UnitID, h.Name, h.DirectoryOptIn).Scan(&h.CreatedAt, &h.UpdatedAt)
+	return translateErr(err)
+}
+
+// GetByID fetches a household by id.
+func (r *pgHouseholdRepo) GetByID(ctx context.Context, id uuid.UUID) (*Household, error) {
+	q := `SELECT ` + householdColumns + ` FROM households WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	return scanHousehold(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+}
+
+// List returns the community's households by unit number.
+func (r *pgHouseholdRepo) List(ctx context.Context) ([]Household, error) {
+	q := `SELECT ` + householdColumns + ` FROM households WHERE ` + communitySQL("community_id", "$1") + ` ORDER BY lower(unit_number);`
+	rows, err := r.db.Query(ctx, q, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Household
+	for rows.Next() {
+		h, err := scanHousehold(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *h)
+	}
+	return out, rows.Err()
+}
+
+// Update saves the household's name and directory opt-in.
+func (r *pgHouseholdRepo) Update(ctx context.Context, h *Household) error {
+	q := `
+UPDATE households SET name = $2, directory_opt_in = $3, updated_at = NOW()
+WHERE id = $1 AND ` + communitySQL("community_id", "$4") + `
+RETURNING updated_at;
+`
+	return translateErr(r.db.QueryRow(ctx, q, h.ID, h.Name, h.DirectoryOptIn, communityArg(ctx)).Scan(&h.UpdatedAt))
+}
+
+// Delete removes a household. Its members are unlinked and its invites deleted.
+func (r *pgHouseholdRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	q := `DELETE FROM households WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	_, err := r.db.Exec(ctx, q, id, communityArg(ctx))
+	return err
+}
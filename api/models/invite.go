@@ -0,0 +1,156 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Invite lets residents register. It can be used MaxUses times until it
+// expires or is revoked, and when UnitNumber is set only for that unit.
+type Invite struct {
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	// Code is what residents type or scan; codes are unique across communities
+	// because registering with one also picks the community.
+	Code       string
+	UnitNumber *string
+	MaxUses    int
+	Uses       int
+	ExpiresAt  *time.Time
+	CreatedBy  *uuid.UUID
+	// HouseholdID is set on invites residents send to co-residents; they
+	// join the household when they redeem it.
+	HouseholdID *uuid.UUID
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// Usable reports whether the invite can still be redeemed at now.
+func (i *Invite) Usable(now time.Time) bool {
+	return i.RevokedAt == nil && i.Uses < i.MaxUses && (i.ExpiresAt == nil || now.Before(*i.ExpiresAt))
+}
+
+// EnsureInvitesTable creates the invites table.
+func EnsureInvitesTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS invites (
+	id UUID PRIMARY KEY,
+	community_id UUID NOT NULL REFERENCES communities(id),
+	code VARCHAR NOT NULL UNIQUE,
+	unit_number VARCHAR NULL,
+	max_uses INTEGER NOT NULL DEFAULT 1,
+	uses INTEGER NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ NULL,
+	created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+	revoked_at TIMESTAMPTZ NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS household_id UUID NULL REFERENCES households(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_invites_community ON invites (community_id, created_at DESC);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure invites table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgInviteRepo struct {
+	db DBTX
+}
+
+// NewPgInviteRepo returns a Postgres-backed InviteRepo.
+func NewPgInviteRepo(db DBTX) InviteRepo {
+	return &pgInviteRepo{db: db}
+}
+
+const inviteColumns = `id, community_id, code, unit_number, max_uses, uses, expires_at, created_by, household_id, revoked_at, created_at`
+
+func scanInvite(row pgx.Row) (*Invite, error) {
+	var i Invite
+	err := row.Scan(&i.ID, &i.CommunityID, &i.Code, &i.UnitNumber, &i.MaxUses, &i.Uses, &i.ExpiresAt, &i.CreatedBy, &i.HouseholdID, &i.RevokedAt, &i.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// Insert creates an invite in the context's community; it returns ErrConflict when the code is taken.
+func (r *pgInviteRepo) Insert(ctx context.Context, i *Invite) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	i.CommunityID = insertCommunity(ctx)
+	const q = `
+INSERT INTO invites (id, community_id, code, unit_number, max_uses, expires_at, created_by, household_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING uses, created_at;
+`
+	err := r.db.QueryRow(ctx, q, i.ID, i.CommunityID, i.Code, i.UnitNumber, i.MaxUses, i.ExpiresAt, i.CreatedBy, i.HouseholdID).Scan(&i.Uses, &i.CreatedAt)
+	return translateErr(err)
+}
+
+// GetByID fetches an invite by id.
+func (r *pgInviteRepo) GetByID(ctx context.Context, id uuid.UUID) (*Invite, error) {
+	q := `SELECT ` + inviteColumns + ` FROM invites WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	return scanInvite(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+}
+
+// GetByCode fetches an invite by code. Registration looks codes up across communities.
+func (r *pgInviteRepo) GetByCode(ctx context.Context, code string) (*Invite, error) {
+	q := `SELECT ` + inviteColumns + ` FROM invites WHERE code = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	return scanInvite(r.db.QueryRow(ctx, q, code, communityArg(ctx)))
+}
+
+// List returns the community's invites, newest first.
+func (r *pgInviteRepo) List(ctx context.Context) ([]Invite, error) {
+	q := `SELECT ` + inviteColumns + ` FROM invites WHERE ` + communitySQL("community_id", "$1") + ` ORDER BY created_at DESC, id DESC;`
+	rows, err := r.db.Query(ctx, q, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Invite
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *i)
+	}
+	return out, rows.Err()
+}
+
+// Redeem uses up one use of the invite if it is still usable at now.
+func (r *pgInviteRepo) Redeem(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	q := `
+UPDATE invites SET uses = uses + 1
+WHERE id = $1 AND revoked_at IS NULL AND uses < max_uses AND (expires_at IS NULL OR expires_at > $2)
+  AND ` + communitySQL("community_id", "$3") + `;
+`
+	tag, err := r.db.Exec(ctx, q, id, now, communityArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Revoke stops an invite from being redeemed and reports whether it was active.
+func (r *pgInviteRepo) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	q := `UPDATE invites SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL AND ` + communitySQL("community_id", "$2") + `;`
+	tag, err := r.db.Exec(ctx, q, id, communityArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type accountActionRepo struct {
+	s *Store
+}
+
+func (r *accountActionRepo) Insert(ctx context.Context, a *models.AccountAction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	if _, ok := r.s.actions[a.ID]; ok {
+		return models.ErrConflict
+	}
+	if !r.s.userInCommunity(ctx, a.UserID) {
+		return models.ErrInvalidReference
+	}
+	a.CreatedAt = r.s.stamp(a.ID)
+	r.s.actions[a.ID] = *a
+	return nil
+}
+
+func (r *accountActionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.AccountAction, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.s.userInCommunity(ctx, userID) {
+		return nil, nil
+	}
+	var out []models.AccountAction
+	for _, a := range r.s.actions {
+		if a.UserID == userID {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type auditRepo struct {
+	s *Store
+}
+
+func (r *auditRepo) Insert(ctx context.Context, e *models.AuditEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if _, ok := r.s.audit[e.ID]; ok {
+		return models.ErrConflict
+	}
+	details := make(map[string]string, len(e.Details))
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	e.Details = details
+	e.CommunityID = insertCommunity(ctx)
+	e.CreatedAt = r.s.stamp(e.ID)
+	r.s.audit[e.ID] = *e
+	return nil
+}
+
+func (r *auditRepo) List(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.AuditEvent
+	for _, e := range r.s.audit {
+		switch {
+		case !inCommunity(ctx, e.CommunityID),
+			f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID),
+			f.Action != "" && e.Action != f.Action,
+			f.TargetType != "" && e.TargetType != f.TargetType,
+			f.TargetID != nil && (e.TargetID == nil || *e.TargetID != *f.TargetID),
+			f.From != nil && e.CreatedAt.Before(*f.From),
+			f.To != nil && !e.CreatedAt.Before(*f.To):
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type blockKey struct {
+	userID, targetID uuid.UUID
+}
+
+type blockRepo struct {
+	s *Store
+}
+
+// hiddenFrom mirrors the Postgres hiddenFromSQL condition. Callers must hold mu.
+func (s *Store) hiddenFrom(viewerID *uuid.UUID, authorID uuid.UUID, mutes bool) bool {
+	if viewerID == nil {
+		return false
+	}
+	if b, ok := s.blocks[blockKey{*viewerID, authorID}]; ok && (mutes || b.Kind == models.BlockKindBlock) {
+		return true
+	}
+	b, ok := s.blocks[blockKey{authorID, *viewerID}]
+	return ok && b.Kind == models.BlockKindBlock
+}
+
+func (r *blockRepo) Upsert(ctx context.Context, b *models.Block) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.userInCommunity(ctx, b.UserID) || !r.s.userInCommunity(ctx, b.TargetID) {
+		return models.ErrInvalidReference
+	}
+	b.CreatedAt = time.Now().UTC()
+	r.s.blocks[blockKey{b.UserID, b.TargetID}] = *b
+	return nil
+}
+
+func (r *blockRepo) Delete(ctx context.Context, userID, targetID uuid.UUID, kind string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := blockKey{userID, targetID}
+	b, ok := r.s.blocks[key]
+	if !ok || b.Kind != kind || !r.s.userInCommunity(ctx, userID) {
+		return false, nil
+	}
+	delete(r.s.blocks, key)
+	return true, nil
+}
+
+func (r *blockRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Block, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.s.userInCommunity(ctx, userID) {
+		return nil, nil
+	}
+	var out []models.Block
+	for _, b := range r.s.blocks {
+		if b.UserID == userID {
+			out = append(out, b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].TargetID.String() < out[j].TargetID.String()
+	})
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+// canRead mirrors the Postgres readableBoardSQL condition. Callers must hold mu.
+func (s *Store) canRead(userID, boardID uuid.UUID) bool {
+	b, ok := s.boards[boardID]
+	if !ok {
+		return false
+	}
+	if b.Visibility != models.BoardMembers {
+		return true
+	}
+	if u, ok := s.users[userID]; ok && (u.IsAdmin || u.IsModerator) {
+		return true
+	}
+	m, ok := s.members[memberKey{boardID, userID}]
+	return ok && m.Status == models.MemberApproved
+}
+
+type memberKey struct {
+	boardID, userID uuid.UUID
+}
+
+type boardMemberRepo struct {
+	s *Store
+}
+
+func (r *boardMemberRepo) Upsert(ctx context.Context, m *models.BoardMember) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.boardInCommunity(ctx, m.BoardID) || !r.s.userInCommunity(ctx, m.UserID) {
+		return models.ErrInvalidReference
+	}
+	key := memberKey{m.BoardID, m.UserID}
+	now := time.Now().UTC()
+	current, ok := r.s.members[key]
+	if !ok {
+		current = models.BoardMember{BoardID: m.BoardID, UserID: m.UserID, CreatedAt: now}
+	}
+	current.Status, current.InvitedBy, current.UpdatedAt = m.Status, m.InvitedBy, now
+	r.s.members[key] = current
+	*m = current
+	return nil
+}
+
+func (r *boardMemberRepo) Get(ctx context.Context, boardID, userID uuid.UUID) (*models.BoardMember, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	m, ok := r.s.members[memberKey{boardID, userID}]
+	if !ok || !r.s.boardInCommunity(ctx, boardID) {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+func (r *boardMemberRepo) ListByBoard(ctx context.Context, boardID uuid.UUID) ([]models.BoardMember, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.s.boardInCommunity(ctx, boardID) {
+		return nil, nil
+	}
+	var out []models.BoardMember
+	for key, m := range r.s.members {
+		if key.boardID == boardID {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *boardMemberRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.BoardMember, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.BoardMember
+	for key, m := range r.s.members {
+		if key.userID == userID && r.s.boardInCommunity(ctx, key.boardID) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (r *boardMemberRepo) Delete(ctx context.Context, boardID, userID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.boardInCommunity(ctx, boardID) {
+		return nil
+	}
+	delete(r.s.members, memberKey{boardID, userID})
+	return nil
+}
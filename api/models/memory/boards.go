@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type boardRepo struct {
+	s *Store
+}
+
+func (r *boardRepo) Insert(ctx context.Context, b *models.Board) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	if _, ok := r.s.boards[b.ID]; ok {
+		return models.ErrConflict
+	}
+	b.CommunityID = insertCommunity(ctx)
+	if r.nameTaken(b) {
+		return models.ErrConflict
+	}
+	if b.Visibility == "" {
+		b.Visibility = models.BoardPublic
+	}
+	now := r.s.stamp(b.ID)
+	b.CreatedAt, b.UpdatedAt = now, now
+	r.s.boards[b.ID] = *b
+	return nil
+}
+
+// nameTaken mirrors the unique index on boards (community_id, name). Callers must hold mu.
+func (r *boardRepo) nameTaken(b *models.Board) bool {
+	for _, existing := range r.s.boards {
+		if existing.ID != b.ID && existing.CommunityID == b.CommunityID && existing.Name == b.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *boardRepo) List(ctx context.Context) ([]models.Board, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Board
+	for _, b := range r.s.boards {
+		if inCommunity(ctx, b.CommunityID) {
+			out = append(out, b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SortOrder != out[j].SortOrder {
+			return out[i].SortOrder < out[j].SortOrder
+		}
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *boardRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Board, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	b, ok := r.s.boards[id]
+	if !ok || !inCommunity(ctx, b.CommunityID) {
+		return nil, nil
+	}
+	return &b, nil
+}
+
+func (r *boardRepo) Update(ctx context.Context, b *models.Board) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current, ok := r.s.boards[b.ID]
+	if !ok || !inCommunity(ctx, current.CommunityID) {
+		return nil
+	}
+	b.CommunityID = current.CommunityID
+	if r.nameTaken(b) {
+		return models.ErrConflict
+	}
+	current.Name, current.Description, current.ArchivedAt = b.Name, b.Description, b.ArchivedAt
+	current.Visibility, current.PublicRead = b.Visibility, b.PublicRead
+	current.UpdatedAt = time.Now().UTC()
+	b.UpdatedAt = current.UpdatedAt
+	r.s.boards[b.ID] = current
+	return nil
+}
+
+func (r *boardRepo) Reorder(ctx context.Context, ids []uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, id := range ids {
+		if b, ok := r.s.boards[id]; ok && inCommunity(ctx, b.CommunityID) {
+			b.SortOrder = i + 1
+			r.s.boards[id] = b
+		}
+	}
+	return nil
+}
+
+func (r *boardRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.boardInCommunity(ctx, id) {
+		return nil
+	}
+	delete(r.s.boards, id)
+	for key := range r.s.subscriptions {
+		if key.boardID == id {
+			delete(r.s.subscriptions, key)
+		}
+	}
+	for key := range r.s.members {
+		if key.boardID == id {
+			delete(r.s.members, key)
+		}
+	}
+	for postID, p := range r.s.posts {
+		if p.BoardID == id {
+			r.s.deletePost(postID)
+		}
+	}
+	return nil
+}
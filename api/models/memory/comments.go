@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type commentRepo struct {
+	s *Store
+}
+
+func (r *commentRepo) Insert(ctx context.Context, c *models.Comment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	if _, ok := r.s.comments[c.ID]; ok {
+		return models.ErrConflict
+	}
+	if !r.s.postInCommunity(ctx, c.PostID) {
+		return models.ErrInvalidReference
+	}
+	if _, ok := r.s.users[c.AuthorID]; !ok {
+		return models.ErrInvalidReference
+	}
+	now := r.s.stamp(c.ID)
+	c.CreatedAt, c.UpdatedAt = now, now
+	r.s.comments[c.ID] = *c
+	return nil
+}
+
+func (r *commentRepo) ListByPost(ctx context.Context, postID uuid.UUID, viewerID *uuid.UUID) ([]models.Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Comment
+	for _, c := range r.s.comments {
+		if c.PostID == postID && c.HiddenAt == nil && r.s.postInCommunity(ctx, c.PostID) && !r.s.hiddenFrom(viewerID, c.AuthorID, false) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[j].ID, out[i].ID, out[j].CreatedAt, out[i].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *commentRepo) ListByAuthor(ctx context.Context, authorID uuid.UUID) ([]models.Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Comment
+	for _, c := range r.s.comments {
+		if c.AuthorID == authorID && r.s.postInCommunity(ctx, c.PostID) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *commentRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.comments[id]
+	if !ok || !r.s.postInCommunity(ctx, c.PostID) {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *commentRepo) SetHidden(ctx context.Context, id uuid.UUID, hidden bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c, ok := r.s.comments[id]; ok && r.s.postInCommunity(ctx, c.PostID) {
+		c.HiddenAt = hiddenAt(c.HiddenAt, hidden)
+		r.s.comments[id] = c
+	}
+	return nil
+}
+
+func (r *commentRepo) CountByPosts(ctx context.Context, postIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID]int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	want := idSet(postIDs)
+	out := make(map[uuid.UUID]int)
+	for _, c := range r.s.comments {
+		if want[c.PostID] && c.HiddenAt == nil && r.s.postInCommunity(ctx, c.PostID) && !r.s.hiddenFrom(viewerID, c.AuthorID, false) {
+			out[c.PostID]++
+		}
+	}
+	return out, nil
+}
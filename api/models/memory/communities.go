@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type communityRepo struct {
+	s *Store
+}
+
+func (r *communityRepo) Insert(_ context.Context, c *models.Community) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	if _, ok := r.s.communities[c.ID]; ok {
+		return models.ErrConflict
+	}
+	for _, existing := range r.s.communities {
+		if existing.Slug == c.Slug {
+			return models.ErrConflict
+		}
+	}
+	c.CreatedAt = r.s.stamp(c.ID)
+	r.s.communities[c.ID] = *c
+	return nil
+}
+
+func (r *communityRepo) GetByID(_ context.Context, id uuid.UUID) (*models.Community, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.communities[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *communityRepo) GetBySlug(_ context.Context, slug string) (*models.Community, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, c := range r.s.communities {
+		if c.Slug == slug {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *communityRepo) List(_ context.Context) ([]models.Community, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := make([]models.Community, 0, len(r.s.communities))
+	for _, c := range r.s.communities {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[j].ID, out[i].ID, out[j].CreatedAt, out[i].CreatedAt)
+	})
+	return out, nil
+}
+
+// inCommunity mirrors communitySQL: it reports whether a row in community id
+// is visible to repository calls made with ctx.
+func inCommunity(ctx context.Context, id uuid.UUID) bool {
+	scope, ok := models.CommunityScope(ctx)
+	return !ok || scope == id
+}
+
+// boardInCommunity reports whether the board exists in ctx's community. Callers must hold mu.
+func (s *Store) boardInCommunity(ctx context.Context, boardID uuid.UUID) bool {
+	b, ok := s.boards[boardID]
+	return ok && inCommunity(ctx, b.CommunityID)
+}
+
+// postInCommunity reports whether the post exists on a board in ctx's community. Callers must hold mu.
+func (s *Store) postInCommunity(ctx context.Context, postID uuid.UUID) bool {
+	p, ok := s.posts[postID]
+	return ok && s.boardInCommunity(ctx, p.BoardID)
+}
+
+// userInCommunity reports whether the user exists in ctx's community. Callers must hold mu.
+func (s *Store) userInCommunity(ctx context.Context, userID uuid.UUID) bool {
+	u, ok := s.users[userID]
+	return ok && inCommunity(ctx, u.CommunityID)
+}
+
+// insertCommunity mirrors models' choice of community for new rows.
+func insertCommunity(ctx context.Context) uuid.UUID {
+	id, _ := models.CommunityScope(ctx)
+	if id == uuid.Nil {
+		return models.DefaultCommunityID
+	}
+	return id
+}
@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+// exportRow keeps an export's archive next to it, like the archive column.
+type exportRow struct {
+	models.DataExport
+	archive []byte
+}
+
+type dataExportRepo struct {
+	s *Store
+}
+
+func (r *dataExportRepo) Insert(ctx context.Context, e *models.DataExport) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.userInCommunity(ctx, e.UserID) {
+		return models.ErrInvalidReference
+	}
+	for _, row := range r.s.exports {
+		if row.UserID == e.UserID && row.Status == models.ExportPending {
+			return models.ErrConflict
+		}
+	}
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	e.Status = models.ExportPending
+	e.CreatedAt = r.s.stamp(e.ID)
+	r.s.exports[e.ID] = exportRow{DataExport: *e}
+	return nil
+}
+
+func (r *dataExportRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	row, ok := r.s.exports[id]
+	if !ok || !r.s.userInCommunity(ctx, row.UserID) {
+		return nil, nil
+	}
+	return &row.DataExport, nil
+}
+
+func (r *dataExportRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.s.userInCommunity(ctx, userID) {
+		return nil, nil
+	}
+	var out []models.DataExport
+	for _, row := range r.s.exports {
+		if row.UserID == userID {
+			out = append(out, row.DataExport)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *dataExportRepo) Complete(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	row, ok := r.s.exports[id]
+	if !ok || row.Status != models.ExportPending || !r.s.userInCommunity(ctx, row.UserID) {
+		return nil
+	}
+	now := time.Now().UTC()
+	row.Status, row.CompletedAt, row.ExpiresAt = models.ExportReady, &now, &expiresAt
+	row.archive = archive
+	r.s.exports[id] = row
+	return nil
+}
+
+func (r *dataExportRepo) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	row, ok := r.s.exports[id]
+	if !ok || row.Status != models.ExportPending || !r.s.userInCommunity(ctx, row.UserID) {
+		return nil
+	}
+	now := time.Now().UTC()
+	row.Status, row.CompletedAt, row.Error = models.ExportFailed, &now, &reason
+	r.s.exports[id] = row
+	return nil
+}
+
+func (r *dataExportRepo) FailStale(_ context.Context, before time.Time, reason string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now().UTC()
+	n := 0
+	for id, row := range r.s.exports {
+		if row.Status == models.ExportPending && row.CreatedAt.Before(before) {
+			row.Status, row.CompletedAt, row.Error = models.ExportFailed, &now, &reason
+			r.s.exports[id] = row
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *dataExportRepo) MarkDownloaded(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	row, ok := r.s.exports[id]
+	if !ok || row.DownloadedAt != nil || !r.s.userInCommunity(ctx, row.UserID) {
+		return nil
+	}
+	now := time.Now().UTC()
+	row.DownloadedAt = &now
+	r.s.exports[id] = row
+	return nil
+}
+
+func (r *dataExportRepo) Archive(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	row, ok := r.s.exports[id]
+	if !ok || row.Status != models.ExportReady || !r.s.userInCommunity(ctx, row.UserID) {
+		return nil, nil
+	}
+	return row.archive, nil
+}
+
+func (r *dataExportRepo) DeleteExpired(_ context.Context, now time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := 0
+	for id, row := range r.s.exports {
+		if row.ExpiresAt != nil && !row.ExpiresAt.After(now) {
+			delete(r.s.exports, id)
+			n++
+		}
+	}
+	return n, nil
+}
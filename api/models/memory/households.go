@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type householdRepo struct {
+	s *Store
+}
+
+func (r *householdRepo) Insert(ctx context.Context, h *models.Household) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	h.CommunityID = insertCommunity(ctx)
+	for id, existing := range r.s.households {
+		if id == h.ID || existing.CommunityID == h.CommunityID && strings.EqualFold(existing.UnitNumber, h.UnitNumber) {
+			return models.ErrConflict
+		}
+	}
+	if h.UnitID != nil {
+		if _, ok := r.s.units[*h.UnitID]; !ok {
+			return models.ErrInvalidReference
+		}
+	}
+	h.CreatedAt = r.s.stamp(h.ID)
+	h.UpdatedAt = h.CreatedAt
+	r.s.households[h.ID] = *h
+	return nil
+}
+
+// household returns the household if it is visible in ctx's community. Callers must hold mu.
+func (r *householdRepo) household(ctx context.Context, id uuid.UUID) (models.Household, bool) {
+	h, ok := r.s.households[id]
+	return h, ok && inCommunity(ctx, h.CommunityID)
+}
+
+func (r *householdRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Household, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	h, ok := r.household(ctx, id)
+	if !ok {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+func (r *householdRepo) List(ctx context.Context) ([]models.Household, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Household
+	for _, h := range r.s.households {
+		if inCommunity(ctx, h.CommunityID) {
+			out = append(out, h)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i].UnitNumber) < strings.ToLower(out[j].UnitNumber)
+	})
+	return out, nil
+}
+
+func (r *householdRepo) Update(ctx context.Context, h *models.Household) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.household(ctx, h.ID)
+	if !ok {
+		// Mirrors UPDATE ... RETURNING on a missing row.
+		return pgx.ErrNoRows
+	}
+	existing.Name, existing.DirectoryOptIn = h.Name, h.DirectoryOptIn
+	existing.UpdatedAt = time.Now().UTC()
+	r.s.households[h.ID] = existing
+	*h = existing
+	return nil
+}
+
+func (r *householdRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.household(ctx, id); ok {
+		r.s.deleteHousehold(id)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type inviteRepo struct {
+	s *Store
+}
+
+func (r *inviteRepo) Insert(ctx context.Context, i *models.Invite) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	if _, ok := r.s.invites[i.ID]; ok {
+		return models.ErrConflict
+	}
+	for _, existing := range r.s.invites {
+		if existing.Code == i.Code {
+			return models.ErrConflict
+		}
+	}
+	if i.CreatedBy != nil {
+		if _, ok := r.s.users[*i.CreatedBy]; !ok {
+			return models.ErrInvalidReference
+		}
+	}
+	if i.HouseholdID != nil {
+		if _, ok := r.s.households[*i.HouseholdID]; !ok {
+			return models.ErrInvalidReference
+		}
+	}
+	i.CommunityID = insertCommunity(ctx)
+	i.Uses = 0
+	i.CreatedAt = r.s.stamp(i.ID)
+	r.s.invites[i.ID] = *i
+	return nil
+}
+
+// invite returns the invite if it is visible in ctx's community. Callers must hold mu.
+func (r *inviteRepo) invite(ctx context.Context, id uuid.UUID) (models.Invite, bool) {
+	i, ok := r.s.invites[id]
+	return i, ok && inCommunity(ctx, i.CommunityID)
+}
+
+func (r *inviteRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Invite, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, ok := r.invite(ctx, id)
+	if !ok {
+		return nil, nil
+	}
+	return &i, nil
+}
+
+func (r *inviteRepo) GetByCode(ctx context.Context, code string) (*models.Invite, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, i := range r.s.invites {
+		if i.Code == code && inCommunity(ctx, i.CommunityID) {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *inviteRepo) List(ctx context.Context) ([]models.Invite, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Invite
+	for _, i := range r.s.invites {
+		if inCommunity(ctx, i.CommunityID) {
+			out = append(out, i)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		return r.s.newer(out[a].ID, out[b].ID, out[a].CreatedAt, out[b].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *inviteRepo) Redeem(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, ok := r.invite(ctx, id)
+	if !ok || !i.Usable(now) {
+		return false, nil
+	}
+	i.Uses++
+	r.s.invites[id] = i
+	return true, nil
+}
+
+func (r *inviteRepo) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, ok := r.invite(ctx, id)
+	if !ok || i.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	i.RevokedAt = &now
+	r.s.invites[id] = i
+	return true, nil
+}
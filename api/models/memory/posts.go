@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type postRepo struct {
+	s *Store
+}
+
+func (r *postRepo) Insert(ctx context.Context, p *models.Post) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	if _, ok := r.s.posts[p.ID]; ok {
+		return models.ErrConflict
+	}
+	if !r.s.boardInCommunity(ctx, p.BoardID) {
+		return models.ErrInvalidReference
+	}
+	if _, ok := r.s.users[p.AuthorID]; !ok {
+		return models.ErrInvalidReference
+	}
+	now := r.s.stamp(p.ID)
+	p.CreatedAt, p.UpdatedAt = now, now
+	r.s.posts[p.ID] = *p
+	return nil
+}
+
+// listPosts returns posts in ctx's community matching keep, newest first. Callers must hold mu.
+func (r *postRepo) listPosts(ctx context.Context, keep func(models.Post) bool) []models.Post {
+	var out []models.Post
+	for _, p := range r.s.posts {
+		if r.s.boardInCommunity(ctx, p.BoardID) && keep(p) {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out
+}
+
+// pastCursor reports whether p comes after the cursor in newest-first order,
+// breaking timestamp ties the same way listPosts does. Callers must hold mu.
+func (s *Store) pastCursor(p models.Post, after *models.FeedCursor) bool {
+	if after.ID == nil || !p.CreatedAt.Equal(after.CreatedAt) {
+		return p.CreatedAt.Before(after.CreatedAt)
+	}
+	return s.newer(*after.ID, p.ID, after.CreatedAt, p.CreatedAt)
+}
+
+func (r *postRepo) ListByBoard(ctx context.Context, boardID uuid.UUID, viewerID *uuid.UUID) ([]models.Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.listPosts(ctx, func(p models.Post) bool {
+		return p.BoardID == boardID && p.HiddenAt == nil && !r.s.hiddenFrom(viewerID, p.AuthorID, true)
+	}), nil
+}
+
+func (r *postRepo) ListBulletins(ctx context.Context, viewerID *uuid.UUID) ([]models.Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.listPosts(ctx, func(p models.Post) bool {
+		return p.IsBulletin && p.HiddenAt == nil && !r.s.hiddenFrom(viewerID, p.AuthorID, false)
+	}), nil
+}
+
+func (r *postRepo) ListByAuthor(ctx context.Context, authorID uuid.UUID) ([]models.Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.listPosts(ctx, func(p models.Post) bool { return p.AuthorID == authorID }), nil
+}
+
+func (r *postRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.posts[id]
+	if !ok || !r.s.boardInCommunity(ctx, p.BoardID) {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (r *postRepo) SetHidden(ctx context.Context, id uuid.UUID, hidden bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if p, ok := r.s.posts[id]; ok && r.s.boardInCommunity(ctx, p.BoardID) {
+		p.HiddenAt = hiddenAt(p.HiddenAt, hidden)
+		r.s.posts[id] = p
+	}
+	return nil
+}
+
+func (r *postRepo) MoveBoard(ctx context.Context, from, to uuid.UUID) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.boardInCommunity(ctx, to) {
+		return 0, models.ErrInvalidReference
+	}
+	moved := 0
+	for id, p := range r.s.posts {
+		if p.BoardID == from && r.s.boardInCommunity(ctx, from) {
+			p.BoardID = to
+			p.UpdatedAt = time.Now().UTC()
+			r.s.posts[id] = p
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (r *postRepo) ListFeed(ctx context.Context, userID uuid.UUID, after *models.FeedCursor, limit int) ([]models.Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := r.listPosts(ctx, func(p models.Post) bool {
+		if p.HiddenAt != nil || (after != nil && !r.s.pastCursor(p, after)) || r.s.hiddenFrom(&userID, p.AuthorID, true) || !r.s.canRead(userID, p.BoardID) {
+			return false
+		}
+		sub, ok := r.s.subscriptions[subscriptionKey{userID, p.BoardID}]
+		return ok && includesPost(sub.Level, p)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
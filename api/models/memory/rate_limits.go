@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+)
+
+type bucket struct {
+	tokens      float64
+	refreshedAt time.Time
+}
+
+type rateLimitRepo struct {
+	s *Store
+}
+
+// NewRateLimitRepo returns a process-local RateLimitRepo, for single-instance
+// deployments that prefer not to touch the database on every limited request.
+func NewRateLimitRepo() models.RateLimitRepo {
+	return &rateLimitRepo{s: NewStore()}
+}
+
+func (r *rateLimitRepo) Take(_ context.Context, key string, capacity, perSecond float64) (models.RateLimitResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	b, ok := r.s.buckets[key]
+	if !ok {
+		b = bucket{tokens: capacity, refreshedAt: now}
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.refreshedAt).Seconds()*perSecond)
+	b.refreshedAt = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	r.s.buckets[key] = b
+	return models.RateLimitResult{Allowed: allowed, Tokens: b.tokens}, nil
+}
+
+func (r *rateLimitRepo) Prune(_ context.Context, idle time.Duration) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	cutoff := time.Now().Add(-idle)
+	for key, b := range r.s.buckets {
+		if b.refreshedAt.Before(cutoff) {
+			delete(r.s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type reactionRepo struct {
+	s *Store
+}
+
+// targetInCommunity mirrors the reactions foreign keys and community scope:
+// comments are scoped through their post. Callers must hold mu.
+func (r *reactionRepo) targetInCommunity(ctx context.Context, targetType string, id uuid.UUID) bool {
+	if targetType == models.ReactionOnComment {
+		c, ok := r.s.comments[id]
+		return ok && r.s.postInCommunity(ctx, c.PostID)
+	}
+	return r.s.postInCommunity(ctx, id)
+}
+
+func (r *reactionRepo) Upsert(ctx context.Context, rx *models.Reaction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.targetInCommunity(ctx, rx.TargetType, rx.TargetID) {
+		return models.ErrInvalidReference
+	}
+	if !r.s.userInCommunity(ctx, rx.UserID) {
+		return models.ErrInvalidReference
+	}
+	for id, existing := range r.s.reactions {
+		if existing.TargetType == rx.TargetType && existing.TargetID == rx.TargetID && existing.UserID == rx.UserID {
+			existing.Type = rx.Type
+			existing.UpdatedAt = time.Now().UTC()
+			r.s.reactions[id] = existing
+			*rx = existing
+			return nil
+		}
+	}
+	if rx.ID == uuid.Nil {
+		rx.ID = uuid.New()
+	}
+	now := r.s.stamp(rx.ID)
+	rx.CreatedAt, rx.UpdatedAt = now, now
+	r.s.reactions[rx.ID] = *rx
+	return nil
+}
+
+func (r *reactionRepo) Remove(ctx context.Context, targetType string, targetID, userID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.targetInCommunity(ctx, targetType, targetID) {
+		return nil
+	}
+	for id, existing := range r.s.reactions {
+		if existing.TargetType == targetType && existing.TargetID == targetID && existing.UserID == userID {
+			delete(r.s.reactions, id)
+		}
+	}
+	return nil
+}
+
+func (r *reactionRepo) Count(ctx context.Context, targetType string, targetID uuid.UUID) ([]models.ReactionCount, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.targetInCommunity(ctx, targetType, targetID) {
+		return []models.ReactionCount{}, nil
+	}
+	counts := map[string]int64{}
+	for _, rx := range r.s.reactions {
+		if rx.TargetType == targetType && rx.TargetID == targetID {
+			counts[rx.Type]++
+		}
+	}
+	out := make([]models.ReactionCount, 0, len(counts))
+	for t, n := range counts {
+		out = append(out, models.ReactionCount{Type: t, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out, nil
+}
+
+func (r *reactionRepo) List(ctx context.Context, targetType string, targetID uuid.UUID, viewerID *uuid.UUID) ([]models.Reaction, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.targetInCommunity(ctx, targetType, targetID) {
+		return nil, nil
+	}
+	var out []models.Reaction
+	for _, rx := range r.s.reactions {
+		if rx.TargetType == targetType && rx.TargetID == targetID && !r.s.hiddenFrom(viewerID, rx.UserID, false) {
+			out = append(out, rx)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[j].ID, out[i].ID, out[j].CreatedAt, out[i].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *reactionRepo) CountMany(ctx context.Context, targetType string, targetIDs []uuid.UUID) (map[uuid.UUID][]models.ReactionCount, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	want := idSet(targetIDs)
+	counts := map[uuid.UUID]map[string]int64{}
+	for _, rx := range r.s.reactions {
+		if rx.TargetType != targetType || !want[rx.TargetID] || !r.targetInCommunity(ctx, targetType, rx.TargetID) {
+			continue
+		}
+		if counts[rx.TargetID] == nil {
+			counts[rx.TargetID] = map[string]int64{}
+		}
+		counts[rx.TargetID][rx.Type]++
+	}
+	out := make(map[uuid.UUID][]models.ReactionCount, len(counts))
+	for id, byType := range counts {
+		for t, n := range byType {
+			out[id] = append(out[id], models.ReactionCount{Type: t, Count: n})
+		}
+		sort.Slice(out[id], func(i, j int) bool { return out[id][i].Type < out[id][j].Type })
+	}
+	return out, nil
+}
+
+func (r *reactionRepo) ByUser(ctx context.Context, targetType string, targetIDs []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	want := idSet(targetIDs)
+	out := make(map[uuid.UUID]string)
+	for _, rx := range r.s.reactions {
+		if rx.TargetType == targetType && rx.UserID == userID && want[rx.TargetID] && r.targetInCommunity(ctx, targetType, rx.TargetID) {
+			out[rx.TargetID] = rx.Type
+		}
+	}
+	return out, nil
+}
+
+func (r *reactionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Reaction, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.s.userInCommunity(ctx, userID) {
+		return nil, nil
+	}
+	var out []models.Reaction
+	for _, rx := range r.s.reactions {
+		if rx.UserID == userID {
+			out = append(out, rx)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+type reactionTypeRepo struct {
+	s *Store
+}
+
+func (r *reactionTypeRepo) List(_ context.Context) ([]models.ReactionType, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := make([]models.ReactionType, 0, len(r.s.reactionTypes))
+	for _, rt := range r.s.reactionTypes {
+		out = append(out, rt)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Shortcode < out[j].Shortcode
+	})
+	return out, nil
+}
+
+func (r *reactionTypeRepo) Insert(_ context.Context, rt *models.ReactionType) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.reactionTypes[rt.Shortcode]; ok {
+		return models.ErrConflict
+	}
+	rt.CreatedAt = time.Now().UTC()
+	r.s.reactionTypes[rt.Shortcode] = *rt
+	return nil
+}
+
+func (r *reactionTypeRepo) Delete(_ context.Context, shortcode string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	_, ok := r.s.reactionTypes[shortcode]
+	delete(r.s.reactionTypes, shortcode)
+	return ok, nil
+}
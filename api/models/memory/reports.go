@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type reportRepo struct {
+	s *Store
+}
+
+func (r *reportRepo) Insert(ctx context.Context, rep *models.Report) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if rep.ID == uuid.Nil {
+		rep.ID = uuid.New()
+	}
+	if _, ok := r.s.reports[rep.ID]; ok {
+		return models.ErrConflict
+	}
+	if _, ok := r.s.users[rep.ReporterID]; !ok {
+		return models.ErrInvalidReference
+	}
+	for _, other := range r.s.reports {
+		if other.Status != models.ReportResolved && other.TargetType == rep.TargetType &&
+			other.TargetID == rep.TargetID && other.ReporterID == rep.ReporterID {
+			return models.ErrConflict
+		}
+	}
+	rep.Status = models.ReportOpen
+	rep.CommunityID = insertCommunity(ctx)
+	now := r.s.stamp(rep.ID)
+	rep.CreatedAt, rep.UpdatedAt = now, now
+	r.s.reports[rep.ID] = *rep
+	return nil
+}
+
+func (r *reportRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rep, ok := r.s.reports[id]
+	if !ok || !inCommunity(ctx, rep.CommunityID) {
+		return nil, nil
+	}
+	return &rep, nil
+}
+
+func (r *reportRepo) List(ctx context.Context, f models.ReportFilter) ([]models.Report, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Report
+	for _, rep := range r.s.reports {
+		switch {
+		case !inCommunity(ctx, rep.CommunityID),
+			f.Status != "" && rep.Status != f.Status,
+			f.TargetType != "" && rep.TargetType != f.TargetType,
+			f.Reason != "" && rep.Reason != f.Reason,
+			f.ClaimedBy != nil && (rep.ClaimedBy == nil || *rep.ClaimedBy != *f.ClaimedBy),
+			f.Unclaimed && rep.ClaimedBy != nil:
+			continue
+		}
+		out = append(out, rep)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[j].ID, out[i].ID, out[j].CreatedAt, out[i].CreatedAt)
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func (r *reportRepo) CountOpenReporters(ctx context.Context, targetType string, targetID uuid.UUID) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	reporters := map[uuid.UUID]bool{}
+	for _, rep := range r.s.reports {
+		if rep.Status != models.ReportResolved && rep.TargetType == targetType && rep.TargetID == targetID && inCommunity(ctx, rep.CommunityID) {
+			reporters[rep.ReporterID] = true
+		}
+	}
+	return len(reporters), nil
+}
+
+func (r *reportRepo) HasResolution(ctx context.Context, targetType string, targetID uuid.UUID, resolution string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, rep := range r.s.reports {
+		if rep.Status == models.ReportResolved && rep.TargetType == targetType && rep.TargetID == targetID &&
+			rep.Resolution != nil && *rep.Resolution == resolution && inCommunity(ctx, rep.CommunityID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *reportRepo) Claim(ctx context.Context, id, moderatorID uuid.UUID) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rep, ok := r.s.reports[id]
+	if !ok || !inCommunity(ctx, rep.CommunityID) || rep.Status == models.ReportResolved || (rep.ClaimedBy != nil && *rep.ClaimedBy != moderatorID) {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	rep.Status = models.ReportClaimed
+	rep.ClaimedBy, rep.ClaimedAt, rep.UpdatedAt = &moderatorID, &now, now
+	r.s.reports[id] = rep
+	return true, nil
+}
+
+func (r *reportRepo) ResolveTarget(ctx context.Context, targetType string, targetID uuid.UUID, resolution string, note *string, moderatorID uuid.UUID) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now().UTC()
+	n := 0
+	for id, rep := range r.s.reports {
+		if rep.Status == models.ReportResolved || rep.TargetType != targetType || rep.TargetID != targetID || !inCommunity(ctx, rep.CommunityID) {
+			continue
+		}
+		rep.Status = models.ReportResolved
+		rep.Resolution, rep.ResolutionNote = &resolution, note
+		rep.ResolvedBy, rep.ResolvedAt, rep.UpdatedAt = &moderatorID, &now, now
+		if rep.ClaimedBy == nil {
+			rep.ClaimedBy, rep.ClaimedAt = &moderatorID, &now
+		}
+		r.s.reports[id] = rep
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,298 @@
+// Package memory provides in-memory implementations of the models repositories.
+// They mirror the Postgres semantics closely enough for service and route tests
+// (unique constraints, foreign keys, ordering) without a database.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+var (
+	_ models.CommunityRepo     = (*communityRepo)(nil)
+	_ models.UserRepo          = (*userRepo)(nil)
+	_ models.InviteRepo        = (*inviteRepo)(nil)
+	_ models.UnitRepo          = (*unitRepo)(nil)
+	_ models.HouseholdRepo     = (*householdRepo)(nil)
+	_ models.BoardRepo         = (*boardRepo)(nil)
+	_ models.BoardMemberRepo   = (*boardMemberRepo)(nil)
+	_ models.SubscriptionRepo  = (*subscriptionRepo)(nil)
+	_ models.PostRepo          = (*postRepo)(nil)
+	_ models.CommentRepo       = (*commentRepo)(nil)
+	_ models.BlockRepo         = (*blockRepo)(nil)
+	_ models.DataExportRepo    = (*dataExportRepo)(nil)
+	_ models.ReactionRepo      = (*reactionRepo)(nil)
+	_ models.ReactionTypeRepo  = (*reactionTypeRepo)(nil)
+	_ models.ReportRepo        = (*reportRepo)(nil)
+	_ models.WarningRepo       = (*warningRepo)(nil)
+	_ models.AccountActionRepo = (*accountActionRepo)(nil)
+	_ models.AuditRepo         = (*auditRepo)(nil)
+	_ models.RateLimitRepo     = (*rateLimitRepo)(nil)
+)
+
+// Store holds every table in memory behind a single lock so that repositories
+// can check cross-table constraints consistently.
+type Store struct {
+	mu sync.RWMutex
+
+	// seq orders rows inserted within the same clock tick.
+	seq   int64
+	order map[uuid.UUID]int64
+
+	communities   map[uuid.UUID]models.Community
+	users         map[uuid.UUID]models.User
+	invites       map[uuid.UUID]models.Invite
+	units         map[uuid.UUID]models.Unit
+	roster        map[uuid.UUID]models.RosterEntry
+	households    map[uuid.UUID]models.Household
+	boards        map[uuid.UUID]models.Board
+	members       map[memberKey]models.BoardMember
+	subscriptions map[subscriptionKey]models.Subscription
+	posts         map[uuid.UUID]models.Post
+	comments      map[uuid.UUID]models.Comment
+	blocks        map[blockKey]models.Block
+	exports       map[uuid.UUID]exportRow
+	reactions     map[uuid.UUID]models.Reaction
+	reactionTypes map[string]models.ReactionType
+	reports       map[uuid.UUID]models.Report
+	warnings      map[uuid.UUID]models.Warning
+	actions       map[uuid.UUID]models.AccountAction
+	audit         map[uuid.UUID]models.AuditEvent
+	buckets       map[string]bucket
+}
+
+// NewStore returns an empty Store with the default community and reaction catalog.
+func NewStore() *Store {
+	s := &Store{
+		order:         map[uuid.UUID]int64{},
+		communities:   map[uuid.UUID]models.Community{},
+		users:         map[uuid.UUID]models.User{},
+		invites:       map[uuid.UUID]models.Invite{},
+		units:         map[uuid.UUID]models.Unit{},
+		roster:        map[uuid.UUID]models.RosterEntry{},
+		households:    map[uuid.UUID]models.Household{},
+		boards:        map[uuid.UUID]models.Board{},
+		members:       map[memberKey]models.BoardMember{},
+		subscriptions: map[subscriptionKey]models.Subscription{},
+		posts:         map[uuid.UUID]models.Post{},
+		comments:      map[uuid.UUID]models.Comment{},
+		blocks:        map[blockKey]models.Block{},
+		exports:       map[uuid.UUID]exportRow{},
+		reactions:     map[uuid.UUID]models.Reaction{},
+		reactionTypes: map[string]models.ReactionType{},
+		reports:       map[uuid.UUID]models.Report{},
+		warnings:      map[uuid.UUID]models.Warning{},
+		actions:       map[uuid.UUID]models.AccountAction{},
+		audit:         map[uuid.UUID]models.AuditEvent{},
+		buckets:       map[string]bucket{},
+	}
+	s.communities[models.DefaultCommunityID] = models.Community{
+		ID:        models.DefaultCommunityID,
+		Slug:      models.DefaultCommunitySlug,
+		Name:      "Default community",
+		CreatedAt: s.stamp(models.DefaultCommunityID),
+	}
+	for i, rt := range models.DefaultReactionTypes {
+		rt.CreatedAt = time.Now().UTC().Add(time.Duration(i) * time.Microsecond)
+		s.reactionTypes[rt.Shortcode] = rt
+	}
+	return s
+}
+
+// NewRepos returns repositories backed by a fresh Store.
+func NewRepos() *models.Repos {
+	return NewStore().Repos()
+}
+
+// Repos returns repositories backed by this Store.
+func (s *Store) Repos() *models.Repos {
+	return &models.Repos{
+		Communities:   &communityRepo{s: s},
+		Users:         &userRepo{s: s},
+		Invites:       &inviteRepo{s: s},
+		Units:         &unitRepo{s: s},
+		Households:    &householdRepo{s: s},
+		Boards:        &boardRepo{s: s},
+		Members:       &boardMemberRepo{s: s},
+		Subscriptions: &subscriptionRepo{s: s},
+		Posts:         &postRepo{s: s},
+		Comments:      &commentRepo{s: s},
+		Blocks:        &blockRepo{s: s},
+		Exports:       &dataExportRepo{s: s},
+		Reactions:     &reactionRepo{s: s},
+		ReactionTypes: &reactionTypeRepo{s: s},
+		Reports:       &reportRepo{s: s},
+		Warnings:      &warningRepo{s: s},
+		Accounts:      &accountActionRepo{s: s},
+		Audit:         &auditRepo{s: s},
+		RateLimits:    &rateLimitRepo{s: s},
+	}
+}
+
+// stamp records insertion order for id and returns the creation time. Callers must hold mu.
+func (s *Store) stamp(id uuid.UUID) time.Time {
+	s.seq++
+	s.order[id] = s.seq
+	return time.Now().UTC()
+}
+
+// hiddenAt mirrors SET hidden_at = CASE WHEN hidden THEN COALESCE(hidden_at, NOW()) ELSE NULL END.
+func hiddenAt(current *time.Time, hidden bool) *time.Time {
+	if !hidden {
+		return nil
+	}
+	if current != nil {
+		return current
+	}
+	now := time.Now().UTC()
+	return &now
+}
+
+// idSet indexes ids for membership checks.
+func idSet(ids []uuid.UUID) map[uuid.UUID]bool {
+	out := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out
+}
+
+// newer reports whether a was inserted after b, breaking timestamp ties by insertion order.
+func (s *Store) newer(a, b uuid.UUID, at, bt time.Time) bool {
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+	return s.order[a] > s.order[b]
+}
+
+// deletePost removes a post and cascades to its comments and reactions. Callers must hold mu.
+func (s *Store) deletePost(id uuid.UUID) {
+	delete(s.posts, id)
+	for commentID, c := range s.comments {
+		if c.PostID == id {
+			s.deleteComment(commentID)
+		}
+	}
+	for reactionID, rx := range s.reactions {
+		if rx.TargetType == models.ReactionOnPost && rx.TargetID == id {
+			delete(s.reactions, reactionID)
+		}
+	}
+}
+
+// deleteComment removes a comment and its reactions. Callers must hold mu.
+func (s *Store) deleteComment(id uuid.UUID) {
+	delete(s.comments, id)
+	for reactionID, rx := range s.reactions {
+		if rx.TargetType == models.ReactionOnComment && rx.TargetID == id {
+			delete(s.reactions, reactionID)
+		}
+	}
+}
+
+// deleteHousehold removes a household, unlinks its members and deletes its
+// invites, mirroring the foreign keys that reference households. Callers must hold mu.
+func (s *Store) deleteHousehold(id uuid.UUID) {
+	delete(s.households, id)
+	for userID, u := range s.users {
+		if u.HouseholdID != nil && *u.HouseholdID == id {
+			u.HouseholdID = nil
+			s.users[userID] = u
+		}
+	}
+	for inviteID, i := range s.invites {
+		if i.HouseholdID != nil && *i.HouseholdID == id {
+			delete(s.invites, inviteID)
+		}
+	}
+}
+
+// deleteUser removes a user and mirrors the ON DELETE CASCADE and SET NULL
+// foreign keys that reference users. Callers must hold mu.
+func (s *Store) deleteUser(id uuid.UUID) {
+	delete(s.users, id)
+	for postID, p := range s.posts {
+		if p.AuthorID == id {
+			s.deletePost(postID)
+		}
+	}
+	for commentID, c := range s.comments {
+		if c.AuthorID == id {
+			s.deleteComment(commentID)
+		}
+	}
+	for reactionID, rx := range s.reactions {
+		if rx.UserID == id {
+			delete(s.reactions, reactionID)
+		}
+	}
+	for inviteID, i := range s.invites {
+		i.CreatedBy = clearRef(i.CreatedBy, id)
+		s.invites[inviteID] = i
+	}
+	for key, m := range s.members {
+		switch {
+		case key.userID == id:
+			delete(s.members, key)
+		case m.InvitedBy != nil && *m.InvitedBy == id:
+			m.InvitedBy = nil
+			s.members[key] = m
+		}
+	}
+	for key := range s.subscriptions {
+		if key.userID == id {
+			delete(s.subscriptions, key)
+		}
+	}
+	for key, b := range s.blocks {
+		if b.UserID == id || b.TargetID == id {
+			delete(s.blocks, key)
+		}
+	}
+	s.deleteExports(id)
+	for reportID, r := range s.reports {
+		if r.ReporterID == id {
+			delete(s.reports, reportID)
+			continue
+		}
+		r.ClaimedBy = clearRef(r.ClaimedBy, id)
+		r.ResolvedBy = clearRef(r.ResolvedBy, id)
+		s.reports[reportID] = r
+	}
+	for warningID, w := range s.warnings {
+		if w.UserID == id {
+			delete(s.warnings, warningID)
+			continue
+		}
+		w.IssuedBy = clearRef(w.IssuedBy, id)
+		s.warnings[warningID] = w
+	}
+	for actionID, a := range s.actions {
+		if a.UserID == id {
+			delete(s.actions, actionID)
+			continue
+		}
+		a.ActorID = clearRef(a.ActorID, id)
+		s.actions[actionID] = a
+	}
+}
+
+// clearRef mirrors ON DELETE SET NULL for a reference to the deleted id.
+func clearRef(ref *uuid.UUID, id uuid.UUID) *uuid.UUID {
+	if ref != nil && *ref == id {
+		return nil
+	}
+	return ref
+}
+
+// deleteExports removes a user's data exports. Callers must hold mu.
+func (s *Store) deleteExports(userID uuid.UUID) {
+	for exportID, row := range s.exports {
+		if row.UserID == userID {
+			delete(s.exports, exportID)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type subscriptionKey struct {
+	userID, boardID uuid.UUID
+}
+
+type subscriptionRepo struct {
+	s *Store
+}
+
+// includesPost reports whether a subscription level surfaces the post.
+func includesPost(level string, p models.Post) bool {
+	switch level {
+	case models.SubscriptionAll:
+		return true
+	case models.SubscriptionBulletins:
+		return p.IsBulletin
+	default:
+		return false
+	}
+}
+
+func (r *subscriptionRepo) Upsert(ctx context.Context, sub *models.Subscription) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.userInCommunity(ctx, sub.UserID) || !r.s.boardInCommunity(ctx, sub.BoardID) {
+		return models.ErrInvalidReference
+	}
+	key := subscriptionKey{sub.UserID, sub.BoardID}
+	now := time.Now().UTC()
+	current, ok := r.s.subscriptions[key]
+	if !ok {
+		current = models.Subscription{UserID: sub.UserID, BoardID: sub.BoardID, LastReadAt: now, CreatedAt: now}
+	}
+	current.Level = sub.Level
+	current.UpdatedAt = now
+	r.s.subscriptions[key] = current
+	*sub = current
+	return nil
+}
+
+func (r *subscriptionRepo) Delete(ctx context.Context, userID, boardID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.boardInCommunity(ctx, boardID) {
+		return nil
+	}
+	delete(r.s.subscriptions, subscriptionKey{userID, boardID})
+	return nil
+}
+
+func (r *subscriptionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Subscription, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.Subscription
+	for key, sub := range r.s.subscriptions {
+		if key.userID != userID || !r.s.boardInCommunity(ctx, key.boardID) {
+			continue
+		}
+		sub.UnreadCount = 0
+		if !r.s.canRead(userID, sub.BoardID) {
+			out = append(out, sub)
+			continue
+		}
+		for _, p := range r.s.posts {
+			if p.BoardID == sub.BoardID && p.CreatedAt.After(sub.LastReadAt) && p.HiddenAt == nil &&
+				p.AuthorID != userID && includesPost(sub.Level, p) && !r.s.hiddenFrom(&userID, p.AuthorID, true) {
+				sub.UnreadCount++
+			}
+		}
+		out = append(out, sub)
+	}
+	return out, nil
+}
+
+func (r *subscriptionRepo) MarkRead(ctx context.Context, userID, boardID uuid.UUID) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := subscriptionKey{userID, boardID}
+	sub, ok := r.s.subscriptions[key]
+	if !ok || !r.s.boardInCommunity(ctx, boardID) {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	sub.LastReadAt, sub.UpdatedAt = now, now
+	r.s.subscriptions[key] = sub
+	return true, nil
+}
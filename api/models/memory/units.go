@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type unitRepo struct {
+	s *Store
+}
+
+func (r *unitRepo) Upsert(ctx context.Context, u *models.Unit) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u.CommunityID = insertCommunity(ctx)
+	now := time.Now().UTC()
+	for id, existing := range r.s.units {
+		if existing.CommunityID == u.CommunityID && existing.Building == u.Building && strings.EqualFold(existing.UnitNumber, u.UnitNumber) {
+			if u.Floor != nil {
+				existing.Floor = u.Floor
+			}
+			existing.Occupancy, existing.UpdatedAt = u.Occupancy, now
+			r.s.units[id] = existing
+			*u = existing
+			return false, nil
+		}
+	}
+	u.ID = uuid.New()
+	u.CreatedAt = r.s.stamp(u.ID)
+	u.UpdatedAt = u.CreatedAt
+	r.s.units[u.ID] = *u
+	return true, nil
+}
+
+func (r *unitRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Unit, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.units[id]
+	if !ok || !inCommunity(ctx, u.CommunityID) {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+// units returns ctx's units that keep accepts, by building and number. Callers must hold mu.
+func (r *unitRepo) units(ctx context.Context, keep func(models.Unit) bool) []models.Unit {
+	var out []models.Unit
+	for _, u := range r.s.units {
+		if inCommunity(ctx, u.CommunityID) && keep(u) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Building != out[j].Building {
+			return out[i].Building < out[j].Building
+		}
+		return strings.ToLower(out[i].UnitNumber) < strings.ToLower(out[j].UnitNumber)
+	})
+	return out
+}
+
+func (r *unitRepo) List(ctx context.Context) ([]models.Unit, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.units(ctx, func(models.Unit) bool { return true }), nil
+}
+
+func (r *unitRepo) ListByNumber(ctx context.Context, unitNumber string) ([]models.Unit, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.units(ctx, func(u models.Unit) bool { return strings.EqualFold(u.UnitNumber, unitNumber) }), nil
+}
+
+func (r *unitRepo) SetOccupancy(ctx context.Context, id uuid.UUID, occupancy string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.units[id]
+	if !ok || !inCommunity(ctx, u.CommunityID) {
+		return nil
+	}
+	u.Occupancy, u.UpdatedAt = occupancy, time.Now().UTC()
+	r.s.units[id] = u
+	return nil
+}
+
+func (r *unitRepo) SetAutoVerify(ctx context.Context, id uuid.UUID, autoVerify bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.units[id]
+	if !ok || !inCommunity(ctx, u.CommunityID) {
+		return nil
+	}
+	u.AutoVerify, u.UpdatedAt = autoVerify, time.Now().UTC()
+	r.s.units[id] = u
+	return nil
+}
+
+func (r *unitRepo) Count(ctx context.Context) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return len(r.units(ctx, func(models.Unit) bool { return true })), nil
+}
+
+func (r *unitRepo) AddRosterEmail(ctx context.Context, e *models.RosterEntry) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e.CommunityID = insertCommunity(ctx)
+	if u, ok := r.s.units[e.UnitID]; !ok || u.CommunityID != e.CommunityID {
+		return false, models.ErrInvalidReference
+	}
+	for id, existing := range r.s.roster {
+		if existing.CommunityID == e.CommunityID && existing.Email == e.Email {
+			existing.UnitID = e.UnitID
+			r.s.roster[id] = existing
+			*e = existing
+			return false, nil
+		}
+	}
+	e.ID = uuid.New()
+	e.CreatedAt = r.s.stamp(e.ID)
+	r.s.roster[e.ID] = *e
+	return true, nil
+}
+
+func (r *unitRepo) GetRosterEntry(ctx context.Context, email string) (*models.RosterEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, e := range r.s.roster {
+		if e.Email == email && inCommunity(ctx, e.CommunityID) {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *unitRepo) ListRoster(ctx context.Context) ([]models.RosterEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.RosterEntry
+	for _, e := range r.s.roster {
+		if inCommunity(ctx, e.CommunityID) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Email < out[j].Email })
+	return out, nil
+}
@@ -0,0 +1,401 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type userRepo struct {
+	s *Store
+}
+
+// user returns the user with id if it is in ctx's community. Callers must hold mu.
+func (r *userRepo) user(ctx context.Context, id uuid.UUID) (models.User, bool) {
+	u, ok := r.s.users[id]
+	if !ok || !inCommunity(ctx, u.CommunityID) {
+		return models.User{}, false
+	}
+	return u, true
+}
+
+func (r *userRepo) Insert(ctx context.Context, u *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	if _, ok := r.s.users[u.ID]; ok {
+		return models.ErrConflict
+	}
+	for _, existing := range r.s.users {
+		if existing.Email == u.Email {
+			return models.ErrConflict
+		}
+	}
+	if u.HouseholdID != nil {
+		if _, ok := r.s.households[*u.HouseholdID]; !ok {
+			return models.ErrInvalidReference
+		}
+	}
+	u.CommunityID = insertCommunity(ctx)
+	if u.Verification == "" {
+		u.Verification = models.VerificationUnverified
+	}
+	now := r.s.stamp(u.ID)
+	u.CreatedAt, u.UpdatedAt = now, now
+	r.s.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, u := range r.s.users {
+		if u.Email == email && inCommunity(ctx, u.CommunityID) {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *userRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.user(ctx, id)
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (r *userRepo) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.User
+	for id := range idSet(ids) {
+		if u, ok := r.user(ctx, id); ok {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (r *userRepo) Update(ctx context.Context, u *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.user(ctx, u.ID)
+	if !ok {
+		// Mirrors UPDATE ... RETURNING on a missing row.
+		return pgx.ErrNoRows
+	}
+	for id, other := range r.s.users {
+		if id != u.ID && other.Email == u.Email {
+			return models.ErrConflict
+		}
+	}
+	if u.HouseholdID != nil {
+		if _, ok := r.s.households[*u.HouseholdID]; !ok {
+			return models.ErrInvalidReference
+		}
+	}
+	u.CommunityID, u.CreatedAt = existing.CommunityID, existing.CreatedAt
+	// Like the SQL UPDATE, login-failure state, moderation hides, roles and
+	// account status are only changed by their own methods.
+	u.FailedLoginAttempts, u.LockedUntil = existing.FailedLoginAttempts, existing.LockedUntil
+	u.ModerationHidden = existing.ModerationHidden
+	u.IsAdmin, u.IsModerator = existing.IsAdmin, existing.IsModerator
+	u.Status, u.StatusReason, u.SuspendedUntil = existing.Status, existing.StatusReason, existing.SuspendedUntil
+	u.DeletionRequestedAt, u.TokenVersion = existing.DeletionRequestedAt, existing.TokenVersion
+	u.UpdatedAt = time.Now().UTC()
+	r.s.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) SetRoles(ctx context.Context, id uuid.UUID, isAdmin, isModerator bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.user(ctx, id)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	u.IsAdmin, u.IsModerator = isAdmin, isModerator
+	u.UpdatedAt = time.Now().UTC()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *userRepo) SetStatus(ctx context.Context, in *models.User, revoke bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.user(ctx, in.ID)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	u.Status, u.StatusReason, u.SuspendedUntil = in.Status, in.StatusReason, in.SuspendedUntil
+	u.DeletionRequestedAt = in.DeletionRequestedAt
+	if revoke {
+		u.TokenVersion++
+	}
+	u.UpdatedAt = time.Now().UTC()
+	r.s.users[u.ID] = u
+	in.TokenVersion, in.UpdatedAt = u.TokenVersion, u.UpdatedAt
+	return nil
+}
+
+func (r *userRepo) RecordLoginFailure(ctx context.Context, id uuid.UUID) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.user(ctx, id)
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	u.FailedLoginAttempts++
+	r.s.users[id] = u
+	return u.FailedLoginAttempts, nil
+}
+
+func (r *userRepo) LockUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u, ok := r.user(ctx, id); ok {
+		u.LockedUntil = &until
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r *userRepo) ResetLoginFailures(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u, ok := r.user(ctx, id); ok {
+		u.FailedLoginAttempts, u.LockedUntil = 0, nil
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r *userRepo) SetModerationHidden(ctx context.Context, id uuid.UUID, hidden bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u, ok := r.user(ctx, id); ok {
+		u.ModerationHidden = hidden
+		u.UpdatedAt = time.Now().UTC()
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r *userRepo) SoftDelete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u, ok := r.user(ctx, id); ok {
+		now := time.Now().UTC()
+		u.Status = models.UserInactive
+		if u.DeletionRequestedAt == nil {
+			u.DeletionRequestedAt = &now
+		}
+		u.TokenVersion++
+		u.UpdatedAt = now
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r *userRepo) ListDeletionDue(ctx context.Context, cutoff time.Time) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var due []models.User
+	for _, u := range r.s.users {
+		if u.Status == models.UserInactive && u.DeletionRequestedAt != nil && !u.DeletionRequestedAt.After(cutoff) && inCommunity(ctx, u.CommunityID) {
+			due = append(due, u)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DeletionRequestedAt.Before(*due[j].DeletionRequestedAt) })
+	return due, nil
+}
+
+func (r *userRepo) Anonymize(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.user(ctx, id)
+	if !ok {
+		return nil
+	}
+	for key, b := range r.s.blocks {
+		if b.UserID == id || b.TargetID == id {
+			delete(r.s.blocks, key)
+		}
+	}
+	for key := range r.s.subscriptions {
+		if key.userID == id {
+			delete(r.s.subscriptions, key)
+		}
+	}
+	for key := range r.s.members {
+		if key.userID == id {
+			delete(r.s.members, key)
+		}
+	}
+	r.s.deleteExports(id)
+	r.s.users[id] = models.User{
+		ID:           id,
+		CommunityID:  u.CommunityID,
+		Email:        models.AnonymizedEmail(id),
+		Status:       models.UserDeleted,
+		Verification: u.Verification,
+		TokenVersion: u.TokenVersion + 1,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    time.Now().UTC(),
+	}
+	return nil
+}
+
+func (r *userRepo) ListForReview(ctx context.Context) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.User
+	for _, u := range r.s.users {
+		if u.Verification == models.VerificationReview && u.Status != models.UserDeleted && inCommunity(ctx, u.CommunityID) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[j].ID, out[i].ID, out[j].CreatedAt, out[i].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *userRepo) HasAdmin(ctx context.Context) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, u := range r.s.users {
+		if u.IsAdmin && u.Status != models.UserDeleted && inCommunity(ctx, u.CommunityID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *userRepo) ListByHousehold(ctx context.Context, householdID uuid.UUID) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.User
+	for _, u := range r.s.users {
+		if u.HouseholdID != nil && *u.HouseholdID == householdID && inCommunity(ctx, u.CommunityID) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[j].ID, out[i].ID, out[j].CreatedAt, out[i].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *userRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.user(ctx, id); ok {
+		r.s.deleteUser(id)
+	}
+	return nil
+}
+
+func (r *userRepo) ListDirectory(ctx context.Context, f models.DirectoryFilter) ([]models.DirectoryUser, error) {
+	if f.Viewer == nil && f.HasCriteria() {
+		return nil, nil
+	}
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	query := strings.ToLower(f.Query)
+	type match struct {
+		du    models.DirectoryUser
+		score float64
+	}
+	var found []match
+	for _, u := range r.s.users {
+		if !u.IsDirectoryOptIn || u.ModerationHidden || u.Status != models.UserActive || !inCommunity(ctx, u.CommunityID) {
+			continue
+		}
+		if f.Viewer != nil && r.s.hiddenFrom(&f.Viewer.ID, u.ID, false) {
+			continue
+		}
+		p := u.Privacy.Effective()
+		sees := func(audience string) bool { return models.CanSee(audience, u.ID, u.UnitNumber, f.Viewer) }
+		seesName, seesUnit := sees(p.Name), sees(p.Unit)
+		seesFloor := seesUnit || sees(p.Floor)
+		name := strings.ToLower(deref(u.DisplayName))
+		var score float64
+		if query != "" {
+			byName := seesName && u.DisplayName != nil && (strings.HasPrefix(name, query) ||
+				strings.Contains(name, " "+query) || models.SimilarEnough(name, query))
+			byUnit := seesUnit && strings.HasPrefix(strings.ToLower(u.UnitNumber), query)
+			if !byName && !byUnit {
+				continue
+			}
+			if seesName {
+				score = models.Similarity(name, query)
+			}
+		}
+		if f.Floor != "" && (!seesFloor || models.FloorOf(u.UnitNumber) != f.Floor) {
+			continue
+		}
+		if f.Building != "" && (!seesFloor || !strings.EqualFold(deref(u.Building), f.Building)) {
+			continue
+		}
+		if f.Interest != "" && (!sees(p.Bio) || !slices.Contains(u.Interests, strings.ToLower(f.Interest))) {
+			continue
+		}
+		household, listed := models.Household{}, false
+		if u.HouseholdID != nil {
+			household, listed = r.s.households[*u.HouseholdID]
+			listed = listed && household.DirectoryOptIn
+		}
+		if f.HouseholdID != nil && (!listed || household.ID != *f.HouseholdID) {
+			continue
+		}
+		du := models.DirectoryUser{
+			ID:                u.ID,
+			UnitNumber:        u.UnitNumber,
+			DisplayName:       u.DisplayName,
+			ProfilePictureURL: u.ProfilePictureURL,
+			Bio:               u.Bio,
+			ContactMethod:     u.ContactMethod,
+			Building:          u.Building,
+			Interests:         u.Interests,
+			Privacy:           u.Privacy,
+		}
+		if listed {
+			du.HouseholdID, du.HouseholdName = &household.ID, household.Name
+		}
+		found = append(found, match{score: score, du: du})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.du.UnitNumber != b.du.UnitNumber {
+			return a.du.UnitNumber < b.du.UnitNumber
+		}
+		return a.du.ID.String() < b.du.ID.String()
+	})
+	out := []models.DirectoryUser{}
+	for i := f.Offset; i < len(found) && len(out) < f.Limit; i++ {
+		out = append(out, found[i].du)
+	}
+	return out, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
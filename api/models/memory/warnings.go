@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+type warningRepo struct {
+	s *Store
+}
+
+func (r *warningRepo) Insert(ctx context.Context, w *models.Warning) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	if _, ok := r.s.warnings[w.ID]; ok {
+		return models.ErrConflict
+	}
+	if !r.s.userInCommunity(ctx, w.UserID) {
+		return models.ErrInvalidReference
+	}
+	w.CreatedAt = r.s.stamp(w.ID)
+	r.s.warnings[w.ID] = *w
+	return nil
+}
+
+func (r *warningRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Warning, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if !r.s.userInCommunity(ctx, userID) {
+		return nil, nil
+	}
+	var out []models.Warning
+	for _, w := range r.s.warnings {
+		if w.UserID == userID {
+			out = append(out, w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return r.s.newer(out[i].ID, out[j].ID, out[i].CreatedAt, out[j].CreatedAt)
+	})
+	return out, nil
+}
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"time"
 
-	"github.com/cameronsralla/culdechat/connectors/postgres"
 	"github.com/cameronsralla/culdechat/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // Post represents a post/thread in a board. Bulletin posts are marked with IsBulletin=true.
@@ -18,12 +18,14 @@ type Post struct {
 	Title      string
 	Content    string
 	IsBulletin bool
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	// HiddenAt is set when moderation hides the post from listings.
+	HiddenAt  *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // EnsurePostsTable creates the posts table if it doesn't exist.
-func EnsurePostsTable(ctx context.Context) error {
+func EnsurePostsTable(ctx context.Context, db DBTX) error {
 	const ddl = `
 CREATE TABLE IF NOT EXISTS posts (
 	id UUID PRIMARY KEY,
@@ -32,6 +34,7 @@ CREATE TABLE IF NOT EXISTS posts (
 	title VARCHAR NOT NULL,
 	content TEXT NOT NULL,
 	is_bulletin BOOLEAN NOT NULL DEFAULT FALSE,
+	hidden_at TIMESTAMPTZ NULL,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	CONSTRAINT fk_posts_board FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
@@ -40,103 +43,194 @@ CREATE TABLE IF NOT EXISTS posts (
 
 CREATE INDEX IF NOT EXISTS idx_posts_board ON posts (board_id, created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_posts_author ON posts (author_id);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ NULL;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
-	}
-	if _, err := p.Exec(ctx, ddl); err != nil {
+	if _, err := db.Exec(ctx, ddl); err != nil {
 		utils.Errorf("failed to ensure posts table: %v", err)
 		return err
 	}
 	return nil
 }
 
-// InsertPost inserts a new post.
-func InsertPost(ctx context.Context, pst *Post) error {
+// FeedCursor is the last post of a feed page. The next page continues with
+// the posts ordered after it by (created_at, id); a nil ID compares by time alone.
+type FeedCursor struct {
+	CreatedAt time.Time
+	ID        *uuid.UUID
+}
+
+type pgPostRepo struct {
+	db DBTX
+}
+
+// NewPgPostRepo returns a Postgres-backed PostRepo.
+func NewPgPostRepo(db DBTX) PostRepo {
+	return &pgPostRepo{db: db}
+}
+
+const postColumns = `id, board_id, author_id, title, content, is_bulletin, hidden_at, created_at, updated_at`
+
+func scanPost(row pgx.Row) (*Post, error) {
+	var pst Post
+	err := row.Scan(&pst.ID, &pst.BoardID, &pst.AuthorID, &pst.Title, &pst.Content, &pst.IsBulletin, &pst.HiddenAt, &pst.CreatedAt, &pst.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &pst, nil
+}
+
+func scanPosts(rows pgx.Rows) ([]Post, error) {
+	defer rows.Close()
+	var out []Post
+	for rows.Next() {
+		pst, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *pst)
+	}
+	return out, rows.Err()
+}
+
+// Insert inserts a new post. It returns ErrInvalidReference when the board
+// is not in the context's community.
+func (r *pgPostRepo) Insert(ctx context.Context, pst *Post) error {
 	if pst.ID == uuid.Nil {
 		pst.ID = uuid.New()
 	}
-	const q = `
+	q := `
 INSERT INTO posts (id, board_id, author_id, title, content, is_bulletin)
-VALUES ($1, $2, $3, $4, $5, $6)
+SELECT $1::uuid, $2::uuid, $3::uuid, $4::varchar, $5::text, $6::boolean
+WHERE ` + boardInCommunitySQL("$2::uuid", "$7") + `
 RETURNING created_at, updated_at;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
+	err := r.db.QueryRow(ctx, q, pst.ID, pst.BoardID, pst.AuthorID, pst.Title, pst.Content, pst.IsBulletin, communityArg(ctx)).Scan(&pst.CreatedAt, &pst.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
 	}
-	return p.QueryRow(ctx, q, pst.ID, pst.BoardID, pst.AuthorID, pst.Title, pst.Content, pst.IsBulletin).Scan(&pst.CreatedAt, &pst.UpdatedAt)
+	return translateErr(err)
 }
 
-// ListPostsByBoard returns posts for a board, newest first.
-func ListPostsByBoard(ctx context.Context, boardID uuid.UUID) ([]Post, error) {
-	const q = `
-SELECT id, board_id, author_id, title, content, is_bulletin, created_at, updated_at
-FROM posts WHERE board_id = $1
+// ListByBoard returns visible posts for a board, newest first, without those
+// by residents the viewer blocked, muted or was blocked by.
+func (r *pgPostRepo) ListByBoard(ctx context.Context, boardID uuid.UUID, viewerID *uuid.UUID) ([]Post, error) {
+	q := `
+SELECT ` + postColumns + `
+FROM posts WHERE board_id = $1 AND hidden_at IS NULL
+  AND ` + boardInCommunitySQL("posts.board_id", "$3") + `
+  AND NOT ` + hiddenFromSQL("posts.author_id", "$2", true) + `
 ORDER BY created_at DESC;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
-	}
-	rows, err := p.Query(ctx, q, boardID)
+	rows, err := r.db.Query(ctx, q, boardID, viewerID, communityArg(ctx))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanPosts(rows)
+}
 
-	var out []Post
-	for rows.Next() {
-		var pst Post
-		if err := rows.Scan(&pst.ID, &pst.BoardID, &pst.AuthorID, &pst.Title, &pst.Content, &pst.IsBulletin, &pst.CreatedAt, &pst.UpdatedAt); err != nil {
-			return nil, err
-		}
-		out = append(out, pst)
+// ListBulletins returns visible bulletin posts, newest first. Blocks apply
+// but mutes do not, so muting never hides announcements.
+func (r *pgPostRepo) ListBulletins(ctx context.Context, viewerID *uuid.UUID) ([]Post, error) {
+	q := `
+SELECT ` + postColumns + `
+FROM posts WHERE is_bulletin = TRUE AND hidden_at IS NULL
+  AND ` + boardInCommunitySQL("posts.board_id", "$2") + `
+  AND NOT ` + hiddenFromSQL("posts.author_id", "$1", false) + `
+ORDER BY created_at DESC;
+`
+	rows, err := r.db.Query(ctx, q, viewerID, communityArg(ctx))
+	if err != nil {
+		return nil, err
 	}
-	return out, rows.Err()
+	return scanPosts(rows)
 }
 
-// ListBulletins returns bulletin posts, newest first.
-func ListBulletins(ctx context.Context) ([]Post, error) {
-	const q = `
-SELECT id, board_id, author_id, title, content, is_bulletin, created_at, updated_at
-FROM posts WHERE is_bulletin = TRUE
+// ListByAuthor returns every post by the author, hidden ones included, newest first.
+func (r *pgPostRepo) ListByAuthor(ctx context.Context, authorID uuid.UUID) ([]Post, error) {
+	q := `
+SELECT ` + postColumns + `
+FROM posts WHERE author_id = $1 AND ` + boardInCommunitySQL("posts.board_id", "$2") + `
 ORDER BY created_at DESC;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
-	}
-	rows, err := p.Query(ctx, q)
+	rows, err := r.db.Query(ctx, q, authorID, communityArg(ctx))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var out []Post
-	for rows.Next() {
-		var pst Post
-		if err := rows.Scan(&pst.ID, &pst.BoardID, &pst.AuthorID, &pst.Title, &pst.Content, &pst.IsBulletin, &pst.CreatedAt, &pst.UpdatedAt); err != nil {
-			return nil, err
-		}
-		out = append(out, pst)
+	return scanPosts(rows)
+}
+
+// GetByID fetches a single post by id, including hidden posts.
+func (r *pgPostRepo) GetByID(ctx context.Context, id uuid.UUID) (*Post, error) {
+	q := `
+SELECT ` + postColumns + `
+FROM posts WHERE id = $1 AND ` + boardInCommunitySQL("posts.board_id", "$2") + ` LIMIT 1;
+`
+	pst, err := scanPost(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-	return out, rows.Err()
+	return pst, err
 }
 
-// GetPostByID fetches a single post by id.
-func GetPostByID(ctx context.Context, id uuid.UUID) (*Post, error) {
-	const q = `
-SELECT id, board_id, author_id, title, content, is_bulletin, created_at, updated_at
-FROM posts WHERE id = $1 LIMIT 1;
+// SetHidden hides the post from listings or restores it.
+func (r *pgPostRepo) SetHidden(ctx context.Context, id uuid.UUID, hidden bool) error {
+	q := `
+UPDATE posts SET hidden_at = CASE WHEN $2 THEN COALESCE(hidden_at, NOW()) ELSE NULL END
+WHERE id = $1 AND ` + boardInCommunitySQL("posts.board_id", "$3") + `;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
+	_, err := r.db.Exec(ctx, q, id, hidden, communityArg(ctx))
+	return err
+}
+
+// MoveBoard moves every post on one board to another and returns how many
+// moved. Both boards must be in the context's community.
+func (r *pgPostRepo) MoveBoard(ctx context.Context, from, to uuid.UUID) (int, error) {
+	var ok bool
+	check := `SELECT ` + boardInCommunitySQL("$1::uuid", "$2") + `;`
+	if err := r.db.QueryRow(ctx, check, to, communityArg(ctx)).Scan(&ok); err != nil {
+		return 0, err
 	}
-	var pst Post
-	if err := p.QueryRow(ctx, q, id).Scan(&pst.ID, &pst.BoardID, &pst.AuthorID, &pst.Title, &pst.Content, &pst.IsBulletin, &pst.CreatedAt, &pst.UpdatedAt); err != nil {
+	if !ok {
+		return 0, ErrInvalidReference
+	}
+	q := `UPDATE posts SET board_id = $2, updated_at = NOW() WHERE board_id = $1 AND ` + boardInCommunitySQL("posts.board_id", "$3") + `;`
+	tag, err := r.db.Exec(ctx, q, from, to, communityArg(ctx))
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ListFeed returns visible posts from the user's subscribed boards, newest
+// first: every post for "all" subscriptions and only bulletins for "bulletins".
+// When after is set only posts past that cursor are returned. Posts by blocked
+// or muted residents and on boards the user cannot read are left out, so
+// every page is full.
+func (r *pgPostRepo) ListFeed(ctx context.Context, userID uuid.UUID, after *FeedCursor, limit int) ([]Post, error) {
+	var before *time.Time
+	var beforeID *uuid.UUID
+	if after != nil {
+		before, beforeID = &after.CreatedAt, after.ID
+	}
+	q := `
+SELECT ` + postColumns + `
+FROM posts
+WHERE hidden_at IS NULL
+  AND ($2::timestamptz IS NULL OR created_at < $2 OR (created_at = $2 AND id < $5::uuid))
+  AND ` + boardInCommunitySQL("posts.board_id", "$4") + `
+  AND NOT ` + hiddenFromSQL("posts.author_id", "$1", true) + `
+  AND ` + readableBoardSQL("posts.board_id", "$1") + `
+  AND board_id IN (
+	SELECT board_id FROM board_subscriptions
+	WHERE user_id = $1 AND (level = 'all' OR (level = 'bulletins' AND posts.is_bulletin))
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $3;
+`
+	rows, err := r.db.Query(ctx, q, userID, before, limit, communityArg(ctx), beforeID)
+	if err != nil {
 		return nil, err
 	}
-	return &pst, nil
+	return scanPosts(rows)
 }
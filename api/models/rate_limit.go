@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+)
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed bool
+	// Tokens left in the bucket after the request (fractional while refilling).
+	Tokens float64
+}
+
+// EnsureRateLimitTable creates the token bucket table used by the rate limiter.
+func EnsureRateLimitTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_refreshed_at ON rate_limit_buckets (refreshed_at);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure rate_limit_buckets table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgRateLimitRepo struct {
+	db DBTX
+}
+
+// NewPgRateLimitRepo returns a Postgres-backed RateLimitRepo shared by every API instance.
+func NewPgRateLimitRepo(db DBTX) RateLimitRepo {
+	return &pgRateLimitRepo{db: db}
+}
+
+// Take refills and debits the bucket in a single upsert so concurrent requests
+// on any instance see a consistent count. The database clock is used throughout.
+func (r *pgRateLimitRepo) Take(ctx context.Context, key string, capacity, perSecond float64) (RateLimitResult, error) {
+	// refilled is the bucket level after adding tokens for the time elapsed since the last request.
+	const refilled = `LEAST($2::double precision,
+        b.tokens + EXTRACT(EPOCH FROM (NOW() - b.refreshed_at))::double precision * $3::double precision)`
+	const q = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, refreshed_at)
+VALUES ($1, $2::double precision - 1, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    tokens = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+    allowed = ` + refilled + ` >= 1,
+    refreshed_at = NOW()
+RETURNING allowed, tokens;
+`
+	var res RateLimitResult
+	err := r.db.QueryRow(ctx, q, key, capacity, perSecond).Scan(&res.Allowed, &res.Tokens)
+	return res, err
+}
+
+// Prune deletes buckets untouched for longer than idle; they would be full again anyway.
+func (r *pgRateLimitRepo) Prune(ctx context.Context, idle time.Duration) error {
+	const q = `DELETE FROM rate_limit_buckets WHERE refreshed_at < NOW() - $1::double precision * INTERVAL '1 second';`
+	_, err := r.db.Exec(ctx, q, idle.Seconds())
+	return err
+}
@@ -3,101 +3,141 @@ package models
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
-	"github.com/cameronsralla/culdechat/connectors/postgres"
 	"github.com/cameronsralla/culdechat/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
-// ReactionType is a constrained set of supported emoji keywords for MVP.
-// Stored as a short string, e.g., "like", "love", "laugh", "wow", "sad", "angry".
+// Reaction targets. Each user has at most one reaction per post and per comment.
+const (
+	ReactionOnPost    = "post"
+	ReactionOnComment = "comment"
+)
+
+// Reaction is a user's emoji reaction to a post or comment. Type is a
+// shortcode from the reaction catalog, e.g. "like".
 type Reaction struct {
-	ID        uuid.UUID
-	PostID    uuid.UUID
-	UserID    uuid.UUID
-	Type      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         uuid.UUID
+	TargetType string
+	TargetID   uuid.UUID
+	UserID     uuid.UUID
+	Type       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
-// EnsureReactionsTable creates the reactions table with a uniqueness constraint per user/post.
-func EnsureReactionsTable(ctx context.Context) error {
+// EnsureReactionsTable creates the reactions table with a uniqueness constraint per user and target.
+func EnsureReactionsTable(ctx context.Context, db DBTX) error {
 	const ddl = `
 CREATE TABLE IF NOT EXISTS reactions (
 	id UUID PRIMARY KEY,
-	post_id UUID NOT NULL,
+	post_id UUID NULL,
+	comment_id UUID NULL,
 	user_id UUID NOT NULL,
 	type VARCHAR NOT NULL,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 	CONSTRAINT fk_reactions_post FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	CONSTRAINT fk_reactions_comment FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
 	CONSTRAINT fk_reactions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-	CONSTRAINT uq_reaction_user_post UNIQUE (post_id, user_id)
+	CONSTRAINT uq_reaction_user_post UNIQUE (post_id, user_id),
+	CONSTRAINT chk_reactions_target CHECK (num_nonnulls(post_id, comment_id) = 1)
 );
 
+ALTER TABLE reactions ALTER COLUMN post_id DROP NOT NULL;
+ALTER TABLE reactions ADD COLUMN IF NOT EXISTS comment_id UUID NULL REFERENCES comments(id) ON DELETE CASCADE;
+
 CREATE INDEX IF NOT EXISTS idx_reactions_post ON reactions (post_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reaction_user_comment ON reactions (comment_id, user_id);
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_reactions_target') THEN
+		ALTER TABLE reactions ADD CONSTRAINT chk_reactions_target CHECK (num_nonnulls(post_id, comment_id) = 1);
+	END IF;
+END $$;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
-	}
-	if _, err := p.Exec(ctx, ddl); err != nil {
+	if _, err := db.Exec(ctx, ddl); err != nil {
 		utils.Errorf("failed to ensure reactions table: %v", err)
 		return err
 	}
 	return nil
 }
 
-// UpsertReaction inserts or updates a user's reaction on a post.
-func UpsertReaction(ctx context.Context, r *Reaction) error {
-	if r.ID == uuid.Nil {
-		r.ID = uuid.New()
+type pgReactionRepo struct {
+	db DBTX
+}
+
+// NewPgReactionRepo returns a Postgres-backed ReactionRepo.
+func NewPgReactionRepo(db DBTX) ReactionRepo {
+	return &pgReactionRepo{db: db}
+}
+
+// reactionColumn names the column holding the target id for targetType.
+func reactionColumn(targetType string) string {
+	if targetType == ReactionOnComment {
+		return "comment_id"
 	}
-	const q = `
-INSERT INTO reactions (id, post_id, user_id, type)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (post_id, user_id)
-DO UPDATE SET type = EXCLUDED.type, updated_at = NOW()
-RETURNING created_at, updated_at;
-`
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
+	return "post_id"
+}
+
+// reactionTargetInCommunitySQL limits rows whose targetType target is in col
+// to the community in param. Comments are scoped through their post.
+func reactionTargetInCommunitySQL(targetType, col, param string) string {
+	if targetType == ReactionOnComment {
+		return `EXISTS (SELECT 1 FROM comments cc WHERE cc.id = ` + col + ` AND ` + postInCommunitySQL("cc.post_id", param) + `)`
 	}
-	return p.QueryRow(ctx, q, r.ID, r.PostID, r.UserID, r.Type).Scan(&r.CreatedAt, &r.UpdatedAt)
+	return postInCommunitySQL(col, param)
 }
 
-// RemoveReaction deletes a user's reaction from a post.
-func RemoveReaction(ctx context.Context, postID, userID uuid.UUID) error {
-	const q = `
-DELETE FROM reactions WHERE post_id = $1 AND user_id = $2;
-`
-	p := postgres.Pool()
-	if p == nil {
-		return errors.New("postgres pool is not initialized")
+// Upsert inserts or updates a user's reaction on a post or comment. It
+// returns ErrInvalidReference unless both the target and the user are in the
+// context's community.
+func (r *pgReactionRepo) Upsert(ctx context.Context, rx *Reaction) error {
+	if rx.ID == uuid.Nil {
+		rx.ID = uuid.New()
+	}
+	q := fmt.Sprintf(`
+INSERT INTO reactions (id, %[1]s, user_id, type)
+SELECT $1::uuid, $2::uuid, $3::uuid, $4::varchar
+WHERE %[2]s AND %[3]s
+ON CONFLICT (%[1]s, user_id)
+DO UPDATE SET type = EXCLUDED.type, updated_at = NOW()
+RETURNING id, created_at, updated_at;
+`, reactionColumn(rx.TargetType), reactionTargetInCommunitySQL(rx.TargetType, "$2::uuid", "$5"), userInCommunitySQL("$3::uuid", "$5"))
+	err := r.db.QueryRow(ctx, q, rx.ID, rx.TargetID, rx.UserID, rx.Type, communityArg(ctx)).Scan(&rx.ID, &rx.CreatedAt, &rx.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
 	}
-	_, err := p.Exec(ctx, q, postID, userID)
+	return translateErr(err)
+}
+
+// Remove deletes a user's reaction from a post or comment.
+func (r *pgReactionRepo) Remove(ctx context.Context, targetType string, targetID, userID uuid.UUID) error {
+	col := reactionColumn(targetType)
+	q := `DELETE FROM reactions WHERE ` + col + ` = $1 AND user_id = $2 AND ` + reactionTargetInCommunitySQL(targetType, col, "$3") + `;`
+	_, err := r.db.Exec(ctx, q, targetID, userID, communityArg(ctx))
 	return err
 }
 
-// CountReactionsByPost returns reaction counts grouped by type.
+// ReactionCount is the number of reactions of one type.
 type ReactionCount struct {
 	Type  string
 	Count int64
 }
 
-func CountReactionsByPost(ctx context.Context, postID uuid.UUID) ([]ReactionCount, error) {
-	const q = `
+// Count returns a target's reaction counts grouped by type.
+func (r *pgReactionRepo) Count(ctx context.Context, targetType string, targetID uuid.UUID) ([]ReactionCount, error) {
+	col := reactionColumn(targetType)
+	q := `
 SELECT type, COUNT(*)
-FROM reactions WHERE post_id = $1
-GROUP BY type;
+FROM reactions WHERE ` + col + ` = $1 AND ` + reactionTargetInCommunitySQL(targetType, col, "$2") + `
+GROUP BY type ORDER BY type;
 `
-	p := postgres.Pool()
-	if p == nil {
-		return nil, errors.New("postgres pool is not initialized")
-	}
-	rows, err := p.Query(ctx, q, postID)
+	rows, err := r.db.Query(ctx, q, targetID, communityArg(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -112,3 +152,201 @@ GROUP BY type;
 	}
 	return out, rows.Err()
 }
+
+// CountMany returns reaction counts grouped by target and type.
+func (r *pgReactionRepo) CountMany(ctx context.Context, targetType string, targetIDs []uuid.UUID) (map[uuid.UUID][]ReactionCount, error) {
+	out := make(map[uuid.UUID][]ReactionCount)
+	if len(targetIDs) == 0 {
+		return out, nil
+	}
+	col := reactionColumn(targetType)
+	q := `
+SELECT ` + col + `, type, COUNT(*)
+FROM reactions WHERE ` + col + ` = ANY($1::uuid[]) AND ` + reactionTargetInCommunitySQL(targetType, col, "$2") + `
+GROUP BY ` + col + `, type ORDER BY type;
+`
+	rows, err := r.db.Query(ctx, q, uuidStrings(targetIDs), communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var rc ReactionCount
+		if err := rows.Scan(&id, &rc.Type, &rc.Count); err != nil {
+			return nil, err
+		}
+		out[id] = append(out[id], rc)
+	}
+	return out, rows.Err()
+}
+
+// ByUser returns userID's reaction type keyed by target.
+func (r *pgReactionRepo) ByUser(ctx context.Context, targetType string, targetIDs []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]string, error) {
+	out := make(map[uuid.UUID]string)
+	if len(targetIDs) == 0 {
+		return out, nil
+	}
+	col := reactionColumn(targetType)
+	q := `SELECT ` + col + `, type FROM reactions WHERE ` + col + ` = ANY($1::uuid[]) AND user_id = $2 AND ` + reactionTargetInCommunitySQL(targetType, col, "$3") + `;`
+	rows, err := r.db.Query(ctx, q, uuidStrings(targetIDs), userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var typ string
+		if err := rows.Scan(&id, &typ); err != nil {
+			return nil, err
+		}
+		out[id] = typ
+	}
+	return out, rows.Err()
+}
+
+// List returns a target's reactions, oldest first, without those by
+// residents the viewer blocked or was blocked by.
+func (r *pgReactionRepo) List(ctx context.Context, targetType string, targetID uuid.UUID, viewerID *uuid.UUID) ([]Reaction, error) {
+	col := reactionColumn(targetType)
+	q := `
+SELECT id, user_id, type, created_at, updated_at
+FROM reactions WHERE ` + col + ` = $1
+  AND NOT ` + hiddenFromSQL("reactions.user_id", "$2", false) + `
+  AND ` + reactionTargetInCommunitySQL(targetType, col, "$3") + `
+ORDER BY created_at ASC, id ASC;
+`
+	rows, err := r.db.Query(ctx, q, targetID, viewerID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Reaction
+	for rows.Next() {
+		rx := Reaction{TargetType: targetType, TargetID: targetID}
+		if err := rows.Scan(&rx.ID, &rx.UserID, &rx.Type, &rx.CreatedAt, &rx.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rx)
+	}
+	return out, rows.Err()
+}
+
+// ListByUser returns every reaction the user left on posts and comments, newest first.
+func (r *pgReactionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]Reaction, error) {
+	q := `
+SELECT id,
+       CASE WHEN comment_id IS NULL THEN 'post' ELSE 'comment' END,
+       COALESCE(post_id, comment_id), type, created_at, updated_at
+FROM reactions WHERE user_id = $1 AND ` + userInCommunitySQL("user_id", "$2") + `
+ORDER BY created_at DESC, id ASC;
+`
+	rows, err := r.db.Query(ctx, q, userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Reaction
+	for rows.Next() {
+		rx := Reaction{UserID: userID}
+		if err := rows.Scan(&rx.ID, &rx.TargetType, &rx.TargetID, &rx.Type, &rx.CreatedAt, &rx.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rx)
+	}
+	return out, rows.Err()
+}
+
+// ReactionType is an entry in the admin-managed reaction catalog.
+type ReactionType struct {
+	Shortcode string
+	Emoji     string
+	CreatedAt time.Time
+}
+
+// DefaultReactionTypes seed the catalog on first start.
+var DefaultReactionTypes = []ReactionType{
+	{Shortcode: "like", Emoji: "👍"},
+	{Shortcode: "love", Emoji: "❤️"},
+	{Shortcode: "laugh", Emoji: "😂"},
+	{Shortcode: "wow", Emoji: "😮"},
+	{Shortcode: "sad", Emoji: "😢"},
+	{Shortcode: "angry", Emoji: "😠"},
+}
+
+// EnsureReactionTypesTable creates the reaction catalog and seeds the defaults
+// when the table is first created, so retiring every entry sticks.
+func EnsureReactionTypesTable(ctx context.Context, db DBTX) error {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT to_regclass('reaction_types') IS NOT NULL;`).Scan(&exists); err != nil {
+		return err
+	}
+	const ddl = `
+CREATE TABLE IF NOT EXISTS reaction_types (
+	shortcode VARCHAR PRIMARY KEY,
+	emoji VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure reaction_types table: %v", err)
+		return err
+	}
+	if exists {
+		return nil
+	}
+	for _, rt := range DefaultReactionTypes {
+		const q = `INSERT INTO reaction_types (shortcode, emoji) VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+		if _, err := db.Exec(ctx, q, rt.Shortcode, rt.Emoji); err != nil {
+			utils.Errorf("failed to seed reaction_types: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+type pgReactionTypeRepo struct {
+	db DBTX
+}
+
+// NewPgReactionTypeRepo returns a Postgres-backed ReactionTypeRepo.
+func NewPgReactionTypeRepo(db DBTX) ReactionTypeRepo {
+	return &pgReactionTypeRepo{db: db}
+}
+
+// List returns the catalog in the order entries were added.
+func (r *pgReactionTypeRepo) List(ctx context.Context) ([]ReactionType, error) {
+	const q = `SELECT shortcode, emoji, created_at FROM reaction_types ORDER BY created_at ASC, shortcode ASC;`
+	rows, err := r.db.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ReactionType
+	for rows.Next() {
+		var rt ReactionType
+		if err := rows.Scan(&rt.Shortcode, &rt.Emoji, &rt.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rt)
+	}
+	return out, rows.Err()
+}
+
+// Insert adds a catalog entry, returning ErrConflict for a taken shortcode.
+func (r *pgReactionTypeRepo) Insert(ctx context.Context, rt *ReactionType) error {
+	const q = `INSERT INTO reaction_types (shortcode, emoji) VALUES ($1, $2) RETURNING created_at;`
+	err := r.db.QueryRow(ctx, q, rt.Shortcode, rt.Emoji).Scan(&rt.CreatedAt)
+	return translateErr(err)
+}
+
+// Delete removes a catalog entry and reports whether it existed. Reactions
+// already using it are kept.
+func (r *pgReactionTypeRepo) Delete(ctx context.Context, shortcode string) (bool, error) {
+	const q = `DELETE FROM reaction_types WHERE shortcode = $1;`
+	tag, err := r.db.Exec(ctx, q, shortcode)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
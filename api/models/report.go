@@ -0,0 +1,261 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Report target types.
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetProfile = "profile"
+)
+
+// Report statuses. A report is open until a moderator claims it and resolved once acted on.
+const (
+	ReportOpen     = "open"
+	ReportClaimed  = "claimed"
+	ReportResolved = "resolved"
+)
+
+// Report is a resident's complaint about a post, comment or profile.
+type Report struct {
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	TargetType  string
+	TargetID    uuid.UUID
+	ReporterID  uuid.UUID
+	Reason      string
+	Details     *string
+	Status      string
+	ClaimedBy   *uuid.UUID
+	ClaimedAt   *time.Time
+	// Resolution records the action taken, e.g. "dismissed" or "hidden".
+	Resolution     *string
+	ResolutionNote *string
+	ResolvedBy     *uuid.UUID
+	ResolvedAt     *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ReportFilter narrows the moderation queue. Zero values match everything.
+type ReportFilter struct {
+	Status     string
+	TargetType string
+	Reason     string
+	ClaimedBy  *uuid.UUID
+	// Unclaimed restricts results to reports nobody has claimed.
+	Unclaimed bool
+	Limit     int
+}
+
+// EnsureReportsTable creates the reports table. Targets are polymorphic, so
+// only the users involved are foreign keys.
+func EnsureReportsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS reports (
+	id UUID PRIMARY KEY,
+	target_type VARCHAR NOT NULL,
+	target_id UUID NOT NULL,
+	reporter_id UUID NOT NULL,
+	reason VARCHAR NOT NULL,
+	details TEXT NULL,
+	status VARCHAR NOT NULL DEFAULT 'open',
+	claimed_by UUID NULL,
+	claimed_at TIMESTAMPTZ NULL,
+	resolution VARCHAR NULL,
+	resolution_note TEXT NULL,
+	resolved_by UUID NULL,
+	resolved_at TIMESTAMPTZ NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_reports_claimed_by FOREIGN KEY (claimed_by) REFERENCES users(id) ON DELETE SET NULL,
+	CONSTRAINT fk_reports_resolved_by FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS community_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES communities(id);
+
+CREATE INDEX IF NOT EXISTS idx_reports_queue ON reports (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_community ON reports (community_id, status);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports (target_type, target_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reports_unresolved_reporter
+	ON reports (target_type, target_id, reporter_id) WHERE status <> 'resolved';
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure reports table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgReportRepo struct {
+	db DBTX
+}
+
+// NewPgReportRepo returns a Postgres-backed ReportRepo.
+func NewPgReportRepo(db DBTX) ReportRepo {
+	return &pgReportRepo{db: db}
+}
+
+const reportColumns = `id, community_id, target_type, target_id, reporter_id, reason, details, status,
+       claimed_by, claimed_at, resolution, resolution_note, resolved_by, resolved_at,
+       created_at, updated_at`
+
+func scanReport(row pgx.Row) (*Report, error) {
+	var r Report
+	err := row.Scan(
+		&r.ID, &r.CommunityID, &r.TargetType, &r.TargetID, &r.ReporterID, &r.Reason, &r.Details, &r.Status,
+		&r.ClaimedBy, &r.ClaimedAt, &r.Resolution, &r.ResolutionNote, &r.ResolvedBy, &r.ResolvedAt,
+		&r.CreatedAt, &r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Insert files a new open report in the context's community.
+func (r *pgReportRepo) Insert(ctx context.Context, rep *Report) error {
+	if rep.ID == uuid.Nil {
+		rep.ID = uuid.New()
+	}
+	rep.Status = ReportOpen
+	rep.CommunityID = insertCommunity(ctx)
+	const q = `
+INSERT INTO reports (id, community_id, target_type, target_id, reporter_id, reason, details, status)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING created_at, updated_at;
+`
+	err := r.db.QueryRow(ctx, q,
+		rep.ID, rep.CommunityID, rep.TargetType, rep.TargetID, rep.ReporterID, rep.Reason, rep.Details, rep.Status,
+	).Scan(&rep.CreatedAt, &rep.UpdatedAt)
+	return translateErr(err)
+}
+
+// GetByID fetches a single report by id.
+func (r *pgReportRepo) GetByID(ctx context.Context, id uuid.UUID) (*Report, error) {
+	q := `SELECT ` + reportColumns + ` FROM reports WHERE id = $1 AND ` + communitySQL("community_id", "$2") + ` LIMIT 1;`
+	rep, err := scanReport(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return rep, err
+}
+
+// List returns reports matching f, oldest first so the queue is worked in order.
+func (r *pgReportRepo) List(ctx context.Context, f ReportFilter) ([]Report, error) {
+	args := []any{communityArg(ctx)}
+	where := []string{communitySQL("community_id", "$1")}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.TargetType != "" {
+		add("target_type = $%d", f.TargetType)
+	}
+	if f.Reason != "" {
+		add("reason = $%d", f.Reason)
+	}
+	if f.ClaimedBy != nil {
+		add("claimed_by = $%d", *f.ClaimedBy)
+	}
+	if f.Unclaimed {
+		where = append(where, "claimed_by IS NULL")
+	}
+
+	q := `SELECT ` + reportColumns + ` FROM reports WHERE ` + strings.Join(where, " AND ")
+	q += ` ORDER BY created_at ASC, id ASC`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Report
+	for rows.Next() {
+		rep, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rep)
+	}
+	return out, rows.Err()
+}
+
+// CountOpenReporters counts distinct reporters with unresolved reports on a target.
+func (r *pgReportRepo) CountOpenReporters(ctx context.Context, targetType string, targetID uuid.UUID) (int, error) {
+	q := `
+SELECT COUNT(DISTINCT reporter_id) FROM reports
+WHERE target_type = $1 AND target_id = $2 AND status <> 'resolved' AND ` + communitySQL("community_id", "$3") + `;
+`
+	var n int
+	err := r.db.QueryRow(ctx, q, targetType, targetID, communityArg(ctx)).Scan(&n)
+	return n, err
+}
+
+// HasResolution reports whether a resolved report on the target ended with resolution.
+func (r *pgReportRepo) HasResolution(ctx context.Context, targetType string, targetID uuid.UUID, resolution string) (bool, error) {
+	q := `
+SELECT EXISTS (
+    SELECT 1 FROM reports
+    WHERE target_type = $1 AND target_id = $2 AND status = 'resolved' AND resolution = $3
+      AND ` + communitySQL("community_id", "$4") + `
+);
+`
+	var found bool
+	err := r.db.QueryRow(ctx, q, targetType, targetID, resolution, communityArg(ctx)).Scan(&found)
+	return found, err
+}
+
+// Claim assigns an unresolved report to moderatorID unless another moderator holds it.
+func (r *pgReportRepo) Claim(ctx context.Context, id, moderatorID uuid.UUID) (bool, error) {
+	q := `
+UPDATE reports SET status = 'claimed', claimed_by = $2, claimed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status <> 'resolved' AND (claimed_by IS NULL OR claimed_by = $2)
+  AND ` + communitySQL("community_id", "$3") + `;
+`
+	tag, err := r.db.Exec(ctx, q, id, moderatorID, communityArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ResolveTarget closes every unresolved report on a target with the same outcome.
+func (r *pgReportRepo) ResolveTarget(ctx context.Context, targetType string, targetID uuid.UUID, resolution string, note *string, moderatorID uuid.UUID) (int, error) {
+	q := `
+UPDATE reports SET
+    status = 'resolved',
+    resolution = $3,
+    resolution_note = $4,
+    resolved_by = $5,
+    resolved_at = NOW(),
+    claimed_by = COALESCE(claimed_by, $5),
+    claimed_at = COALESCE(claimed_at, NOW()),
+    updated_at = NOW()
+WHERE target_type = $1 AND target_id = $2 AND status <> 'resolved'
+  AND ` + communitySQL("community_id", "$6") + `;
+`
+	tag, err := r.db.Exec(ctx, q, targetType, targetID, resolution, note, moderatorID, communityArg(ctx))
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,337 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrConflict is returned when a write violates a uniqueness constraint.
+	ErrConflict = errors.New("record already exists")
+	// ErrInvalidReference is returned when a write references a row that does not exist.
+	ErrInvalidReference = errors.New("referenced record does not exist")
+)
+
+// DBTX is the subset of pgx used by the Postgres repositories. It is satisfied
+// by *pgxpool.Pool, *pgx.Conn and pgx.Tx.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// CommunityRepo persists communities. Lookups return (nil, nil) when no community matches.
+type CommunityRepo interface {
+	// Insert returns ErrConflict when the slug is taken.
+	Insert(ctx context.Context, c *Community) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Community, error)
+	GetBySlug(ctx context.Context, slug string) (*Community, error)
+	List(ctx context.Context) ([]Community, error)
+}
+
+// InviteRepo persists registration invites. Lookups return (nil, nil) when no invite matches.
+type InviteRepo interface {
+	// Insert returns ErrConflict when the code is taken.
+	Insert(ctx context.Context, i *Invite) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Invite, error)
+	GetByCode(ctx context.Context, code string) (*Invite, error)
+	List(ctx context.Context) ([]Invite, error)
+	// Redeem atomically uses up one use of a usable invite, reporting whether it did.
+	Redeem(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
+	Revoke(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// UnitRepo persists a community's unit registry and the roster of
+// pre-approved resident emails. Lookups return (nil, nil) when nothing matches.
+type UnitRepo interface {
+	// Upsert matches units by building and number, ignoring case, keeps the
+	// floor when u.Floor is nil, and reports whether it inserted.
+	Upsert(ctx context.Context, u *Unit) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Unit, error)
+	List(ctx context.Context) ([]Unit, error)
+	ListByNumber(ctx context.Context, unitNumber string) ([]Unit, error)
+	SetOccupancy(ctx context.Context, id uuid.UUID, occupancy string) error
+	SetAutoVerify(ctx context.Context, id uuid.UUID, autoVerify bool) error
+	Count(ctx context.Context) (int, error)
+	// AddRosterEmail reports whether the email is new to the roster; an
+	// existing entry moves to e.UnitID. It returns ErrInvalidReference for an unknown unit.
+	AddRosterEmail(ctx context.Context, e *RosterEntry) (bool, error)
+	GetRosterEntry(ctx context.Context, email string) (*RosterEntry, error)
+	ListRoster(ctx context.Context) ([]RosterEntry, error)
+}
+
+// HouseholdRepo persists households. Lookups return (nil, nil) when no household matches.
+type HouseholdRepo interface {
+	// Insert returns ErrConflict when the unit already has a household.
+	Insert(ctx context.Context, h *Household) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Household, error)
+	List(ctx context.Context) ([]Household, error)
+	Update(ctx context.Context, h *Household) error
+	// Delete unlinks the household's members and deletes its invites.
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// UserRepo persists residents. Lookups return (nil, nil) when no user matches.
+type UserRepo interface {
+	Insert(ctx context.Context, u *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// ListByIDs fetches several users at once, skipping unknown ids.
+	ListByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
+	// Update writes the profile, unit and household fields. Roles, account
+	// status and token version are only changed by SetRoles and SetStatus.
+	Update(ctx context.Context, u *User) error
+	SetRoles(ctx context.Context, id uuid.UUID, isAdmin, isModerator bool) error
+	// SetStatus writes u's status, status reason, suspension end and deletion
+	// request and, when revoke is true, bumps its token version into u.
+	SetStatus(ctx context.Context, u *User, revoke bool) error
+	// SoftDelete deactivates a user and starts the deletion grace period.
+	SoftDelete(ctx context.Context, id uuid.UUID) error
+	// ListDeletionDue returns inactive users whose deletion was requested at or before cutoff.
+	ListDeletionDue(ctx context.Context, cutoff time.Time) ([]User, error)
+	// ListForReview returns registrations whose verification is "review".
+	ListForReview(ctx context.Context) ([]User, error)
+	// HasAdmin reports whether the community has an admin who is not deleted.
+	HasAdmin(ctx context.Context) (bool, error)
+	// SetModerationHidden hides a profile from the directory or restores it.
+	SetModerationHidden(ctx context.Context, id uuid.UUID, hidden bool) error
+	ListByHousehold(ctx context.Context, householdID uuid.UUID) ([]User, error)
+	// Anonymize scrubs a user's personal details but keeps their content.
+	Anonymize(ctx context.Context, id uuid.UUID) error
+	// Delete removes a user along with everything they created.
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListDirectory(ctx context.Context, f DirectoryFilter) ([]DirectoryUser, error)
+	// RecordLoginFailure atomically increments the failed login counter and returns it.
+	RecordLoginFailure(ctx context.Context, id uuid.UUID) (int, error)
+	LockUntil(ctx context.Context, id uuid.UUID, until time.Time) error
+	// ResetLoginFailures clears the failed login counter and any lock.
+	ResetLoginFailures(ctx context.Context, id uuid.UUID) error
+}
+
+// BoardRepo persists boards. Lookups return (nil, nil) when no board matches.
+type BoardRepo interface {
+	Insert(ctx context.Context, b *Board) error
+	List(ctx context.Context) ([]Board, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Board, error)
+	Update(ctx context.Context, b *Board) error
+	// Reorder sets the sort order of the given boards to their position in ids.
+	Reorder(ctx context.Context, ids []uuid.UUID) error
+	// Delete removes a board along with any posts still on it.
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// PostRepo persists posts. Lookups return (nil, nil) when no post matches.
+type PostRepo interface {
+	Insert(ctx context.Context, p *Post) error
+	// ListByBoard and ListBulletins leave out posts by residents hidden from
+	// viewerID by a block or, for board listings, a mute; viewerID may be nil.
+	ListByBoard(ctx context.Context, boardID uuid.UUID, viewerID *uuid.UUID) ([]Post, error)
+	ListBulletins(ctx context.Context, viewerID *uuid.UUID) ([]Post, error)
+	// ListByAuthor returns all of an author's posts, hidden ones included.
+	ListByAuthor(ctx context.Context, authorID uuid.UUID) ([]Post, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Post, error)
+	SetHidden(ctx context.Context, id uuid.UUID, hidden bool) error
+	// MoveBoard moves every post on board from to board to and returns how many moved.
+	MoveBoard(ctx context.Context, from, to uuid.UUID) (int, error)
+	// ListFeed returns up to limit posts from the user's subscribed boards, newest first,
+	// optionally only those after the cursor, leaving out blocked and muted authors
+	// and boards the user cannot read.
+	ListFeed(ctx context.Context, userID uuid.UUID, after *FeedCursor, limit int) ([]Post, error)
+}
+
+// BoardMemberRepo persists access to members-only boards. Get returns (nil, nil)
+// when the user has no membership or request.
+type BoardMemberRepo interface {
+	Upsert(ctx context.Context, m *BoardMember) error
+	Get(ctx context.Context, boardID, userID uuid.UUID) (*BoardMember, error)
+	ListByBoard(ctx context.Context, boardID uuid.UUID) ([]BoardMember, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]BoardMember, error)
+	Delete(ctx context.Context, boardID, userID uuid.UUID) error
+}
+
+// SubscriptionRepo persists board subscriptions, one per user per board.
+type SubscriptionRepo interface {
+	Upsert(ctx context.Context, s *Subscription) error
+	Delete(ctx context.Context, userID, boardID uuid.UUID) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error)
+	// MarkRead resets the board's unread count and reports whether a subscription exists.
+	MarkRead(ctx context.Context, userID, boardID uuid.UUID) (bool, error)
+}
+
+// CommentRepo persists comments on posts. Lookups return (nil, nil) when no comment matches.
+type CommentRepo interface {
+	Insert(ctx context.Context, c *Comment) error
+	// ListByPost leaves out comments by residents blocked by or blocking viewerID.
+	ListByPost(ctx context.Context, postID uuid.UUID, viewerID *uuid.UUID) ([]Comment, error)
+	// ListByAuthor returns all of an author's comments, hidden ones included.
+	ListByAuthor(ctx context.Context, authorID uuid.UUID) ([]Comment, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Comment, error)
+	SetHidden(ctx context.Context, id uuid.UUID, hidden bool) error
+	// CountByPosts counts the comments on each post that ListByPost would
+	// show viewerID; posts without comments are left out.
+	CountByPosts(ctx context.Context, postIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID]int, error)
+}
+
+// ReactionRepo persists one reaction per user per post or comment. Targets
+// are named by ReactionOnPost or ReactionOnComment.
+type ReactionRepo interface {
+	Upsert(ctx context.Context, r *Reaction) error
+	Remove(ctx context.Context, targetType string, targetID, userID uuid.UUID) error
+	Count(ctx context.Context, targetType string, targetID uuid.UUID) ([]ReactionCount, error)
+	// List leaves out reactions by residents blocked by or blocking viewerID.
+	List(ctx context.Context, targetType string, targetID uuid.UUID, viewerID *uuid.UUID) ([]Reaction, error)
+	// CountMany tallies reactions for several targets in one query.
+	CountMany(ctx context.Context, targetType string, targetIDs []uuid.UUID) (map[uuid.UUID][]ReactionCount, error)
+	// ByUser returns userID's reaction type on each of the targets they reacted to.
+	ByUser(ctx context.Context, targetType string, targetIDs []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]string, error)
+	// ListByUser returns every reaction the user left, on posts and comments alike.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Reaction, error)
+}
+
+// ReactionTypeRepo persists the catalog of allowed reaction shortcodes.
+type ReactionTypeRepo interface {
+	List(ctx context.Context) ([]ReactionType, error)
+	// Insert returns ErrConflict when the shortcode is taken.
+	Insert(ctx context.Context, rt *ReactionType) error
+	Delete(ctx context.Context, shortcode string) (bool, error)
+}
+
+// BlockRepo persists the residents each user blocked or muted.
+type BlockRepo interface {
+	Upsert(ctx context.Context, b *Block) error
+	Delete(ctx context.Context, userID, targetID uuid.UUID, kind string) (bool, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Block, error)
+}
+
+// DataExportRepo persists residents' data export requests and their archives.
+// GetByID returns (nil, nil) when no export matches.
+type DataExportRepo interface {
+	Insert(ctx context.Context, e *DataExport) error
+	GetByID(ctx context.Context, id uuid.UUID) (*DataExport, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]DataExport, error)
+	Complete(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error
+	Fail(ctx context.Context, id uuid.UUID, reason string) error
+	// FailStale marks exports still pending since before as failed and returns how many.
+	FailStale(ctx context.Context, before time.Time, reason string) (int, error)
+	MarkDownloaded(ctx context.Context, id uuid.UUID) error
+	Archive(ctx context.Context, id uuid.UUID) ([]byte, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// ReportRepo persists moderation reports. Lookups return (nil, nil) when no report matches.
+type ReportRepo interface {
+	// Insert returns ErrConflict if the reporter already has an unresolved report on the target.
+	Insert(ctx context.Context, r *Report) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Report, error)
+	List(ctx context.Context, f ReportFilter) ([]Report, error)
+	// CountOpenReporters counts distinct reporters with unresolved reports on the target.
+	CountOpenReporters(ctx context.Context, targetType string, targetID uuid.UUID) (int, error)
+	// HasResolution reports whether any resolved report on the target ended with resolution.
+	HasResolution(ctx context.Context, targetType string, targetID uuid.UUID, resolution string) (bool, error)
+	// Claim assigns an unresolved report to a moderator unless someone else holds it,
+	// reporting whether the claim succeeded.
+	Claim(ctx context.Context, id, moderatorID uuid.UUID) (bool, error)
+	// ResolveTarget resolves every unresolved report on the target and returns how many changed.
+	ResolveTarget(ctx context.Context, targetType string, targetID uuid.UUID, resolution string, note *string, moderatorID uuid.UUID) (int, error)
+}
+
+// WarningRepo persists moderator warnings issued to residents.
+type WarningRepo interface {
+	Insert(ctx context.Context, w *Warning) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Warning, error)
+}
+
+// AccountActionRepo records administrative changes to account status.
+type AccountActionRepo interface {
+	Insert(ctx context.Context, a *AccountAction) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]AccountAction, error)
+}
+
+// AuditRepo is the append-only log of privileged actions.
+type AuditRepo interface {
+	Insert(ctx context.Context, e *AuditEvent) error
+	List(ctx context.Context, f AuditFilter) ([]AuditEvent, error)
+}
+
+// RateLimitRepo stores token buckets. Take refills the bucket named key at
+// perSecond tokens per second up to capacity, then consumes one token if one is available.
+type RateLimitRepo interface {
+	Take(ctx context.Context, key string, capacity, perSecond float64) (RateLimitResult, error)
+	Prune(ctx context.Context, idle time.Duration) error
+}
+
+// Repos bundles every repository the services depend on.
+type Repos struct {
+	Communities   CommunityRepo
+	Users         UserRepo
+	Invites       InviteRepo
+	Units         UnitRepo
+	Households    HouseholdRepo
+	Boards        BoardRepo
+	Members       BoardMemberRepo
+	Subscriptions SubscriptionRepo
+	Posts         PostRepo
+	Comments      CommentRepo
+	Blocks        BlockRepo
+	Exports       DataExportRepo
+	Reactions     ReactionRepo
+	ReactionTypes ReactionTypeRepo
+	Reports       ReportRepo
+	Warnings      WarningRepo
+	Accounts      AccountActionRepo
+	Audit         AuditRepo
+	RateLimits    RateLimitRepo
+}
+
+// NewPgRepos returns Postgres-backed repositories sharing the given connection.
+func NewPgRepos(db DBTX) *Repos {
+	return &Repos{
+		Communities:   NewPgCommunityRepo(db),
+		Users:         NewPgUserRepo(db),
+		Invites:       NewPgInviteRepo(db),
+		Units:         NewPgUnitRepo(db),
+		Households:    NewPgHouseholdRepo(db),
+		Boards:        NewPgBoardRepo(db),
+		Members:       NewPgBoardMemberRepo(db),
+		Subscriptions: NewPgSubscriptionRepo(db),
+		Posts:         NewPgPostRepo(db),
+		Comments:      NewPgCommentRepo(db),
+		Blocks:        NewPgBlockRepo(db),
+		Exports:       NewPgDataExportRepo(db),
+		Reactions:     NewPgReactionRepo(db),
+		ReactionTypes: NewPgReactionTypeRepo(db),
+		Reports:       NewPgReportRepo(db),
+		Warnings:      NewPgWarningRepo(db),
+		Accounts:      NewPgAccountActionRepo(db),
+		Audit:         NewPgAuditRepo(db),
+		RateLimits:    NewPgRateLimitRepo(db),
+	}
+}
+
+// translateErr maps Postgres constraint violations onto repository errors.
+func translateErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return ErrConflict
+		case "23503": // foreign_key_violation
+			return ErrInvalidReference
+		}
+	}
+	return err
+}
+
+// uuidStrings formats ids for a $n::uuid[] parameter.
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
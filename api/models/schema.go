@@ -0,0 +1,34 @@
+package models
+
+import "context"
+
+// EnsureSchema creates every table the API needs, in foreign key order.
+func EnsureSchema(ctx context.Context, db DBTX) error {
+	steps := []func(context.Context, DBTX) error{
+		EnsureCommunitiesTable,
+		EnsureUnitsTable,
+		EnsureHouseholdsTable,
+		EnsureUsersTable,
+		EnsureInvitesTable,
+		EnsureBlocksTable,
+		EnsureDataExportsTable,
+		EnsureBoardsTable,
+		EnsureBoardMembersTable,
+		EnsureSubscriptionsTable,
+		EnsurePostsTable,
+		EnsureCommentsTable,
+		EnsureReactionsTable,
+		EnsureReactionTypesTable,
+		EnsureReportsTable,
+		EnsureWarningsTable,
+		EnsureAccountActionsTable,
+		EnsureAuditTable,
+		EnsureRateLimitTable,
+	}
+	for _, ensure := range steps {
+		if err := ensure(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Subscription levels. Muted subscriptions keep the membership but drop the
+// board from the feed and unread counts.
+const (
+	SubscriptionAll       = "all"
+	SubscriptionBulletins = "bulletins"
+	SubscriptionMuted     = "muted"
+)
+
+// Subscription is a resident's membership in a board.
+type Subscription struct {
+	UserID  uuid.UUID
+	BoardID uuid.UUID
+	Level   string
+	// LastReadAt marks the newest post the resident has seen on the board.
+	LastReadAt time.Time
+	// UnreadCount is computed by ListByUser: visible posts by others since
+	// LastReadAt that the level includes, leaving out blocked or muted authors
+	// and boards the user can no longer read.
+	UnreadCount int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// EnsureSubscriptionsTable creates the board_subscriptions table.
+func EnsureSubscriptionsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS board_subscriptions (
+	user_id UUID NOT NULL,
+	board_id UUID NOT NULL,
+	level VARCHAR NOT NULL DEFAULT 'all',
+	last_read_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, board_id),
+	CONSTRAINT fk_board_subscriptions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_board_subscriptions_board FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_board_subscriptions_board ON board_subscriptions (board_id);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure board_subscriptions table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgSubscriptionRepo struct {
+	db DBTX
+}
+
+// NewPgSubscriptionRepo returns a Postgres-backed SubscriptionRepo.
+func NewPgSubscriptionRepo(db DBTX) SubscriptionRepo {
+	return &pgSubscriptionRepo{db: db}
+}
+
+// Upsert subscribes a user to a board or changes the level of an existing
+// subscription. New subscriptions start with nothing unread. It returns
+// ErrInvalidReference unless both the user and the board are in the context's
+// community.
+func (r *pgSubscriptionRepo) Upsert(ctx context.Context, s *Subscription) error {
+	q := `
+INSERT INTO board_subscriptions (user_id, board_id, level)
+SELECT $1::uuid, $2::uuid, $3::varchar
+WHERE ` + userInCommunitySQL("$1::uuid", "$4") + ` AND ` + boardInCommunitySQL("$2::uuid", "$4") + `
+ON CONFLICT (user_id, board_id)
+DO UPDATE SET level = EXCLUDED.level, updated_at = NOW()
+RETURNING last_read_at, created_at, updated_at;
+`
+	err := r.db.QueryRow(ctx, q, s.UserID, s.BoardID, s.Level, communityArg(ctx)).Scan(&s.LastReadAt, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+// Delete unsubscribes a user from a board.
+func (r *pgSubscriptionRepo) Delete(ctx context.Context, userID, boardID uuid.UUID) error {
+	q := `DELETE FROM board_subscriptions WHERE user_id = $1 AND board_id = $2 AND ` + boardInCommunitySQL("board_id", "$3") + `;`
+	_, err := r.db.Exec(ctx, q, userID, boardID, communityArg(ctx))
+	return err
+}
+
+// ListByUser returns a user's subscriptions to boards in the context's
+// community with unread counts.
+func (r *pgSubscriptionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
+	q := `
+SELECT s.user_id, s.board_id, s.level, s.last_read_at, s.created_at, s.updated_at, COUNT(p.id)
+FROM board_subscriptions s
+LEFT JOIN posts p ON p.board_id = s.board_id
+	AND p.created_at > s.last_read_at
+	AND p.hidden_at IS NULL
+	AND p.author_id <> s.user_id
+	AND (s.level = 'all' OR (s.level = 'bulletins' AND p.is_bulletin))
+	AND NOT ` + hiddenFromSQL("p.author_id", "s.user_id", true) + `
+	AND ` + readableBoardSQL("s.board_id", "s.user_id") + `
+WHERE s.user_id = $1 AND ` + boardInCommunitySQL("s.board_id", "$2") + `
+GROUP BY s.user_id, s.board_id;
+`
+	rows, err := r.db.Query(ctx, q, userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Subscription
+	for rows.Next() {
+		var s Subscription
+		if err := rows.Scan(&s.UserID, &s.BoardID, &s.Level, &s.LastReadAt, &s.CreatedAt, &s.UpdatedAt, &s.UnreadCount); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// MarkRead clears a board's unread count, reporting whether the user is subscribed.
+func (r *pgSubscriptionRepo) MarkRead(ctx context.Context, userID, boardID uuid.UUID) (bool, error) {
+	q := `
+UPDATE board_subscriptions SET last_read_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND board_id = $2 AND ` + boardInCommunitySQL("board_id", "$3") + `;
+`
+	tag, err := r.db.Exec(ctx, q, userID, boardID, communityArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
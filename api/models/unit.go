@@ -0,0 +1,245 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Unit occupancy, as listed on the admin's roster.
+const (
+	OccupancyOccupied = "occupied"
+	OccupancyVacant   = "vacant"
+)
+
+// Resident verification states, decided at registration from the roster.
+const (
+	// VerificationUnverified means the community has no unit registry to check against.
+	VerificationUnverified = "unverified"
+	VerificationVerified   = "verified"
+	// VerificationReview flags a registration that does not match the roster.
+	VerificationReview = "review"
+)
+
+// Unit is one home in a community's unit registry.
+type Unit struct {
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	// Building is empty in single-building communities.
+	Building   string
+	Floor      *string
+	UnitNumber string
+	Occupancy  string
+	// AutoVerify lets registrations for the unit be verified while the
+	// roster lists no emails for it.
+	AutoVerify bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// RosterEntry pre-approves the resident with Email for a unit.
+type RosterEntry struct {
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	UnitID      uuid.UUID
+	Email       string
+	CreatedAt   time.Time
+}
+
+// EnsureUnitsTable creates the units and roster_entries tables.
+func EnsureUnitsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS units (
+	id UUID PRIMARY KEY,
+	community_id UUID NOT NULL REFERENCES communities(id),
+	building VARCHAR NOT NULL DEFAULT '',
+	floor VARCHAR NULL,
+	unit_number VARCHAR NOT NULL,
+	occupancy VARCHAR NOT NULL DEFAULT 'occupied',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE units ADD COLUMN IF NOT EXISTS auto_verify BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_units_number ON units (community_id, building, lower(unit_number));
+
+CREATE TABLE IF NOT EXISTS roster_entries (
+	id UUID PRIMARY KEY,
+	community_id UUID NOT NULL REFERENCES communities(id),
+	unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+	email VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (community_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_roster_entries_unit ON roster_entries (unit_id);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure units table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgUnitRepo struct {
+	db DBTX
+}
+
+// NewPgUnitRepo returns a Postgres-backed UnitRepo.
+func NewPgUnitRepo(db DBTX) UnitRepo {
+	return &pgUnitRepo{db: db}
+}
+
+const unitColumns = `id, community_id, building, floor, unit_number, occupancy, auto_verify, created_at, updated_at`
+
+func scanUnit(row pgx.Row) (*Unit, error) {
+	var u Unit
+	err := row.Scan(&u.ID, &u.CommunityID, &u.Building, &u.Floor, &u.UnitNumber, &u.Occupancy, &u.AutoVerify, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *pgUnitRepo) queryUnits(ctx context.Context, q string, args ...any) ([]Unit, error) {
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Unit
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	return out, rows.Err()
+}
+
+// Upsert adds the unit to the context's community, or updates the occupancy
+// (and floor, when set) of the unit with the same building and number. It
+// reports whether the unit is new.
+func (r *pgUnitRepo) Upsert(ctx context.Context, u *Unit) (bool, error) {
+	u.CommunityID = insertCommunity(ctx)
+	const q = `
+INSERT INTO units (id, community_id, building, floor, unit_number, occupancy)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (community_id, building, lower(unit_number)) DO UPDATE SET
+	floor = COALESCE(EXCLUDED.floor, units.floor),
+	occupancy = EXCLUDED.occupancy,
+	updated_at = NOW()
+RETURNING id, floor, unit_number, auto_verify, created_at, updated_at, (xmax = 0) AS inserted;
+`
+	var inserted bool
+	err := r.db.QueryRow(ctx, q, uuid.New(), u.CommunityID, u.Building, u.Floor, u.UnitNumber, u.Occupancy).
+		Scan(&u.ID, &u.Floor, &u.UnitNumber, &u.AutoVerify, &u.CreatedAt, &u.UpdatedAt, &inserted)
+	return inserted, err
+}
+
+// GetByID fetches a unit by id.
+func (r *pgUnitRepo) GetByID(ctx context.Context, id uuid.UUID) (*Unit, error) {
+	q := `SELECT ` + unitColumns + ` FROM units WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	return scanUnit(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+}
+
+// List returns the community's units by building and number.
+func (r *pgUnitRepo) List(ctx context.Context) ([]Unit, error) {
+	q := `SELECT ` + unitColumns + ` FROM units WHERE ` + communitySQL("community_id", "$1") + ` ORDER BY building, lower(unit_number);`
+	return r.queryUnits(ctx, q, communityArg(ctx))
+}
+
+// ListByNumber returns the units numbered unitNumber, ignoring case, in every building.
+func (r *pgUnitRepo) ListByNumber(ctx context.Context, unitNumber string) ([]Unit, error) {
+	q := `SELECT ` + unitColumns + ` FROM units WHERE lower(unit_number) = lower($1) AND ` + communitySQL("community_id", "$2") + ` ORDER BY building;`
+	return r.queryUnits(ctx, q, unitNumber, communityArg(ctx))
+}
+
+// SetOccupancy marks a unit occupied or vacant.
+func (r *pgUnitRepo) SetOccupancy(ctx context.Context, id uuid.UUID, occupancy string) error {
+	q := `UPDATE units SET occupancy = $2, updated_at = NOW() WHERE id = $1 AND ` + communitySQL("community_id", "$3") + `;`
+	_, err := r.db.Exec(ctx, q, id, occupancy, communityArg(ctx))
+	return err
+}
+
+// SetAutoVerify sets whether registrations for a unit without roster emails are verified.
+func (r *pgUnitRepo) SetAutoVerify(ctx context.Context, id uuid.UUID, autoVerify bool) error {
+	q := `UPDATE units SET auto_verify = $2, updated_at = NOW() WHERE id = $1 AND ` + communitySQL("community_id", "$3") + `;`
+	_, err := r.db.Exec(ctx, q, id, autoVerify, communityArg(ctx))
+	return err
+}
+
+// Count returns how many units the community has registered.
+func (r *pgUnitRepo) Count(ctx context.Context) (int, error) {
+	q := `SELECT COUNT(*) FROM units WHERE ` + communitySQL("community_id", "$1") + `;`
+	var n int
+	err := r.db.QueryRow(ctx, q, communityArg(ctx)).Scan(&n)
+	return n, err
+}
+
+const rosterColumns = `id, community_id, unit_id, email, created_at`
+
+func scanRosterEntry(row pgx.Row) (*RosterEntry, error) {
+	var e RosterEntry
+	err := row.Scan(&e.ID, &e.CommunityID, &e.UnitID, &e.Email, &e.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// AddRosterEmail pre-approves an email for a unit of the context's community.
+// An email already on the roster moves to the new unit. It reports whether
+// the email is new to the roster.
+func (r *pgUnitRepo) AddRosterEmail(ctx context.Context, e *RosterEntry) (bool, error) {
+	e.CommunityID = insertCommunity(ctx)
+	q := `
+INSERT INTO roster_entries (id, community_id, unit_id, email)
+SELECT $1::uuid, $2::uuid, u.id, $4::varchar FROM units u WHERE u.id = $3 AND u.community_id = $2::uuid
+ON CONFLICT (community_id, email) DO UPDATE SET unit_id = EXCLUDED.unit_id
+RETURNING id, created_at, (xmax = 0) AS inserted;
+`
+	var inserted bool
+	err := r.db.QueryRow(ctx, q, uuid.New(), e.CommunityID, e.UnitID, e.Email).Scan(&e.ID, &e.CreatedAt, &inserted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrInvalidReference
+	}
+	return inserted, err
+}
+
+// GetRosterEntry fetches the roster entry for an email.
+func (r *pgUnitRepo) GetRosterEntry(ctx context.Context, email string) (*RosterEntry, error) {
+	q := `SELECT ` + rosterColumns + ` FROM roster_entries WHERE email = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	return scanRosterEntry(r.db.QueryRow(ctx, q, email, communityArg(ctx)))
+}
+
+// ListRoster returns the community's roster entries by email.
+func (r *pgUnitRepo) ListRoster(ctx context.Context) ([]RosterEntry, error) {
+	q := `SELECT ` + rosterColumns + ` FROM roster_entries WHERE ` + communitySQL("community_id", "$1") + ` ORDER BY email;`
+	rows, err := r.db.Query(ctx, q, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []RosterEntry
+	for rows.Next() {
+		e, err := scanRosterEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *e)
+	}
+	return out, rows.Err()
+}
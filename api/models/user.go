@@ -5,28 +5,128 @@ import (
 	"errors"
 	"time"
 
-	"github.com/cameronsralla/culdechat/connectors/postgres"
 	"github.com/cameronsralla/culdechat/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
+// Account statuses. Only active users may log in or use their tokens.
+const (
+	UserActive    = "active"
+	UserInactive  = "inactive"
+	UserPending   = "pending"
+	UserSuspended = "suspended"
+	UserBanned    = "banned"
+	// UserDeleted marks an account the retention worker anonymized.
+	UserDeleted = "deleted"
+	// UserMovedOut marks an account closed when an admin moved its household out.
+	UserMovedOut = "moved_out"
+)
+
 // User represents the users table.
 type User struct {
-	ID                uuid.UUID
-	UnitNumber        string
+	ID          uuid.UUID
+	CommunityID uuid.UUID
+	UnitNumber  string
+	// UnitID links the resident to the unit registry once verified.
+	UnitID *uuid.UUID
+	// Verification is unverified, verified or review; VerificationNote says
+	// why a registration was flagged for review.
+	Verification     string
+	VerificationNote *string
+	// HouseholdID links residents who share a unit.
+	HouseholdID       *uuid.UUID
 	Email             string
 	HashedPassword    string
 	ProfilePictureURL *string
-	IsDirectoryOptIn  bool
-	IsAdmin           bool
-	Status            string
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	// DisplayName, Pronouns, Bio, MoveInDate and Interests are optional
+	// profile details the resident fills in themselves.
+	DisplayName   *string
+	Pronouns      *string
+	Bio           *string
+	MoveInDate    *time.Time
+	Interests     []string
+	ContactMethod *string
+	// Building is the resident's building in multi-building complexes.
+	Building *string
+	// Privacy decides who else sees each profile field.
+	Privacy          PrivacySettings
+	IsDirectoryOptIn bool
+	// ModerationHidden keeps a profile hidden by a moderator out of the
+	// directory, whatever its opt-in says, and withholds its picture from
+	// others. It is managed by SetModerationHidden, not Update.
+	ModerationHidden bool
+	IsAdmin          bool
+	// IsModerator grants access to the report queue. Admins are always moderators.
+	IsModerator bool
+	Status      string
+	// StatusReason explains a suspension or ban; SuspendedUntil ends a suspension.
+	StatusReason   *string
+	SuspendedUntil *time.Time
+	// DeletionRequestedAt is set while an inactive account waits out the
+	// deletion grace period.
+	DeletionRequestedAt *time.Time
+	// TokenVersion is embedded in access tokens; bumping it revokes them all.
+	// Roles, status fields and TokenVersion are managed by SetRoles and
+	// SetStatus, so a stale Update cannot undo an admin action.
+	TokenVersion int
+	// FailedLoginAttempts counts consecutive failed logins; LockedUntil blocks
+	// logins while in the future. Both are managed by the login-failure methods, not Update.
+	FailedLoginAttempts int
+	LockedUntil         *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// Privacy audiences for profile fields.
+const (
+	AudienceNobody   = "nobody"
+	AudienceFloor    = "floor"
+	AudienceEveryone = "everyone"
+)
+
+// PrivacySettings holds one audience per profile field. Empty values mean
+// the field's default, so settings saved before a field existed keep working.
+type PrivacySettings struct {
+	Name    string `json:"name,omitempty"`
+	Unit    string `json:"unit,omitempty"`
+	Floor   string `json:"floor,omitempty"`
+	Avatar  string `json:"avatar,omitempty"`
+	Bio     string `json:"bio,omitempty"`
+	Contact string `json:"contact,omitempty"`
+}
+
+// DefaultPrivacy applies to fields a resident has not chosen an audience for.
+// Units stay private unless shared; only the floor is shown by default.
+var DefaultPrivacy = PrivacySettings{
+	Name:    AudienceEveryone,
+	Unit:    AudienceNobody,
+	Floor:   AudienceEveryone,
+	Avatar:  AudienceEveryone,
+	Bio:     AudienceEveryone,
+	Contact: AudienceNobody,
+}
+
+// Effective fills unset fields with their defaults.
+func (p PrivacySettings) Effective() PrivacySettings {
+	pick := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	}
+	return PrivacySettings{
+		Name:    pick(p.Name, DefaultPrivacy.Name),
+		Unit:    pick(p.Unit, DefaultPrivacy.Unit),
+		Floor:   pick(p.Floor, DefaultPrivacy.Floor),
+		Avatar:  pick(p.Avatar, DefaultPrivacy.Avatar),
+		Bio:     pick(p.Bio, DefaultPrivacy.Bio),
+		Contact: pick(p.Contact, DefaultPrivacy.Contact),
+	}
 }
 
 // EnsureUsersTable creates the users table if it doesn't exist.
-func EnsureUsersTable(ctx context.Context) error {
+func EnsureUsersTable(ctx context.Context, db DBTX) error {
 	const ddl = `
 CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY,
@@ -36,63 +136,82 @@ CREATE TABLE IF NOT EXISTS users (
     profile_picture_url VARCHAR NULL,
     is_directory_opt_in BOOLEAN NOT NULL DEFAULT FALSE,
     is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    is_moderator BOOLEAN NOT NULL DEFAULT FALSE,
     status VARCHAR NOT NULL DEFAULT 'active',
+    status_reason TEXT NULL,
+    suspended_until TIMESTAMPTZ NULL,
+    token_version INTEGER NOT NULL DEFAULT 0,
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ NULL,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMPTZ NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pronouns VARCHAR NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS move_in_date DATE NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS interests TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS contact_method VARCHAR NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS privacy JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS building VARCHAR NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMPTZ NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS community_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES communities(id);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS unit_id UUID NULL REFERENCES units(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification VARCHAR NOT NULL DEFAULT 'unverified';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_note TEXT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS household_id UUID NULL REFERENCES households(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS moderation_hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
 CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_community ON users (community_id);
+CREATE INDEX IF NOT EXISTS idx_users_unit ON users (unit_id) WHERE unit_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_household ON users (household_id) WHERE household_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_verification_review ON users (community_id, created_at) WHERE verification = 'review';
+CREATE INDEX IF NOT EXISTS idx_users_deletion_requested ON users (deletion_requested_at) WHERE deletion_requested_at IS NOT NULL;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING GIN (lower(display_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_unit_prefix ON users (lower(unit_number) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_interests ON users USING GIN (interests);
 `
-	pool := postgres.Pool()
-	if pool == nil {
-		return errors.New("postgres pool is not initialized")
-	}
-	if _, err := pool.Exec(ctx, ddl); err != nil {
+	if _, err := db.Exec(ctx, ddl); err != nil {
 		utils.Errorf("failed to ensure users table: %v", err)
 		return err
 	}
 	return nil
 }
 
-// InsertUser inserts a new user. Caller must provide a hashed password.
-func InsertUser(ctx context.Context, u *User) error {
-	if u.ID == uuid.Nil {
-		u.ID = uuid.New()
-	}
-	const q = `
-INSERT INTO users (
-    id, unit_number, email, hashed_password, profile_picture_url,
-    is_directory_opt_in, is_admin, status
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING created_at, updated_at;
-`
-	pool := postgres.Pool()
-	if pool == nil {
-		return errors.New("postgres pool is not initialized")
-	}
-	return pool.QueryRow(ctx, q,
-		u.ID, u.UnitNumber, u.Email, u.HashedPassword, u.ProfilePictureURL,
-		u.IsDirectoryOptIn, u.IsAdmin, u.Status,
-	).Scan(&u.CreatedAt, &u.UpdatedAt)
+type pgUserRepo struct {
+	db DBTX
 }
 
-// GetUserByEmail fetches a user by email.
-func GetUserByEmail(ctx context.Context, email string) (*User, error) {
-	const q = `
-SELECT id, unit_number, email, hashed_password, profile_picture_url,
-       is_directory_opt_in, is_admin, status, created_at, updated_at
-FROM users WHERE email = $1 LIMIT 1;
-`
-	pool := postgres.Pool()
-	if pool == nil {
-		return nil, errors.New("postgres pool is not initialized")
-	}
+// NewPgUserRepo returns a Postgres-backed UserRepo.
+func NewPgUserRepo(db DBTX) UserRepo {
+	return &pgUserRepo{db: db}
+}
+
+const userColumns = `id, community_id, unit_number, email, hashed_password, profile_picture_url,
+       display_name, pronouns, bio, move_in_date, interests, contact_method, privacy, building,
+       is_directory_opt_in, is_admin, is_moderator, status, status_reason, suspended_until,
+       deletion_requested_at, token_version, failed_login_attempts, locked_until,
+       unit_id, verification, verification_note, household_id, moderation_hidden, created_at, updated_at`
+
+func scanUser(row pgx.Row) (*User, error) {
 	var u User
 	var profileURL *string
-	err := pool.QueryRow(ctx, q, email).Scan(
-		&u.ID, &u.UnitNumber, &u.Email, &u.HashedPassword, &profileURL,
-		&u.IsDirectoryOptIn, &u.IsAdmin, &u.Status, &u.CreatedAt, &u.UpdatedAt,
+	err := row.Scan(
+		&u.ID, &u.CommunityID, &u.UnitNumber, &u.Email, &u.HashedPassword, &profileURL,
+		&u.DisplayName, &u.Pronouns, &u.Bio, &u.MoveInDate, &u.Interests, &u.ContactMethod, &u.Privacy, &u.Building,
+		&u.IsDirectoryOptIn, &u.IsAdmin, &u.IsModerator, &u.Status, &u.StatusReason, &u.SuspendedUntil,
+		&u.DeletionRequestedAt, &u.TokenVersion, &u.FailedLoginAttempts, &u.LockedUntil,
+		&u.UnitID, &u.Verification, &u.VerificationNote, &u.HouseholdID, &u.ModerationHidden, &u.CreatedAt, &u.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -104,68 +223,321 @@ FROM users WHERE email = $1 LIMIT 1;
 	return &u, nil
 }
 
-// GetUserByID fetches a user by ID.
-func GetUserByID(ctx context.Context, id uuid.UUID) (*User, error) {
-	const q = `
-SELECT id, unit_number, email, hashed_password, profile_picture_url,
-       is_directory_opt_in, is_admin, status, created_at, updated_at
-FROM users WHERE id = $1 LIMIT 1;
-`
-	pool := postgres.Pool()
-	if pool == nil {
-		return nil, errors.New("postgres pool is not initialized")
+// Insert inserts a new user into the context's community. Caller must provide a hashed password.
+func (r *pgUserRepo) Insert(ctx context.Context, u *User) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
 	}
-	var u User
-	var profileURL *string
-	err := pool.QueryRow(ctx, q, id).Scan(
-		&u.ID, &u.UnitNumber, &u.Email, &u.HashedPassword, &profileURL,
-		&u.IsDirectoryOptIn, &u.IsAdmin, &u.Status, &u.CreatedAt, &u.UpdatedAt,
-	)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
+	u.CommunityID = insertCommunity(ctx)
+	if u.Verification == "" {
+		u.Verification = VerificationUnverified
 	}
-	u.ProfilePictureURL = profileURL
-	return &u, nil
+	const q = `
+INSERT INTO users (
+    id, community_id, unit_number, email, hashed_password, profile_picture_url,
+    is_directory_opt_in, is_admin, is_moderator, status,
+    unit_id, verification, verification_note, household_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+) RETURNING created_at, updated_at;
+`
+	err := r.db.QueryRow(ctx, q,
+		u.ID, u.CommunityID, u.UnitNumber, u.Email, u.HashedPassword, u.ProfilePictureURL,
+		u.IsDirectoryOptIn, u.IsAdmin, u.IsModerator, u.Status,
+		u.UnitID, u.Verification, u.VerificationNote, u.HouseholdID,
+	).Scan(&u.CreatedAt, &u.UpdatedAt)
+	return translateErr(err)
 }
 
-// UpdateUser updates mutable fields and bumps updated_at.
-func UpdateUser(ctx context.Context, u *User) error {
-	const q = `
+// GetByEmail fetches a user by email. Emails are unique across communities,
+// so logins look them up with AcrossCommunities.
+func (r *pgUserRepo) GetByEmail(ctx context.Context, email string) (*User, error) {
+	q := `SELECT ` + userColumns + ` FROM users WHERE email = $1 AND ` + communitySQL("community_id", "$2") + ` LIMIT 1;`
+	return scanUser(r.db.QueryRow(ctx, q, email, communityArg(ctx)))
+}
+
+// GetByID fetches a user by ID.
+func (r *pgUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	q := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND ` + communitySQL("community_id", "$2") + ` LIMIT 1;`
+	return scanUser(r.db.QueryRow(ctx, q, id, communityArg(ctx)))
+}
+
+// Update updates the profile, unit and household fields and bumps updated_at.
+func (r *pgUserRepo) Update(ctx context.Context, u *User) error {
+	q := `
 UPDATE users SET
     unit_number = $2,
     email = $3,
     hashed_password = $4,
     profile_picture_url = $5,
     is_directory_opt_in = $6,
-    is_admin = $7,
-    status = $8,
+    display_name = $7,
+    pronouns = $8,
+    bio = $9,
+    move_in_date = $10,
+    interests = $11,
+    contact_method = $12,
+    privacy = $13,
+    building = $14,
+    unit_id = $16,
+    verification = $17,
+    verification_note = $18,
+    household_id = $19,
     updated_at = NOW()
-WHERE id = $1
+WHERE id = $1 AND ` + communitySQL("community_id", "$15") + `
 RETURNING created_at, updated_at;
 `
-	pool := postgres.Pool()
-	if pool == nil {
-		return errors.New("postgres pool is not initialized")
-	}
 	var createdAt time.Time
-	return pool.QueryRow(ctx, q,
-		u.ID, u.UnitNumber, u.Email, u.HashedPassword, u.ProfilePictureURL,
-		u.IsDirectoryOptIn, u.IsAdmin, u.Status,
+	err := r.db.QueryRow(ctx, q,
+		u.ID, u.UnitNumber, u.Email, u.HashedPassword, u.ProfilePictureURL, u.IsDirectoryOptIn,
+		u.DisplayName, u.Pronouns, u.Bio, u.MoveInDate, nonNilStrings(u.Interests),
+		u.ContactMethod, u.Privacy, u.Building, communityArg(ctx),
+		u.UnitID, u.Verification, u.VerificationNote, u.HouseholdID,
 	).Scan(&createdAt, &u.UpdatedAt)
+	return translateErr(err)
 }
 
-// SoftDeleteUser flags a user as inactive. Hard delete is handled by retention jobs.
-func SoftDeleteUser(ctx context.Context, id uuid.UUID) error {
-	const q = `
-UPDATE users SET status = 'inactive', updated_at = NOW() WHERE id = $1;
+// SetRoles grants or revokes the admin and moderator roles.
+func (r *pgUserRepo) SetRoles(ctx context.Context, id uuid.UUID, isAdmin, isModerator bool) error {
+	q := `
+UPDATE users SET is_admin = $2, is_moderator = $3, updated_at = NOW()
+WHERE id = $1 AND ` + communitySQL("community_id", "$4") + `
+RETURNING id;
+`
+	return r.db.QueryRow(ctx, q, id, isAdmin, isModerator, communityArg(ctx)).Scan(&id)
+}
+
+// SetStatus writes the account status fields and, when revoke is true, bumps
+// token_version in the same statement.
+func (r *pgUserRepo) SetStatus(ctx context.Context, u *User, revoke bool) error {
+	q := `
+UPDATE users SET
+    status = $2,
+    status_reason = $3,
+    suspended_until = $4,
+    deletion_requested_at = $5,
+    token_version = token_version + CASE WHEN $6 THEN 1 ELSE 0 END,
+    updated_at = NOW()
+WHERE id = $1 AND ` + communitySQL("community_id", "$7") + `
+RETURNING token_version, updated_at;
 `
-	pool := postgres.Pool()
-	if pool == nil {
-		return errors.New("postgres pool is not initialized")
+	return r.db.QueryRow(ctx, q,
+		u.ID, u.Status, u.StatusReason, u.SuspendedUntil, u.DeletionRequestedAt, revoke, communityArg(ctx),
+	).Scan(&u.TokenVersion, &u.UpdatedAt)
+}
+
+// SetModerationHidden hides the profile from the directory or restores it.
+func (r *pgUserRepo) SetModerationHidden(ctx context.Context, id uuid.UUID, hidden bool) error {
+	q := `UPDATE users SET moderation_hidden = $2, updated_at = NOW() WHERE id = $1 AND ` + communitySQL("community_id", "$3") + `;`
+	_, err := r.db.Exec(ctx, q, id, hidden, communityArg(ctx))
+	return err
+}
+
+// ListByIDs fetches the users with the given ids in one query; missing ids are skipped.
+func (r *pgUserRepo) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	q := `SELECT ` + userColumns + ` FROM users WHERE id = ANY($1::uuid[]) AND ` + communitySQL("community_id", "$2") + `;`
+	rows, err := r.db.Query(ctx, q, uuidStrings(ids), communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	return out, rows.Err()
+}
+
+// nonNilStrings maps nil to an empty slice so NOT NULL array columns get '{}'.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// RecordLoginFailure increments failed_login_attempts and returns the new count.
+func (r *pgUserRepo) RecordLoginFailure(ctx context.Context, id uuid.UUID) (int, error) {
+	q := `
+UPDATE users SET failed_login_attempts = failed_login_attempts + 1
+WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `
+RETURNING failed_login_attempts;
+`
+	var attempts int
+	err := r.db.QueryRow(ctx, q, id, communityArg(ctx)).Scan(&attempts)
+	return attempts, err
+}
+
+// LockUntil blocks logins for the user until the given time.
+func (r *pgUserRepo) LockUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	q := `UPDATE users SET locked_until = $2 WHERE id = $1 AND ` + communitySQL("community_id", "$3") + `;`
+	_, err := r.db.Exec(ctx, q, id, until, communityArg(ctx))
+	return err
+}
+
+// ResetLoginFailures clears the failure counter and any lock after a successful login.
+func (r *pgUserRepo) ResetLoginFailures(ctx context.Context, id uuid.UUID) error {
+	q := `UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	_, err := r.db.Exec(ctx, q, id, communityArg(ctx))
+	return err
+}
+
+// SoftDelete flags a user as inactive and starts the deletion grace period.
+// The retention worker anonymizes or deletes the account once it has passed.
+func (r *pgUserRepo) SoftDelete(ctx context.Context, id uuid.UUID) error {
+	q := `
+UPDATE users SET
+    status = 'inactive',
+    deletion_requested_at = COALESCE(deletion_requested_at, NOW()),
+    token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;
+`
+	_, err := r.db.Exec(ctx, q, id, communityArg(ctx))
+	return err
+}
+
+// ListDeletionDue returns the inactive users whose deletion was requested at or before cutoff.
+func (r *pgUserRepo) ListDeletionDue(ctx context.Context, cutoff time.Time) ([]User, error) {
+	q := `
+SELECT ` + userColumns + ` FROM users
+WHERE status = 'inactive' AND deletion_requested_at <= $1 AND ` + communitySQL("community_id", "$2") + `
+ORDER BY deletion_requested_at;
+`
+	rows, err := r.db.Query(ctx, q, cutoff, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	return out, rows.Err()
+}
+
+// HasAdmin reports whether the context's community has an admin who is not deleted.
+func (r *pgUserRepo) HasAdmin(ctx context.Context) (bool, error) {
+	q := `SELECT EXISTS (SELECT 1 FROM users WHERE is_admin AND status <> 'deleted' AND ` + communitySQL("community_id", "$1") + `);`
+	var exists bool
+	err := r.db.QueryRow(ctx, q, communityArg(ctx)).Scan(&exists)
+	return exists, err
+}
+
+// ListForReview returns the registrations flagged for review, oldest first.
+func (r *pgUserRepo) ListForReview(ctx context.Context) ([]User, error) {
+	q := `
+SELECT ` + userColumns + ` FROM users
+WHERE verification = 'review' AND status <> 'deleted' AND ` + communitySQL("community_id", "$1") + `
+ORDER BY created_at, id;
+`
+	rows, err := r.db.Query(ctx, q, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	return out, rows.Err()
+}
+
+// ListByHousehold returns a household's members in the order they joined the site.
+func (r *pgUserRepo) ListByHousehold(ctx context.Context, householdID uuid.UUID) ([]User, error) {
+	q := `
+SELECT ` + userColumns + ` FROM users
+WHERE household_id = $1 AND ` + communitySQL("community_id", "$2") + `
+ORDER BY created_at, id;
+`
+	rows, err := r.db.Query(ctx, q, householdID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
 	}
-	_, err := pool.Exec(ctx, q, id)
+	return out, rows.Err()
+}
+
+// AnonymizedEmail is the placeholder address an anonymized account keeps so
+// the email column stays unique.
+func AnonymizedEmail(id uuid.UUID) string {
+	return "deleted+" + id.String() + "@invalid"
+}
+
+// Anonymize scrubs a user's personal details and relationships but keeps the
+// row, so their posts, comments and reactions stay up without attribution.
+func (r *pgUserRepo) Anonymize(ctx context.Context, id uuid.UUID) error {
+	q := `
+WITH target AS (
+    SELECT id FROM users WHERE id = $1 AND ` + communitySQL("community_id", "$3") + `
+), blocks AS (
+    DELETE FROM user_blocks WHERE user_id IN (SELECT id FROM target) OR target_id IN (SELECT id FROM target)
+), subscriptions AS (
+    DELETE FROM board_subscriptions WHERE user_id IN (SELECT id FROM target)
+), memberships AS (
+    DELETE FROM board_members WHERE user_id IN (SELECT id FROM target)
+), exports AS (
+    DELETE FROM data_exports WHERE user_id IN (SELECT id FROM target)
+)
+UPDATE users SET
+    unit_number = '',
+    email = $2,
+    hashed_password = '',
+    profile_picture_url = NULL,
+    display_name = NULL,
+    pronouns = NULL,
+    bio = NULL,
+    move_in_date = NULL,
+    interests = '{}',
+    contact_method = NULL,
+    privacy = '{}',
+    building = NULL,
+    is_directory_opt_in = FALSE,
+    is_admin = FALSE,
+    is_moderator = FALSE,
+    status = 'deleted',
+    status_reason = NULL,
+    suspended_until = NULL,
+    deletion_requested_at = NULL,
+    unit_id = NULL,
+    verification_note = NULL,
+    household_id = NULL,
+    moderation_hidden = FALSE,
+    token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id IN (SELECT id FROM target);
+`
+	_, err := r.db.Exec(ctx, q, id, AnonymizedEmail(id), communityArg(ctx))
+	return err
+}
+
+// Delete removes a user for good. Their posts, comments, reactions, reports,
+// warnings and history go with them through ON DELETE CASCADE.
+func (r *pgUserRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	q := `DELETE FROM users WHERE id = $1 AND ` + communitySQL("community_id", "$2") + `;`
+	_, err := r.db.Exec(ctx, q, id, communityArg(ctx))
 	return err
 }
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Warning is a formal moderator warning issued to a resident.
+type Warning struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ReportID  *uuid.UUID
+	Reason    string
+	IssuedBy  *uuid.UUID
+	CreatedAt time.Time
+}
+
+// EnsureWarningsTable creates the user_warnings table.
+func EnsureWarningsTable(ctx context.Context, db DBTX) error {
+	const ddl = `
+CREATE TABLE IF NOT EXISTS user_warnings (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL,
+	report_id UUID NULL,
+	reason TEXT NOT NULL,
+	issued_by UUID NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	CONSTRAINT fk_user_warnings_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user_warnings_report FOREIGN KEY (report_id) REFERENCES reports(id) ON DELETE SET NULL,
+	CONSTRAINT fk_user_warnings_issued_by FOREIGN KEY (issued_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_warnings_user ON user_warnings (user_id, created_at DESC);
+`
+	if _, err := db.Exec(ctx, ddl); err != nil {
+		utils.Errorf("failed to ensure user_warnings table: %v", err)
+		return err
+	}
+	return nil
+}
+
+type pgWarningRepo struct {
+	db DBTX
+}
+
+// NewPgWarningRepo returns a Postgres-backed WarningRepo.
+func NewPgWarningRepo(db DBTX) WarningRepo {
+	return &pgWarningRepo{db: db}
+}
+
+// Insert records a new warning. It returns ErrInvalidReference when the user
+// is not in the context's community.
+func (r *pgWarningRepo) Insert(ctx context.Context, w *Warning) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	q := `
+INSERT INTO user_warnings (id, user_id, report_id, reason, issued_by)
+SELECT $1::uuid, $2::uuid, $3::uuid, $4::text, $5::uuid
+WHERE ` + userInCommunitySQL("$2::uuid", "$6") + `
+RETURNING created_at;
+`
+	err := r.db.QueryRow(ctx, q, w.ID, w.UserID, w.ReportID, w.Reason, w.IssuedBy, communityArg(ctx)).Scan(&w.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidReference
+	}
+	return translateErr(err)
+}
+
+// ListByUser returns a user's warnings, newest first.
+func (r *pgWarningRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]Warning, error) {
+	q := `
+SELECT id, user_id, report_id, reason, issued_by, created_at
+FROM user_warnings WHERE user_id = $1 AND ` + userInCommunitySQL("user_id", "$2") + `
+ORDER BY created_at DESC;
+`
+	rows, err := r.db.Query(ctx, q, userID, communityArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Warning
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.ID, &w.UserID, &w.ReportID, &w.Reason, &w.IssuedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
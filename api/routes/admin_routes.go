@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminRoutes registers admin account management under /admin/users
+// and the audit log under /admin/audit.
+func RegisterAdminRoutes(r gin.IRouter, service *services.AccountService, audit *services.AuditService, authRequired gin.HandlerFunc) {
+	admin := r.Group("/admin", authRequired)
+
+	admin.GET("/audit", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.AuditQueryInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		out, err := audit.Query(c.Request.Context(), actorID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.GET("/audit/export", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.AuditQueryInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		var buf bytes.Buffer
+		if err := audit.Export(c.Request.Context(), actorID, in, &buf); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="audit-events.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	})
+
+	users := admin.Group("/users")
+
+	users.PUT("/:user_id/roles", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		var in services.UpdateRolesInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.UpdateRoles(c.Request.Context(), actorID, userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	users.GET("/:user_id", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		out, err := service.Get(c.Request.Context(), actorID, userID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	users.GET("/:user_id/actions", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		out, err := service.History(c.Request.Context(), actorID, userID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	users.POST("/:user_id/suspend", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		var in services.SuspendUserInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Suspend(c.Request.Context(), actorID, userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	users.POST("/:user_id/ban", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		var in services.AccountActionInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Ban(c.Request.Context(), actorID, userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	users.POST("/:user_id/reinstate", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		var in services.AccountActionInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Reinstate(c.Request.Context(), actorID, userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
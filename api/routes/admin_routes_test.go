@@ -0,0 +1,147 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testAdminRoutes(t *testing.T, srv *testutil.Server) {
+	_, admin := srv.Register(t, "acct.admin@example.com", "1001", "correct-horse-1")
+	srv.MakeAdmin(t, "acct.admin@example.com")
+	adminID, _ := srv.Register(t, "acct.admin2@example.com", "1002", "correct-horse-1")
+	srv.MakeAdmin(t, "acct.admin2@example.com")
+	residentID, resident := srv.Register(t, "acct.resident@example.com", "1003", "correct-horse-1")
+	bannedID, banned := srv.Register(t, "acct.banned@example.com", "1004", "correct-horse-1")
+
+	login := func(email string, status int) string {
+		t.Helper()
+		var out struct {
+			Token string `json:"token"`
+		}
+		resp := srv.Expect(t, status, http.MethodPost, "/api/auth/login", "", map[string]any{
+			"email": email, "password": "correct-horse-1",
+		})
+		if status == http.StatusOK {
+			resp.JSON(t, &out)
+		}
+		return out.Token
+	}
+	userPath := func(id, action string) string {
+		if action == "" {
+			return "/api/admin/users/" + id
+		}
+		return "/api/admin/users/" + id + "/" + action
+	}
+	until := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("requires an admin", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, userPath(residentID, ""), resident, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, userPath(bannedID, "ban"), resident, map[string]any{
+			"reason": "no",
+		}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, userPath(adminID, "ban"), admin, map[string]any{
+			"reason": "no",
+		}).Error(t, services.CodeForbidden)
+	})
+
+	t.Run("suspend validates input", func(t *testing.T) {
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPost, userPath(residentID, "suspend"), admin, map[string]any{
+			"reason": "spam", "until": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		}).Error(t, services.CodeValidation)
+		if bad.Details["until"] == "" {
+			t.Fatalf("expected until detail: %+v", bad.Details)
+		}
+		bad = srv.Expect(t, http.StatusBadRequest, http.MethodPost, userPath(residentID, "suspend"), admin, map[string]any{}).Error(t, services.CodeValidation)
+		if bad.Details["reason"] == "" || bad.Details["until"] == "" {
+			t.Fatalf("expected reason and until details: %+v", bad.Details)
+		}
+	})
+
+	t.Run("suspension blocks tokens and logins", func(t *testing.T) {
+		var out services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, userPath(residentID, "suspend"), admin, map[string]any{
+			"reason": "repeated spam", "until": until,
+		}).JSON(t, &out)
+		if out.Status != "suspended" || out.SuspendedUntil == nil || out.StatusReason == nil {
+			t.Fatalf("unexpected account: %+v", out)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/auth/me", resident, nil).Error(t, services.CodeUnauthorized)
+		login("acct.resident@example.com", http.StatusForbidden)
+		srv.Expect(t, http.StatusConflict, http.MethodPost, userPath(bannedID, "reinstate"), admin, map[string]any{
+			"reason": "not restricted",
+		}).Error(t, services.CodeConflict)
+	})
+
+	t.Run("reinstate restores access with a fresh login", func(t *testing.T) {
+		var out services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, userPath(residentID, "reinstate"), admin, map[string]any{
+			"reason": "appeal accepted",
+		}).JSON(t, &out)
+		if out.Status != "active" || out.SuspendedUntil != nil {
+			t.Fatalf("unexpected account: %+v", out)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/auth/me", resident, nil).Error(t, services.CodeUnauthorized)
+		token := login("acct.resident@example.com", http.StatusOK)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", token, nil)
+	})
+
+	t.Run("ban blocks the account", func(t *testing.T) {
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", banned, nil)
+		// A profile edit that read the account before the ban must not undo it.
+		stale, err := srv.Repos.Users.GetByEmail(context.Background(), "acct.banned@example.com")
+		if err != nil || stale == nil {
+			t.Fatalf("load user: %v", err)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodPost, userPath(bannedID, "ban"), admin, map[string]any{
+			"reason": "threats",
+		})
+		if err := srv.Repos.Users.Update(context.Background(), stale); err != nil {
+			t.Fatalf("stale update: %v", err)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/auth/me", banned, nil)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/auth/login", "", map[string]any{
+			"email": "acct.banned@example.com", "password": "correct-horse-1",
+		}).Error(t, services.CodeForbidden)
+
+		srv.Expect(t, http.StatusConflict, http.MethodPost, userPath(bannedID, "suspend"), admin, map[string]any{
+			"reason": "cool off", "until": until,
+		}).Error(t, services.CodeConflict)
+		var out services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, userPath(bannedID, ""), admin, nil).JSON(t, &out)
+		if out.Status != "banned" || out.SuspendedUntil != nil {
+			t.Fatalf("suspend replaced the ban: %+v", out)
+		}
+	})
+
+	t.Run("pending deletions cannot be restricted", func(t *testing.T) {
+		leaverID, leaver := srv.Register(t, "acct.leaver@example.com", "1005", "correct-horse-1")
+		srv.Expect(t, http.StatusAccepted, http.MethodDelete, "/api/profile/me", leaver, map[string]any{"password": "correct-horse-1"})
+		srv.Expect(t, http.StatusConflict, http.MethodPost, userPath(leaverID, "suspend"), admin, map[string]any{
+			"reason": "spam", "until": until,
+		}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusConflict, http.MethodPost, userPath(leaverID, "ban"), admin, map[string]any{
+			"reason": "spam",
+		}).Error(t, services.CodeConflict)
+		var out services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, userPath(leaverID, ""), admin, nil).JSON(t, &out)
+		if out.Status != "inactive" {
+			t.Fatalf("deletion request was replaced: %+v", out)
+		}
+	})
+
+	t.Run("history records every action", func(t *testing.T) {
+		var out []services.AccountActionDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, userPath(residentID, "actions"), admin, nil).JSON(t, &out)
+		if len(out) != 2 || out[0].Action != "reinstate" || out[1].Action != "suspend" {
+			t.Fatalf("unexpected history: %+v", out)
+		}
+		if out[1].Until == nil || out[1].ActorID == nil || out[1].Reason != "repeated spam" {
+			t.Fatalf("unexpected suspend entry: %+v", out[1])
+		}
+	})
+}
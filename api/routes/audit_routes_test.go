@@ -0,0 +1,105 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testAuditRoutes(t *testing.T, srv *testutil.Server) {
+	adminID, admin := srv.Register(t, "audit.admin@example.com", "1101", "correct-horse-1")
+	srv.MakeAdmin(t, "audit.admin@example.com")
+	residentID, resident := srv.Register(t, "audit.resident@example.com", "1102", "correct-horse-1")
+
+	query := func(params url.Values) []services.AuditEventDTO {
+		t.Helper()
+		var out []services.AuditEventDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/audit?"+params.Encode(), admin, nil).JSON(t, &out)
+		return out
+	}
+
+	var created services.BoardDTO
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", admin, map[string]any{"name": "Audited"}).JSON(t, &created)
+	board := created.ID
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", admin, map[string]any{
+		"board_id": board, "title": "Pool closed", "content": "Maintenance", "bulletin": true,
+	})
+	createPost(t, srv, admin, board, "not a bulletin")
+
+	t.Run("privileged actions are recorded", func(t *testing.T) {
+		var account services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodPut, "/api/admin/users/"+residentID+"/roles", admin, map[string]any{
+			"is_moderator": true,
+		}).JSON(t, &account)
+		if !account.IsModerator || account.IsAdmin {
+			t.Fatalf("unexpected roles: %+v", account)
+		}
+		srv.Expect(t, http.StatusForbidden, http.MethodPut, "/api/admin/users/"+adminID+"/roles", admin, map[string]any{
+			"is_admin": false,
+		}).Error(t, services.CodeForbidden)
+
+		events := query(url.Values{"actor_id": {adminID}})
+		if len(events) != 3 {
+			t.Fatalf("expected 3 events, got %+v", events)
+		}
+		if events[0].Action != "user.roles" || events[1].Action != "bulletin.create" || events[2].Action != "board.create" {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+		if events[0].TargetID == nil || *events[0].TargetID != residentID || events[0].Details["is_moderator"] != "true" {
+			t.Fatalf("unexpected role event: %+v", events[0])
+		}
+		if *events[2].TargetID != board || events[2].Details["name"] != "Audited" {
+			t.Fatalf("unexpected board event: %+v", events[2])
+		}
+	})
+
+	t.Run("filters by target, action and time", func(t *testing.T) {
+		events := query(url.Values{"target_type": {"user"}, "target_id": {residentID}})
+		if len(events) != 1 || events[0].Action != "user.roles" {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+		events = query(url.Values{"action": {"board.create"}, "actor_id": {adminID}})
+		if len(events) != 1 {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+		future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		if events := query(url.Values{"actor_id": {adminID}, "from": {future}}); len(events) != 0 {
+			t.Fatalf("expected no events after %s: %+v", future, events)
+		}
+		if events := query(url.Values{"actor_id": {adminID}, "to": {future}, "limit": {"2"}}); len(events) != 2 {
+			t.Fatalf("expected limit to apply: %+v", events)
+		}
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/admin/audit?from=yesterday&actor_id=me", admin, nil).Error(t, services.CodeValidation)
+		if bad.Details["from"] == "" || bad.Details["actor_id"] == "" {
+			t.Fatalf("expected from and actor_id details: %+v", bad.Details)
+		}
+	})
+
+	t.Run("exports CSV", func(t *testing.T) {
+		resp := srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/audit/export?actor_id="+adminID, admin, nil)
+		if ct := resp.Header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Fatalf("content type = %q", ct)
+		}
+		records, err := csv.NewReader(bytes.NewReader(resp.Body)).ReadAll()
+		if err != nil {
+			t.Fatalf("parse csv: %v", err)
+		}
+		if len(records) != 4 || records[0][3] != "action" || records[1][3] != "user.roles" || records[1][6] != `{"is_moderator":"true"}` {
+			t.Fatalf("unexpected csv: %q", records)
+		}
+	})
+
+	t.Run("requires an admin", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/admin/audit", resident, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/admin/audit/export", resident, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPut, "/api/admin/users/"+residentID+"/roles", resident, map[string]any{
+			"is_admin": true,
+		}).Error(t, services.CodeForbidden)
+	})
+}
@@ -0,0 +1,73 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testAuthRoutes(t *testing.T, srv *testutil.Server) {
+	id, token := srv.Register(t, "Auth.User@Example.com", "101", "correct-horse-1")
+
+	t.Run("register rejects duplicates and missing fields", func(t *testing.T) {
+		dup := srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "auth.user@example.com", "unit_number": "102", "password": "another-pass-1", "invite_code": srv.InviteCode(t),
+		}).Error(t, services.CodeConflict)
+		if dup.Details["email"] == "" {
+			t.Fatalf("expected email detail on conflict: %+v", dup)
+		}
+		missing := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "missing@example.com",
+		}).Error(t, services.CodeValidation)
+		if missing.Details["unit_number"] == "" || missing.Details["password"] == "" || missing.Details["email"] != "" {
+			t.Fatalf("unexpected validation details: %+v", missing.Details)
+		}
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", "not an object").Error(t, services.CodeValidation)
+	})
+
+	t.Run("register validates fields", func(t *testing.T) {
+		invalid := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "not-an-email", "unit_number": strings.Repeat("9", 17), "password": "short",
+		}).Error(t, services.CodeValidation)
+		for _, field := range []string{"email", "unit_number", "password"} {
+			if invalid.Details[field] == "" {
+				t.Errorf("expected %s detail, got %+v", field, invalid.Details)
+			}
+		}
+		weak := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "weak@example.com", "unit_number": "103", "password": "onlyletters",
+		}).Error(t, services.CodeValidation)
+		if weak.Details["password"] == "" {
+			t.Fatalf("expected password detail, got %+v", weak.Details)
+		}
+	})
+
+	t.Run("login", func(t *testing.T) {
+		var out services.AuthResponse
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": " AUTH.user@example.com ", "password": "correct-horse-1",
+		}).JSON(t, &out)
+		if out.Token == "" || out.User.ID != id || out.User.UnitNumber != "101" {
+			t.Fatalf("unexpected login response: %+v", out)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "auth.user@example.com", "password": "wrong",
+		}).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "nobody@example.com", "password": "whatever",
+		}).Error(t, services.CodeUnauthorized)
+	})
+
+	t.Run("me", func(t *testing.T) {
+		var me services.MeDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", token, nil).JSON(t, &me)
+		if me.ID != id || me.Email != "auth.user@example.com" || me.Status != "active" {
+			t.Fatalf("unexpected me response: %+v", me)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/auth/me", "", nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/auth/me", "not-a-jwt", nil).Error(t, services.CodeUnauthorized)
+	})
+}
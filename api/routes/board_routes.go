@@ -3,37 +3,227 @@ package routes
 import (
 	"net/http"
 
-	"github.com/cameronsralla/culdechat/middleware"
 	"github.com/cameronsralla/culdechat/services"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterBoardRoutes registers board related endpoints under /boards.
-func RegisterBoardRoutes(r gin.IRouter) {
-	service := &services.BoardService{}
-
+func RegisterBoardRoutes(r gin.IRouter, service *services.BoardService, authRequired gin.HandlerFunc) {
 	grp := r.Group("/boards")
 
 	grp.GET("", func(c *gin.Context) {
-		out, err := service.List(c.Request.Context())
+		out, err := service.List(c.Request.Context(), optionalUserID(c))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			_ = c.Error(err)
 			return
 		}
 		c.JSON(http.StatusOK, out)
 	})
 
-	grp.POST("", middleware.AuthRequired(), func(c *gin.Context) {
+	grp.POST("", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
 		var in services.CreateBoardInput
-		if err := c.ShouldBindJSON(&in); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !bindJSON(c, &in) {
 			return
 		}
-		out, err := service.Create(c.Request.Context(), in)
+		out, err := service.Create(c.Request.Context(), userUUID, in)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(err)
 			return
 		}
 		c.JSON(http.StatusCreated, out)
 	})
+
+	grp.PUT("/order", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.ReorderBoardsInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Reorder(c.Request.Context(), userUUID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.PATCH("/:board_id", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		var in services.UpdateBoardInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Update(c.Request.Context(), userUUID, boardID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.PUT("/:board_id/subscription", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		var in services.SubscribeInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Subscribe(c.Request.Context(), userUUID, boardID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.DELETE("/:board_id/subscription", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		if err := service.Unsubscribe(c.Request.Context(), userUUID, boardID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	grp.POST("/:board_id/read", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		if err := service.MarkRead(c.Request.Context(), userUUID, boardID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	grp.GET("/:board_id/members", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		out, err := service.ListMembers(c.Request.Context(), userUUID, boardID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.POST("/:board_id/members", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		var in services.BoardMemberInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Join(c.Request.Context(), userUUID, boardID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.POST("/:board_id/members/:user_id/approve", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		memberID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		out, err := service.Approve(c.Request.Context(), userUUID, boardID, memberID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.DELETE("/:board_id/members/:user_id", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		memberID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		if err := service.RemoveMember(c.Request.Context(), userUUID, boardID, memberID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	grp.DELETE("/:board_id", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		boardID, ok := uuidParam(c, "board_id")
+		if !ok {
+			return
+		}
+		var in services.DeleteBoardInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		if err := service.Delete(c.Request.Context(), userUUID, boardID, in); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
 }
@@ -0,0 +1,523 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testBoardRoutes(t *testing.T, srv *testutil.Server) {
+	_, token := srv.Register(t, "boards@example.com", "201", "correct-horse-1")
+	admin := srv.AdminToken(t)
+	missing := uuid.NewString()
+
+	first := createBoard(t, srv, "Dog Lovers")
+	second := createBoard(t, srv, "Book Club")
+
+	listBoards := func() []services.BoardDTO {
+		t.Helper()
+		var boards []services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/boards", token, nil).JSON(t, &boards)
+		return boards
+	}
+	findBoard := func(id string) *services.BoardDTO {
+		t.Helper()
+		for _, b := range listBoards() {
+			if b.ID == id {
+				return &b
+			}
+		}
+		return nil
+	}
+
+	t.Run("create is admin only, validates and rejects duplicates", func(t *testing.T) {
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/boards", "", map[string]any{"name": "Anon"})
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/boards", token, map[string]any{"name": "Resident"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/boards", admin, map[string]any{"name": "  "}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/boards", admin, map[string]any{"name": "Dog Lovers"}).Error(t, services.CodeConflict)
+	})
+
+	t.Run("list newest first", func(t *testing.T) {
+		pos := map[string]int{}
+		for i, b := range listBoards() {
+			pos[b.ID] = i
+		}
+		i, ok1 := pos[second]
+		j, ok2 := pos[first]
+		if !ok1 || !ok2 || i > j {
+			t.Fatalf("expected %s before %s", second, first)
+		}
+	})
+
+	t.Run("update renames and describes", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodPatch, "/api/boards/"+first, token, map[string]any{"name": "Cat Lovers"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusConflict, http.MethodPatch, "/api/boards/"+first, admin, map[string]any{"name": "Book Club"}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPatch, "/api/boards/"+first, admin, map[string]any{"name": " "}).Error(t, services.CodeValidation)
+
+		var out services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+first, admin, map[string]any{
+			"name": "Dog Owners", "description": "Walks and playdates",
+		}).JSON(t, &out)
+		if out.Name != "Dog Owners" || out.Description == nil || *out.Description != "Walks and playdates" {
+			t.Fatalf("unexpected board: %+v", out)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+first, admin, map[string]any{"description": ""}).JSON(t, &out)
+		if out.Name != "Dog Owners" || out.Description != nil {
+			t.Fatalf("description not cleared: %+v", out)
+		}
+		srv.Expect(t, http.StatusNotFound, http.MethodPatch, "/api/boards/"+missing, admin, map[string]any{"name": "x"}).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("archived boards are read-only", func(t *testing.T) {
+		post := createPost(t, srv, token, first, "before archiving")
+		var out services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+first, admin, map[string]any{"archived": true}).JSON(t, &out)
+		if !out.Archived || out.ArchivedAt == nil {
+			t.Fatalf("board not archived: %+v", out)
+		}
+		if b := findBoard(first); b == nil || !b.Archived {
+			t.Fatalf("archived board missing from list: %+v", b)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/posts", token, map[string]any{
+			"board_id": first, "title": "after", "content": "after body",
+		}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/comments", token, map[string]any{
+			"post_id": post, "content": "too late",
+		}).Error(t, services.CodeConflict)
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+first, token, nil).JSON(t, &posts)
+		if len(posts) != 1 {
+			t.Fatalf("archived posts should stay readable: %+v", posts)
+		}
+
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+first, admin, map[string]any{"archived": false}).JSON(t, &out)
+		if out.Archived {
+			t.Fatalf("board still archived: %+v", out)
+		}
+		createPost(t, srv, token, first, "after restoring")
+	})
+
+	t.Run("reorder requires every board once", func(t *testing.T) {
+		ids := func() []string {
+			var out []string
+			for _, b := range listBoards() {
+				out = append(out, b.ID)
+			}
+			return out
+		}
+		current := ids()
+		reversed := make([]string, len(current))
+		for i, id := range current {
+			reversed[len(current)-1-i] = id
+		}
+
+		srv.Expect(t, http.StatusForbidden, http.MethodPut, "/api/boards/order", token, map[string]any{"board_ids": reversed}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPut, "/api/boards/order", admin, map[string]any{"board_ids": reversed[1:]}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPut, "/api/boards/order", admin, map[string]any{
+			"board_ids": append([]string{reversed[0]}, reversed[:len(reversed)-1]...),
+		}).Error(t, services.CodeValidation)
+
+		var out []services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodPut, "/api/boards/order", admin, map[string]any{"board_ids": reversed}).JSON(t, &out)
+		got := ids()
+		for i := range reversed {
+			if got[i] != reversed[i] || out[i].ID != reversed[i] || out[i].SortOrder != i+1 {
+				t.Fatalf("order = %v, want %v", got, reversed)
+			}
+		}
+	})
+
+	t.Run("delete moves or removes posts", func(t *testing.T) {
+		doomed := createBoard(t, srv, "Doomed")
+		moved := createPost(t, srv, token, doomed, "keep me")
+
+		srv.Expect(t, http.StatusForbidden, http.MethodDelete, "/api/boards/"+doomed, token, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusBadRequest, http.MethodDelete, "/api/boards/"+doomed+"?move_to="+doomed, admin, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodDelete, "/api/boards/"+doomed+"?move_to="+missing, admin, nil).Error(t, services.CodeValidation)
+
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/boards/"+doomed+"?move_to="+second, admin, nil)
+		if findBoard(doomed) != nil {
+			t.Fatal("board still listed after delete")
+		}
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+second, token, nil).JSON(t, &posts)
+		if len(posts) != 1 || posts[0].ID != moved {
+			t.Fatalf("post not moved: %+v", posts)
+		}
+
+		gone := createBoard(t, srv, "Gone")
+		removed := createPost(t, srv, token, gone, "delete me")
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/boards/"+gone, admin, nil)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/comments", token, map[string]any{
+			"post_id": removed, "content": "anyone there?",
+		}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodDelete, "/api/boards/"+gone, admin, nil).Error(t, services.CodeNotFound)
+	})
+}
+
+func testSubscriptionRoutes(t *testing.T, srv *testutil.Server) {
+	_, reader := srv.Register(t, "subs.reader@example.com", "211", "correct-horse-1")
+	authorID, author := srv.Register(t, "subs.author@example.com", "212", "correct-horse-1")
+	admin := srv.AdminToken(t)
+
+	all := createBoard(t, srv, "Subs All")
+	bulletins := createBoard(t, srv, "Subs Bulletins")
+	muted := createBoard(t, srv, "Subs Muted")
+
+	subscribe := func(board, level string) {
+		t.Helper()
+		var out services.SubscriptionDTO
+		srv.Expect(t, http.StatusOK, http.MethodPut, "/api/boards/"+board+"/subscription", reader, map[string]any{"level": level}).JSON(t, &out)
+		if out.BoardID != board || out.Level != level || out.UnreadCount != 0 {
+			t.Fatalf("unexpected subscription: %+v", out)
+		}
+	}
+	subscriptions := func(token string) map[string]*services.SubscriptionDTO {
+		t.Helper()
+		var boards []services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/boards", token, nil).JSON(t, &boards)
+		out := map[string]*services.SubscriptionDTO{}
+		for _, b := range boards {
+			if b.Subscription != nil {
+				out[b.ID] = b.Subscription
+			}
+		}
+		return out
+	}
+	feed := func(query string) []services.PostDTO {
+		t.Helper()
+		var out []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/feed"+query, reader, nil).JSON(t, &out)
+		return out
+	}
+
+	t.Run("subscribe validates level and board", func(t *testing.T) {
+		srv.Expect(t, http.StatusBadRequest, http.MethodPut, "/api/boards/"+all+"/subscription", reader, map[string]any{"level": "loud"}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPut, "/api/boards/"+uuid.NewString()+"/subscription", reader, map[string]any{"level": "all"}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPut, "/api/boards/"+all+"/subscription", "", map[string]any{"level": "all"})
+		subscribe(all, "muted")
+		subscribe(all, "all")
+		subscribe(bulletins, "bulletins")
+		subscribe(muted, "muted")
+	})
+
+	first := createPost(t, srv, author, all, "first")
+	second := createPost(t, srv, author, all, "second")
+	createPost(t, srv, author, bulletins, "chatter")
+	var bulletin services.PostDTO
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", admin, map[string]any{
+		"board_id": bulletins, "title": "Water shutoff", "content": "Tuesday 9-11", "bulletin": true,
+	}).JSON(t, &bulletin)
+	createPost(t, srv, author, muted, "ignored")
+	createPost(t, srv, reader, all, "my own post")
+
+	t.Run("board list carries subscription state", func(t *testing.T) {
+		if subs := subscriptions(""); len(subs) != 0 {
+			t.Fatalf("anonymous callers should see no subscriptions: %+v", subs)
+		}
+		subs := subscriptions(reader)
+		if len(subs) != 3 {
+			t.Fatalf("expected 3 subscriptions, got %+v", subs)
+		}
+		if subs[all].UnreadCount != 2 || subs[bulletins].UnreadCount != 1 || subs[muted].UnreadCount != 0 {
+			t.Fatalf("unexpected unread counts: all=%+v bulletins=%+v muted=%+v", subs[all], subs[bulletins], subs[muted])
+		}
+		if subs[muted].Level != "muted" {
+			t.Fatalf("unexpected level: %+v", subs[muted])
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/boards", "not-a-token", nil)
+	})
+
+	t.Run("feed draws from subscribed boards", func(t *testing.T) {
+		posts := feed("")
+		var ids []string
+		for _, p := range posts {
+			ids = append(ids, p.Title)
+		}
+		want := []string{"my own post", "Water shutoff", "second", "first"}
+		if len(ids) != len(want) {
+			t.Fatalf("feed = %v, want %v", ids, want)
+		}
+		for i := range want {
+			if ids[i] != want[i] {
+				t.Fatalf("feed = %v, want %v", ids, want)
+			}
+		}
+
+		page := feed("?limit=2")
+		if len(page) != 2 || page[1].ID != bulletin.ID {
+			t.Fatalf("unexpected first page: %+v", page)
+		}
+		next := feed("?limit=2&before=" + url.QueryEscape(page[1].CreatedAt.Format(time.RFC3339Nano)))
+		if len(next) != 2 || next[0].ID != second || next[1].ID != first {
+			t.Fatalf("unexpected second page: %+v", next)
+		}
+
+		keyset := feed("?limit=2&before=" + url.QueryEscape(page[1].CreatedAt.Format(time.RFC3339Nano)) + "&before_id=" + page[1].ID)
+		if len(keyset) != 2 || keyset[0].ID != second || keyset[1].ID != first {
+			t.Fatalf("unexpected keyset page: %+v", keyset)
+		}
+
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/posts/feed?before=yesterday", reader, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/posts/feed?before_id="+first, reader, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/posts/feed?before=2024-01-01T00:00:00Z&before_id=nope", reader, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/posts/feed", "", nil)
+	})
+
+	t.Run("mark read and unsubscribe", func(t *testing.T) {
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/boards/"+all+"/read", reader, nil)
+		if subs := subscriptions(reader); subs[all].UnreadCount != 0 || subs[bulletins].UnreadCount != 1 {
+			t.Fatalf("unexpected unread counts after reading: %+v %+v", subs[all], subs[bulletins])
+		}
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/boards/"+all+"/read", author, nil).Error(t, services.CodeNotFound)
+
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/boards/"+all+"/subscription", reader, nil)
+		if _, ok := subscriptions(reader)[all]; ok {
+			t.Fatal("still subscribed after unsubscribing")
+		}
+		if posts := feed(""); len(posts) != 1 || posts[0].ID != bulletin.ID {
+			t.Fatalf("feed should only hold the bulletin: %+v", posts)
+		}
+	})
+
+	t.Run("unread counts skip blocked authors and boards the reader cannot see", func(t *testing.T) {
+		subscribe(all, "all")
+		createPost(t, srv, author, all, "after resubscribing")
+		unread := func() int {
+			t.Helper()
+			sub := subscriptions(reader)[all]
+			if sub == nil {
+				return 0
+			}
+			return sub.UnreadCount
+		}
+		if n := unread(); n != 1 {
+			t.Fatalf("unread = %d, want 1", n)
+		}
+		srv.Expect(t, http.StatusNoContent, http.MethodPut, "/api/profile/me/blocks/"+authorID, reader, nil)
+		if n := unread(); n != 0 {
+			t.Fatalf("unread = %d, want blocked authors left out", n)
+		}
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/profile/me/blocks/"+authorID, reader, nil)
+		if n := unread(); n != 1 {
+			t.Fatalf("unread = %d, want 1 after unblocking", n)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+all, admin, map[string]any{"visibility": "members"})
+		if n := unread(); n != 0 {
+			t.Fatalf("unread = %d, want members-only boards left out for non-members", n)
+		}
+		// Unreadable boards are filtered before the limit, so the page is still full.
+		if posts := feed("?limit=1"); len(posts) != 1 || posts[0].ID != bulletin.ID {
+			t.Fatalf("feed page should skip the members-only board: %+v", posts)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+all, admin, map[string]any{"visibility": "public"})
+	})
+}
+
+func testBoardAccessRoutes(t *testing.T, srv *testutil.Server) {
+	residentID, resident := srv.Register(t, "access.resident@example.com", "221", "correct-horse-1")
+	_, outsider := srv.Register(t, "access.outsider@example.com", "222", "correct-horse-1")
+	_, mod := srv.Register(t, "access.mod@example.com", "223", "correct-horse-1")
+	srv.MakeModerator(t, "access.mod@example.com")
+	admin := srv.AdminToken(t)
+
+	var private, notices services.BoardDTO
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", admin, map[string]any{"name": "Access Private", "visibility": "members"}).JSON(t, &private)
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", admin, map[string]any{"name": "Access Notices", "visibility": "announcement"}).JSON(t, &notices)
+	if private.Visibility != "members" || notices.Visibility != "announcement" {
+		t.Fatalf("unexpected visibility: %+v %+v", private, notices)
+	}
+	srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/boards", admin, map[string]any{"name": "Access Bad", "visibility": "secret"}).Error(t, services.CodeValidation)
+
+	secret := createPost(t, srv, mod, private.ID, "Private plans")
+	members := "/api/boards/" + private.ID + "/members"
+
+	t.Run("members-only content is hidden from non-members", func(t *testing.T) {
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/posts/board/"+private.ID, "", nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/posts/board/"+private.ID, resident, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/comments/post/"+secret, resident, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/posts", resident, map[string]any{"board_id": private.ID, "title": "Hi", "content": "Let me in"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/comments", resident, map[string]any{"post_id": secret, "content": "Hello"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/reactions", resident, map[string]any{"post_id": secret, "type": "like"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodPut, "/api/boards/"+private.ID+"/subscription", resident, map[string]any{"level": "all"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+private.ID, mod, nil)
+	})
+
+	t.Run("residents request access and staff approve", func(t *testing.T) {
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/boards/"+notices.ID+"/members", resident, map[string]any{}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, members, outsider, map[string]any{"user_id": residentID}).Error(t, services.CodeForbidden)
+
+		var req services.BoardMemberDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, members, resident, map[string]any{}).JSON(t, &req)
+		if req.Status != "pending" || req.UserID != residentID {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/posts/board/"+private.ID, resident, nil)
+
+		var boards []services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/boards", resident, nil).JSON(t, &boards)
+		for _, b := range boards {
+			if b.ID == private.ID && b.Membership != "pending" {
+				t.Fatalf("membership = %q, want pending", b.Membership)
+			}
+		}
+
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, members, resident, nil).Error(t, services.CodeForbidden)
+		var list []services.BoardMemberDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, members, mod, nil).JSON(t, &list)
+		if len(list) != 1 || list[0].Status != "pending" {
+			t.Fatalf("unexpected members: %+v", list)
+		}
+
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, members+"/"+residentID+"/approve", outsider, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusOK, http.MethodPost, members+"/"+residentID+"/approve", mod, nil).JSON(t, &req)
+		if req.Status != "approved" || req.InvitedBy == nil {
+			t.Fatalf("unexpected approval: %+v", req)
+		}
+
+		createPost(t, srv, resident, private.ID, "Member post")
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", resident, map[string]any{"post_id": secret, "content": "Thanks"})
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", resident, map[string]any{"post_id": secret, "type": "like"})
+		srv.Expect(t, http.StatusOK, http.MethodPut, "/api/boards/"+private.ID+"/subscription", resident, map[string]any{"level": "all"})
+	})
+
+	t.Run("removing a member revokes access and the subscription", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodDelete, members+"/"+residentID, outsider, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, members+"/"+residentID, mod, nil)
+		srv.Expect(t, http.StatusNotFound, http.MethodDelete, members+"/"+residentID, resident, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/comments/post/"+secret, resident, nil)
+
+		var feed []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/feed", resident, nil).JSON(t, &feed)
+		for _, p := range feed {
+			if p.BoardID == private.ID {
+				t.Fatalf("feed still shows members-only post %s", p.ID)
+			}
+		}
+	})
+
+	t.Run("staff invitations are approved at once", func(t *testing.T) {
+		var out services.BoardMemberDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, members, mod, map[string]any{"user_id": residentID}).JSON(t, &out)
+		if out.Status != "approved" {
+			t.Fatalf("invite status = %q, want approved", out.Status)
+		}
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, members, mod, map[string]any{"user_id": uuid.NewString()}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+private.ID, resident, nil)
+	})
+
+	t.Run("bulletins on members-only boards stay private", func(t *testing.T) {
+		var bulletin services.PostDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", admin, map[string]any{
+			"board_id": private.ID, "title": "Members bulletin", "content": "For members", "bulletin": true,
+		}).JSON(t, &bulletin)
+		has := func(token string) bool {
+			t.Helper()
+			var posts []services.PostDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/bulletins", token, nil).JSON(t, &posts)
+			for _, p := range posts {
+				if p.ID == bulletin.ID {
+					return true
+				}
+			}
+			return false
+		}
+		if has("") || has(outsider) || !has(resident) || !has(mod) {
+			t.Fatal("members-only bulletin visibility is wrong")
+		}
+	})
+
+	t.Run("announcement boards accept posts from staff only", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/posts", resident, map[string]any{"board_id": notices.ID, "title": "Hi", "content": "Me too"}).Error(t, services.CodeForbidden)
+		notice := createPost(t, srv, mod, notices.ID, "Pool closed")
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+notices.ID, resident, nil)
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", resident, map[string]any{"post_id": notice, "content": "Thanks"})
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", resident, map[string]any{"post_id": notice, "type": "sad"})
+	})
+
+	t.Run("visibility changes are audited", func(t *testing.T) {
+		var out services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/boards/"+notices.ID, admin, map[string]any{"visibility": "public"}).JSON(t, &out)
+		if out.Visibility != "public" {
+			t.Fatalf("visibility = %q, want public", out.Visibility)
+		}
+		createPost(t, srv, resident, notices.ID, "Open now")
+
+		var events []services.AuditEventDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/audit?target_id="+notices.ID+"&action=board.update", admin, nil).JSON(t, &events)
+		if len(events) != 1 || events[0].Details["visibility"] != "public" {
+			t.Fatalf("unexpected audit events: %+v", events)
+		}
+	})
+}
+
+func testPublicReadRoutes(t *testing.T, srv *testutil.Server) {
+	_, resident := srv.Register(t, "public.resident@example.com", "231", "correct-horse-1")
+	admin := srv.AdminToken(t)
+
+	var open services.BoardDTO
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", admin, map[string]any{"name": "Public Notices", "public_read": true}).JSON(t, &open)
+	closed := createBoard(t, srv, "Residents Only")
+	openPost := createPost(t, srv, resident, open.ID, "Street fair")
+	closedPost := createPost(t, srv, resident, closed, "Gate code")
+	var bulletin services.PostDTO
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", admin, map[string]any{
+		"board_id": open.ID, "title": "Water shut-off", "content": "Tuesday 9-11", "bulletin": true,
+	}).JSON(t, &bulletin)
+
+	t.Run("anonymous callers only read public boards", func(t *testing.T) {
+		var boards []services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/boards", "", nil).JSON(t, &boards)
+		if len(boards) != 1 || boards[0].ID != open.ID || !boards[0].PublicRead {
+			t.Fatalf("anonymous board list = %+v, want only %s", boards, open.ID)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+open.ID, "", nil)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/comments/post/"+openPost, "", nil)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/posts/board/"+closed, "", nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/comments/post/"+closedPost, "", nil).Error(t, services.CodeUnauthorized)
+
+		var bulletins []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/bulletins", "", nil).JSON(t, &bulletins)
+		if len(bulletins) != 1 || bulletins[0].ID != bulletin.ID {
+			t.Fatalf("anonymous bulletins = %+v, want only %s", bulletins, bulletin.ID)
+		}
+	})
+
+	t.Run("everything else needs a session", func(t *testing.T) {
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/directory", "", nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/posts/feed", "", nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/boards", "not-a-token", nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+closed, resident, nil)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory", resident, nil)
+	})
+
+	t.Run("members-only boards cannot be public", func(t *testing.T) {
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/boards", admin, map[string]any{
+			"name": "Secret Public", "visibility": "members", "public_read": true,
+		}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPatch, "/api/boards/"+open.ID, admin, map[string]any{"visibility": "members"}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusForbidden, http.MethodPatch, "/api/boards/"+closed, resident, map[string]any{"public_read": true}).Error(t, services.CodeForbidden)
+	})
+
+	t.Run("the allow-list is configurable", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.Access.PublicRoutes = []string{"GET /api/health", "POST /api/auth/login", "GET /api/directory"}
+		locked := testutil.NewServerWithConfig(t, srv.Repos, cfg)
+
+		locked.Expect(t, http.StatusOK, http.MethodGet, "/api/health", "", nil)
+		locked.Expect(t, http.StatusOK, http.MethodGet, "/api/directory", "", nil)
+		locked.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/boards", "", nil).Error(t, services.CodeUnauthorized)
+		locked.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/posts/board/"+open.ID, "", nil).Error(t, services.CodeUnauthorized)
+		locked.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "public.locked@example.com", "unit_number": "232", "password": "correct-horse-1",
+		})
+		locked.Expect(t, http.StatusOK, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "public.resident@example.com", "password": "correct-horse-1",
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testCommentRoutes(t *testing.T, srv *testutil.Server) {
+	authorID, token := srv.Register(t, "commenter@example.com", "401", "correct-horse-1")
+	board := createBoard(t, srv, "Neighborly Help")
+	post := createPost(t, srv, token, board, "Lost cat")
+
+	for _, content := range []string{"first", "second"} {
+		var out services.CommentDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", token, map[string]any{
+			"post_id": post, "content": content,
+		}).JSON(t, &out)
+		if out.AuthorID != authorID || out.PostID != post || out.Content != content {
+			t.Fatalf("unexpected comment: %+v", out)
+		}
+	}
+
+	t.Run("create validates input", func(t *testing.T) {
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/comments", "", map[string]any{"post_id": post, "content": "x"})
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/comments", token, map[string]any{"post_id": post, "content": " "}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/comments", token, map[string]any{"post_id": "bad", "content": "x"}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/comments", token, map[string]any{"post_id": uuid.NewString(), "content": "x"}).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("list in chronological order", func(t *testing.T) {
+		var comments []services.CommentDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/comments/post/"+post, token, nil).JSON(t, &comments)
+		if len(comments) != 2 || comments[0].Content != "first" || comments[1].Content != "second" {
+			t.Fatalf("unexpected comments: %+v", comments)
+		}
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/comments/post/bad", token, nil).Error(t, services.CodeValidation)
+	})
+}
@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCommunityRoutes registers the current community under /communities
+// and operator community management, including invites into a community,
+// under /admin/communities.
+func RegisterCommunityRoutes(r gin.IRouter, service *services.CommunityService, authRequired gin.HandlerFunc) {
+	r.GET("/communities/current", func(c *gin.Context) {
+		out, err := service.Current(c.Request.Context())
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin := r.Group("/admin/communities", authRequired)
+
+	admin.GET("", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.List(c.Request.Context(), actorID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.POST("", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.CreateCommunityInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Create(c.Request.Context(), actorID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+
+	admin.PUT("/:community_id/admins/:user_id", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		communityID, ok := uuidParam(c, "community_id")
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		out, err := service.GrantAdmin(c.Request.Context(), actorID, communityID, userID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.POST("/:community_id/invites", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		communityID, ok := uuidParam(c, "community_id")
+		if !ok {
+			return
+		}
+		var in services.CreateInviteInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.CreateInvite(c.Request.Context(), actorID, communityID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+}
@@ -0,0 +1,244 @@
+package routes_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testCommunityRoutes(t *testing.T, srv *testutil.Server) {
+	operator := srv.AdminToken(t)
+	var me services.MeDTO
+	srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", operator, nil).JSON(t, &me)
+	residentID, resident := srv.Register(t, "homebody@example.com", "1301", "correct-horse-1")
+	homeBoard := createBoard(t, srv, "Community Home")
+	homePost := createPost(t, srv, resident, homeBoard, "Home post")
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", resident, map[string]any{"directory_opt_in": true})
+
+	var maple services.CommunityDTO
+	t.Run("operators create communities", func(t *testing.T) {
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/admin/communities", operator, map[string]any{
+			"slug": "maple-court", "name": "Maple Court",
+		}).JSON(t, &maple)
+		if maple.Slug != "maple-court" || maple.Name != "Maple Court" {
+			t.Fatalf("unexpected community: %+v", maple)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/admin/communities", operator, map[string]any{
+			"slug": "maple-court", "name": "Again",
+		}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/admin/communities", operator, map[string]any{
+			"slug": "Maple Court", "name": "Bad",
+		}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/admin/communities", resident, map[string]any{
+			"slug": "elm", "name": "Elm",
+		}).Error(t, services.CodeForbidden)
+
+		var list []services.CommunityDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/communities", operator, nil).JSON(t, &list)
+		if len(list) != 2 || list[0].Slug != "default" || list[1].ID != maple.ID {
+			t.Fatalf("unexpected communities: %+v", list)
+		}
+	})
+
+	var mapleAdminID, mapleAdmin, neighbour string
+	t.Run("residents register into a community", func(t *testing.T) {
+		// Operators invite a new community's first residents.
+		var invite services.InviteDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/admin/communities/"+maple.ID+"/invites", operator, map[string]any{
+			"max_uses": 3,
+		}).JSON(t, &invite)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/admin/communities/"+maple.ID+"/invites", resident, map[string]any{}).
+			Error(t, services.CodeForbidden)
+
+		var out services.AuthResponse
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "maple-admin@example.com", "unit_number": "1", "password": "correct-horse-1", "invite_code": invite.Code,
+		}).JSON(t, &out)
+		if out.User.CommunityID != maple.ID {
+			t.Fatalf("registered into %s, want %s", out.User.CommunityID, maple.ID)
+		}
+		mapleAdminID, mapleAdmin = out.User.ID, out.Token
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "maple-neighbour@example.com", "unit_number": "2", "password": "correct-horse-1", "community": "maple-court", "invite_code": invite.Code,
+		}).JSON(t, &out)
+		neighbour = out.Token
+
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "lost@example.com", "unit_number": "3", "password": "correct-horse-1", "community": "nowhere", "invite_code": invite.Code,
+		}).Error(t, services.CodeValidation)
+		// The invite decides the community.
+		mismatch := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "lost@example.com", "unit_number": "3", "password": "correct-horse-1", "community": "default", "invite_code": invite.Code,
+		}).Error(t, services.CodeValidation)
+		if mismatch.Details["community"] == "" {
+			t.Fatalf("expected community detail, got %+v", mismatch.Details)
+		}
+		// Emails stay unique across communities.
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "homebody@example.com", "unit_number": "4", "password": "correct-horse-1", "invite_code": invite.Code,
+		}).Error(t, services.CodeConflict)
+
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "maple-neighbour@example.com", "password": "correct-horse-1",
+		}).JSON(t, &out)
+		if out.User.CommunityID != maple.ID {
+			t.Fatalf("login community = %s, want %s", out.User.CommunityID, maple.ID)
+		}
+	})
+
+	t.Run("the current community comes from the token or header", func(t *testing.T) {
+		var current services.CommunityDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/communities/current", neighbour, nil).JSON(t, &current)
+		if current.ID != maple.ID {
+			t.Fatalf("token community = %+v", current)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/communities/current", "", nil).JSON(t, &current)
+		if current.Slug != "default" {
+			t.Fatalf("anonymous community = %+v", current)
+		}
+		if status, body := getWithCommunity(t, srv, "/api/communities/current", "maple-court"); status != http.StatusOK || !strings.Contains(body, maple.ID) {
+			t.Fatalf("header community: %d %s", status, body)
+		}
+		if status, _ := getWithCommunity(t, srv, "/api/communities/current", "nowhere"); status != http.StatusNotFound {
+			t.Fatalf("unknown community status = %d, want 404", status)
+		}
+	})
+
+	t.Run("operators appoint community admins", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/boards", mapleAdmin, map[string]any{"name": "Lobby"}).
+			Error(t, services.CodeForbidden)
+		var account services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodPut, "/api/admin/communities/"+maple.ID+"/admins/"+mapleAdminID, operator, nil).JSON(t, &account)
+		if !account.IsAdmin {
+			t.Fatalf("expected admin, got %+v", account)
+		}
+		srv.Expect(t, http.StatusNotFound, http.MethodPut, "/api/admin/communities/"+maple.ID+"/admins/"+me.ID, operator, nil).
+			Error(t, services.CodeNotFound)
+		// Community admins are not operators.
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/admin/communities", mapleAdmin, nil).Error(t, services.CodeForbidden)
+		// The reaction catalog is shared by every community, so only operators change it.
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/reactions/catalog", mapleAdmin, map[string]any{"shortcode": "maple", "emoji": "🍁"}).
+			Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodDelete, "/api/reactions/catalog/like", mapleAdmin, nil).Error(t, services.CodeForbidden)
+	})
+
+	var mapleBoard string
+	t.Run("boards, posts and bulletins stay in their community", func(t *testing.T) {
+		var board services.BoardDTO
+		// Board names only need to be unique within a community.
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", mapleAdmin, map[string]any{"name": "Community Home"}).JSON(t, &board)
+		mapleBoard = board.ID
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", mapleAdmin, map[string]any{
+			"board_id": mapleBoard, "title": "Maple bulletin", "content": "c", "bulletin": true,
+		})
+
+		var boards []services.BoardDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/boards", neighbour, nil).JSON(t, &boards)
+		if len(boards) != 1 || boards[0].ID != mapleBoard {
+			t.Fatalf("maple boards = %+v", boards)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/boards", resident, nil).JSON(t, &boards)
+		for _, b := range boards {
+			if b.ID == mapleBoard {
+				t.Fatalf("default community sees maple board")
+			}
+		}
+
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/posts/board/"+homeBoard, neighbour, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/posts", neighbour, map[string]any{
+			"board_id": homeBoard, "title": "Intruder", "content": "c",
+		}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/comments", neighbour, map[string]any{
+			"post_id": homePost, "content": "Intruder",
+		}).Error(t, services.CodeNotFound)
+
+		var bulletins []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/bulletins", neighbour, nil).JSON(t, &bulletins)
+		if len(bulletins) != 1 || bulletins[0].Title != "Maple bulletin" {
+			t.Fatalf("maple bulletins = %+v", bulletins)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/bulletins", resident, nil).JSON(t, &bulletins)
+		for _, p := range bulletins {
+			if p.Title == "Maple bulletin" {
+				t.Fatalf("default community sees maple bulletin")
+			}
+		}
+	})
+
+	t.Run("the directory and admin actions stay in their community", func(t *testing.T) {
+		var entries []services.DirectoryUserDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory", neighbour, nil).JSON(t, &entries)
+		if len(entries) != 0 {
+			t.Fatalf("maple directory shows other communities: %+v", entries)
+		}
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/admin/users/"+residentID, mapleAdmin, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/admin/users/"+residentID+"/ban", mapleAdmin, map[string]any{
+			"reason": "not mine",
+		}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPut, "/api/profile/me/blocks/"+residentID, neighbour, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/reports", neighbour, map[string]any{
+			"target_type": "post", "target_id": homePost, "reason": "spam",
+		}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/reactions", neighbour, map[string]any{
+			"post_id": homePost, "type": "like",
+		}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPut, "/api/boards/"+homeBoard+"/subscription", neighbour, map[string]any{
+			"level": "all",
+		}).Error(t, services.CodeNotFound)
+
+		var private services.BoardDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", mapleAdmin, map[string]any{"name": "Maple Private", "visibility": "members"}).JSON(t, &private)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/boards/"+private.ID+"/members", mapleAdmin, map[string]any{
+			"user_id": residentID,
+		}).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("the audit log stays in its community", func(t *testing.T) {
+		actions := func(token string) map[string]bool {
+			t.Helper()
+			var events []services.AuditEventDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/audit?limit=1000", token, nil).JSON(t, &events)
+			seen := map[string]bool{}
+			for _, e := range events {
+				if e.TargetID != nil && *e.TargetID == mapleBoard {
+					seen["maple board"] = true
+				}
+				seen[e.Action] = true
+			}
+			return seen
+		}
+		maple := actions(mapleAdmin)
+		if !maple["community.admin_grant"] || !maple["maple board"] || maple["community.create"] {
+			t.Fatalf("maple audit log = %v", maple)
+		}
+		home := actions(operator)
+		if !home["community.create"] || home["maple board"] || home["community.admin_grant"] {
+			t.Fatalf("default audit log = %v", home)
+		}
+	})
+}
+
+// getWithCommunity sends an anonymous GET naming a community by slug.
+func getWithCommunity(t *testing.T, srv *testutil.Server, path, slug string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("X-Community", slug)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
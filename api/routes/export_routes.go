@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterExportRoutes registers the personal data export endpoints under /profile/me/exports.
+func RegisterExportRoutes(r gin.IRouter, service *services.ExportService, authRequired gin.HandlerFunc) {
+	exports := r.Group("/profile/me/exports", authRequired)
+
+	exports.POST("", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.Request(c.Request.Context(), userUUID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusAccepted, out)
+	})
+
+	exports.GET("", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.List(c.Request.Context(), userUUID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	exports.GET("/:export_id", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		exportID, ok := uuidParam(c, "export_id")
+		if !ok {
+			return
+		}
+		out, err := service.Get(c.Request.Context(), userUUID, exportID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	exports.GET("/:export_id/download", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		exportID, ok := uuidParam(c, "export_id")
+		if !ok {
+			return
+		}
+		archive, name, err := service.Download(c.Request.Context(), userUUID, exportID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+		c.Data(http.StatusOK, "application/zip", archive)
+	})
+}
@@ -0,0 +1,156 @@
+package routes_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testExportRoutes(t *testing.T, srv *testutil.Server) {
+	_, token := srv.Register(t, "exporter@example.com", "1001", "correct-horse-1")
+	_, other := srv.Register(t, "snoop@example.com", "1002", "correct-horse-1")
+	board := createBoard(t, srv, "Exports")
+	post := createPost(t, srv, token, board, "My post")
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", token, map[string]any{"post_id": post, "content": "My comment"})
+	srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post, "type": "like"})
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{"display_name": "Exporter"})
+
+	// ready polls an export until its background build finishes.
+	ready := func(t *testing.T, srv *testutil.Server, token, id string) services.DataExportDTO {
+		t.Helper()
+		var out services.DataExportDTO
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me/exports/"+id, token, nil).JSON(t, &out)
+			if out.Status != "pending" {
+				return out
+			}
+		}
+		t.Fatalf("export %s still pending", id)
+		return out
+	}
+
+	t.Run("builds a zip of the resident's data", func(t *testing.T) {
+		var started services.DataExportDTO
+		srv.Expect(t, http.StatusAccepted, http.MethodPost, "/api/profile/me/exports", token, nil).JSON(t, &started)
+		if started.Status != "pending" || started.DownloadURL != nil {
+			t.Fatalf("unexpected new export: %+v", started)
+		}
+		done := ready(t, srv, token, started.ID)
+		if done.Status != "ready" || done.DownloadURL == nil || done.ExpiresAt == nil {
+			t.Fatalf("unexpected finished export: %+v", done)
+		}
+
+		exportsReady := func() int {
+			t.Helper()
+			var me services.MeDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", token, nil).JSON(t, &me)
+			return me.ExportsReady
+		}
+		if n := exportsReady(); n != 1 {
+			t.Fatalf("exports_ready = %d, want 1 once the archive is built", n)
+		}
+
+		resp := srv.Expect(t, http.StatusOK, http.MethodGet, *done.DownloadURL, token, nil)
+		if n := exportsReady(); n != 0 {
+			t.Fatalf("exports_ready = %d, want 0 after downloading", n)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/zip" {
+			t.Fatalf("content type = %q", ct)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(resp.Body), int64(len(resp.Body)))
+		if err != nil {
+			t.Fatalf("open archive: %v", err)
+		}
+		files := map[string][]byte{}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("open %s: %v", f.Name, err)
+			}
+			files[f.Name], _ = io.ReadAll(rc)
+			rc.Close()
+		}
+		for _, name := range []string{"profile.json", "posts.json", "comments.json", "reactions.json", "subscriptions.json", "board_memberships.json", "blocks.json", "warnings.json", "account_history.json"} {
+			if _, ok := files[name]; !ok {
+				t.Fatalf("archive is missing %s", name)
+			}
+		}
+		var profile services.ProfileDTO
+		var posts, comments, reactions []map[string]any
+		for name, v := range map[string]any{"profile.json": &profile, "posts.json": &posts, "comments.json": &comments, "reactions.json": &reactions} {
+			if err := json.Unmarshal(files[name], v); err != nil {
+				t.Fatalf("decode %s: %v", name, err)
+			}
+		}
+		if profile.Email != "exporter@example.com" || profile.DisplayName == nil || *profile.DisplayName != "Exporter" {
+			t.Fatalf("unexpected profile: %+v", profile)
+		}
+		if len(posts) != 1 || posts[0]["id"] != post || len(comments) != 1 || len(reactions) != 1 || reactions[0]["type"] != "like" {
+			t.Fatalf("unexpected content: posts=%v comments=%v reactions=%v", posts, comments, reactions)
+		}
+
+		var list []services.DataExportDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me/exports", token, nil).JSON(t, &list)
+		if len(list) != 1 || list[0].ID != started.ID {
+			t.Fatalf("unexpected exports: %+v", list)
+		}
+	})
+
+	t.Run("exports are private", func(t *testing.T) {
+		var list []services.DataExportDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me/exports", token, nil).JSON(t, &list)
+		id := list[0].ID
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/profile/me/exports/"+id, other, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/profile/me/exports/"+id+"/download", other, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/profile/me/exports/"+uuid.NewString(), token, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/profile/me/exports/nope", token, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/profile/me/exports", "", nil)
+	})
+
+	t.Run("downloads expire", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.Retention.ExportTTL = time.Nanosecond
+		short := testutil.NewServerWithConfig(t, srv.Repos, cfg)
+		var started services.DataExportDTO
+		short.Expect(t, http.StatusAccepted, http.MethodPost, "/api/profile/me/exports", other, nil).JSON(t, &started)
+		done := ready(t, short, other, started.ID)
+		if done.Status != "ready" || done.DownloadURL != nil {
+			t.Fatalf("expired export should have no download link: %+v", done)
+		}
+		short.Expect(t, http.StatusNotFound, http.MethodGet, "/api/profile/me/exports/"+started.ID+"/download", other, nil).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("lost builds time out", func(t *testing.T) {
+		_, waiting := srv.Register(t, "stuck.exporter@example.com", "1003", "correct-horse-1")
+		var me services.MeDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", waiting, nil).JSON(t, &me)
+		// A pending export nobody is building, as after a crash mid-build.
+		stuck := &models.DataExport{UserID: uuid.MustParse(me.ID)}
+		if err := srv.Repos.Exports.Insert(context.Background(), stuck); err != nil {
+			t.Fatalf("insert export: %v", err)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/profile/me/exports", waiting, nil).Error(t, services.CodeConflict)
+
+		cfg := testutil.Config()
+		cfg.Retention.ExportTimeout = time.Nanosecond
+		impatient := testutil.NewServerWithConfig(t, srv.Repos, cfg)
+		var started services.DataExportDTO
+		impatient.Expect(t, http.StatusAccepted, http.MethodPost, "/api/profile/me/exports", waiting, nil).JSON(t, &started)
+		var lost services.DataExportDTO
+		impatient.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me/exports/"+stuck.ID.String(), waiting, nil).JSON(t, &lost)
+		if lost.Status != "failed" || lost.Error == nil {
+			t.Fatalf("stale export should have failed: %+v", lost)
+		}
+		ready(t, impatient, waiting, started.ID)
+	})
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxBodyBytes caps request bodies; field limits are enforced by service validation.
+const maxBodyBytes = 1 << 20
+
+// bindJSON decodes the request body into dst, recording a validation error on failure.
+func bindJSON(c *gin.Context, dst any) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+	if err := c.ShouldBindJSON(dst); err != nil {
+		_ = c.Error(services.ValidationError("request body must be valid JSON", nil))
+		return false
+	}
+	return true
+}
+
+// readBody reads a non-JSON request body such as a CSV upload, recording a
+// validation error when it is too large.
+func readBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes))
+	if err != nil {
+		_ = c.Error(services.ValidationError("request body must be at most 1 MiB", nil))
+		return nil, false
+	}
+	return body, true
+}
+
+// bindQuery decodes query parameters into dst using its `form` tags.
+func bindQuery(c *gin.Context, dst any) bool {
+	if err := c.ShouldBindQuery(dst); err != nil {
+		_ = c.Error(services.ValidationError("invalid query parameters", nil))
+		return false
+	}
+	return true
+}
+
+// currentUserID returns the authenticated user's id set by middleware.RequireSession.
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		_ = c.Error(services.UnauthorizedError("invalid user id in token"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// uuidParam parses the named path parameter as a UUID.
+func uuidParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		_ = c.Error(services.FieldError(name, "must be a valid UUID"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// optionalUserID returns the caller's id on routes open to anonymous callers,
+// or nil when there is no session.
+func optionalUserID(c *gin.Context) *uuid.UUID {
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		return nil
+	}
+	return &id
+}
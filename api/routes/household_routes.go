@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterHouseholdRoutes registers the caller's household under /households
+// and household administration under /admin/households.
+func RegisterHouseholdRoutes(r gin.IRouter, service *services.HouseholdService, authRequired gin.HandlerFunc) {
+	households := r.Group("/households", authRequired)
+
+	households.POST("", func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.CreateHouseholdInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Create(c.Request.Context(), userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+
+	households.POST("/join", func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.JoinHouseholdInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Join(c.Request.Context(), userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	households.GET("/me", func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.Mine(c.Request.Context(), userID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	households.PATCH("/me", func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.UpdateHouseholdInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Update(c.Request.Context(), userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	households.DELETE("/me", func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		if err := service.Leave(c.Request.Context(), userID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	households.POST("/me/invites", func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.HouseholdInviteInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Invite(c.Request.Context(), userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+
+	admin := r.Group("/admin/households", authRequired)
+
+	admin.GET("", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.List(c.Request.Context(), actorID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.POST("/:household_id/move-out", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := uuidParam(c, "household_id")
+		if !ok {
+			return
+		}
+		var in services.AccountActionInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.MoveOut(c.Request.Context(), actorID, id, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
@@ -0,0 +1,211 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testHouseholdRoutes(t *testing.T, srv *testutil.Server) {
+	admin := srv.AdminToken(t)
+	// The founder and partner are on the roster, so the household is linked to the unit registry.
+	postCSV(t, srv, admin, "/api/admin/roster", "building,unit_number,occupancy,email\nWest,1701,occupied,hh.founder@example.com\nWest,1701,occupied,hh.partner@example.com\n").expect(t, http.StatusOK)
+	founderID, founder := srv.Register(t, "hh.founder@example.com", "1701", "correct-horse-1")
+	partnerID, partner := srv.Register(t, "hh.partner@example.com", "1701", "correct-horse-1")
+	_, outsider := srv.Register(t, "hh.outsider@example.com", "1703", "correct-horse-1")
+
+	invite := func(t *testing.T, body map[string]any) services.InviteDTO {
+		t.Helper()
+		var out services.InviteDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/households/me/invites", founder, body).JSON(t, &out)
+		return out
+	}
+	mine := func(t *testing.T, token string) services.HouseholdDTO {
+		t.Helper()
+		var out services.HouseholdDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/households/me", token, nil).JSON(t, &out)
+		return out
+	}
+
+	var household services.HouseholdDTO
+	var kidID, kid string
+
+	t.Run("residents start a household for their unit", func(t *testing.T) {
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/households/me", founder, nil).Error(t, services.CodeNotFound)
+		// The community keeps a unit registry, so unverified residents cannot claim a unit.
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/households", outsider, map[string]any{}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/households", founder, map[string]any{
+			"name": "  The Parks ", "directory_opt_in": false,
+		}).JSON(t, &household)
+		if household.UnitNumber != "1701" || household.Name == nil || *household.Name != "The Parks" || len(household.Members) != 1 || household.Members[0].ID != founderID {
+			t.Fatalf("unexpected household: %+v", household)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/households", founder, map[string]any{}).Error(t, services.CodeConflict)
+		// A unit has one household; co-residents join it by invite.
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/households", partner, map[string]any{}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/households/me/invites", founder, map[string]any{"max_uses": 11}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/households/me/invites", partner, map[string]any{}).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("co-residents register with a household invite", func(t *testing.T) {
+		inv := invite(t, map[string]any{})
+		if inv.HouseholdID == nil || *inv.HouseholdID != household.ID || inv.UnitNumber == nil || *inv.UnitNumber != "1701" ||
+			inv.MaxUses != 1 || inv.ExpiresAt == nil || inv.QRURL != "" {
+			t.Fatalf("unexpected invite: %+v", inv)
+		}
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "hh.wrongunit@example.com", "unit_number": "1702", "password": "correct-horse-1", "invite_code": inv.Code,
+		}).Error(t, services.CodeValidation)
+		if bad.Details["unit_number"] == "" {
+			t.Fatalf("expected a unit_number error: %+v", bad)
+		}
+		var out services.AuthResponse
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "hh.kid@example.com", "unit_number": "1701", "password": "correct-horse-1", "invite_code": inv.Code,
+		}).JSON(t, &out)
+		kidID, kid = out.User.ID, out.Token
+		var me services.MeDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", kid, nil).JSON(t, &me)
+		if me.HouseholdID == nil || *me.HouseholdID != household.ID {
+			t.Fatalf("registering with a household invite should join the household: %+v", me)
+		}
+		// The single use is spent.
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/households/join", partner, map[string]any{"invite_code": inv.Code}).Error(t, services.CodeValidation)
+	})
+
+	t.Run("existing residents join with an invite", func(t *testing.T) {
+		inv := invite(t, map[string]any{"max_uses": 2})
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/households/join", outsider, map[string]any{"invite_code": inv.Code}).Error(t, services.CodeValidation)
+		if bad.Details["unit_number"] == "" {
+			t.Fatalf("residents of other units should not join: %+v", bad)
+		}
+		bad = srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/households/join", partner, map[string]any{"invite_code": srv.InviteCode(t)}).Error(t, services.CodeValidation)
+		if bad.Details["invite_code"] == "" {
+			t.Fatalf("registration invites should not join a household: %+v", bad)
+		}
+		var joined services.HouseholdDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/households/join", partner, map[string]any{"invite_code": inv.Code}).JSON(t, &joined)
+		if len(joined.Members) != 3 || joined.Members[0].ID != founderID || joined.Members[1].ID != partnerID || joined.Members[2].ID != kidID {
+			t.Fatalf("unexpected members: %+v", joined.Members)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/households/join", partner, map[string]any{"invite_code": inv.Code}).Error(t, services.CodeConflict)
+	})
+
+	t.Run("households share a directory entry once opted in", func(t *testing.T) {
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", founder, map[string]any{"directory_opt_in": true})
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", kid, map[string]any{"directory_opt_in": true})
+		members := func(t *testing.T) []services.DirectoryUserDTO {
+			t.Helper()
+			var out []services.DirectoryUserDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory?household="+household.ID, outsider, nil).JSON(t, &out)
+			return out
+		}
+		if got := members(t); len(got) != 0 {
+			t.Fatalf("households stay out of the directory until they opt in: %+v", got)
+		}
+
+		var updated services.HouseholdDTO
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/households/me", kid, map[string]any{"directory_opt_in": true}).JSON(t, &updated)
+		if !updated.DirectoryOptIn || updated.Name == nil || *updated.Name != "The Parks" {
+			t.Fatalf("unexpected update: %+v", updated)
+		}
+		got := members(t)
+		if len(got) != 2 {
+			t.Fatalf("expected the two opted-in members: %+v", got)
+		}
+		for _, e := range got {
+			if e.Household == nil || e.Household.ID != household.ID || e.Household.Name == nil || *e.Household.Name != "The Parks" {
+				t.Fatalf("unexpected household entry: %+v", e)
+			}
+			if e.ID == partnerID || e.UnitNumber != nil {
+				t.Fatalf("the household entry should respect each member's privacy: %+v", e)
+			}
+		}
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/directory?household=nope", outsider, nil).Error(t, services.CodeValidation)
+	})
+
+	t.Run("residents leave their household", func(t *testing.T) {
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/households/me", partner, nil)
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/households/me", partner, nil).Error(t, services.CodeNotFound)
+		if got := mine(t, founder); len(got.Members) != 2 {
+			t.Fatalf("unexpected members after leaving: %+v", got.Members)
+		}
+	})
+
+	t.Run("admins move a household out", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/admin/households", founder, nil).Error(t, services.CodeForbidden)
+		var list []services.AdminHouseholdDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/households", admin, nil).JSON(t, &list)
+		var listed *services.AdminHouseholdDTO
+		for i := range list {
+			if list[i].ID == household.ID {
+				listed = &list[i]
+			}
+		}
+		if listed == nil || len(listed.Members) != 2 || listed.UnitID == nil {
+			t.Fatalf("unexpected admin listing: %+v", list)
+		}
+
+		path := "/api/admin/households/" + household.ID + "/move-out"
+		reason := map[string]any{"reason": "lease ended"}
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, path, founder, reason).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, path, admin, map[string]any{}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/admin/households/"+partnerID+"/move-out", admin, reason).Error(t, services.CodeNotFound)
+
+		srv.MakeAdmin(t, "hh.kid@example.com")
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, path, admin, reason).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusOK, http.MethodPut, "/api/admin/users/"+kidID+"/roles", admin, map[string]any{"is_admin": false})
+
+		var out services.MoveOutDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, path, admin, reason).JSON(t, &out)
+		if !out.UnitVacated || len(out.Members) != 2 {
+			t.Fatalf("unexpected move-out: %+v", out)
+		}
+		for _, m := range out.Members {
+			if m.Status != "moved_out" || m.StatusReason == nil || *m.StatusReason != "lease ended" {
+				t.Fatalf("unexpected member after move-out: %+v", m)
+			}
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/auth/me", founder, nil).Error(t, services.CodeUnauthorized)
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "hh.kid@example.com", "password": "correct-horse-1",
+		}).Error(t, services.CodeForbidden)
+		if units := unitsByNumber(t, srv, admin); units["West/1701"].Occupancy != "vacant" {
+			t.Fatalf("the unit should be vacant: %+v", units["West/1701"])
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/households", admin, nil).JSON(t, &list)
+		for _, h := range list {
+			if h.ID == household.ID {
+				t.Fatalf("the household should be dissolved: %+v", h)
+			}
+		}
+		// The partner left before the move-out and keeps their account.
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", partner, nil)
+
+		var events []services.AuditEventDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/audit?target_type=household&target_id="+household.ID, admin, nil).JSON(t, &events)
+		if len(events) != 1 || events[0].Action != "household.move_out" || events[0].Details["members"] != "2" {
+			t.Fatalf("unexpected audit events: %+v", events)
+		}
+
+		var account services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/admin/users/"+founderID+"/reinstate", admin, reason).JSON(t, &account)
+		if account.Status != "active" {
+			t.Fatalf("moved-out accounts can be reinstated: %+v", account)
+		}
+	})
+}
+
+// unitsByNumber lists the unit registry keyed by building and unit number.
+func unitsByNumber(t *testing.T, srv *testutil.Server, admin string) map[string]services.UnitDTO {
+	t.Helper()
+	var list []services.UnitDTO
+	srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/units", admin, nil).JSON(t, &list)
+	out := map[string]services.UnitDTO{}
+	for _, u := range list {
+		out[u.Building+"/"+u.UnitNumber] = u
+	}
+	return out
+}
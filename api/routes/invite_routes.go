@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterInviteRoutes registers admin invite management under /admin/invites.
+func RegisterInviteRoutes(r gin.IRouter, service *services.InviteService, authRequired gin.HandlerFunc) {
+	invites := r.Group("/admin/invites", authRequired)
+
+	invites.POST("", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.CreateInviteInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Create(c.Request.Context(), actorID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+
+	invites.GET("", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.List(c.Request.Context(), actorID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	invites.DELETE("/:invite_id", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := uuidParam(c, "invite_id")
+		if !ok {
+			return
+		}
+		if err := service.Revoke(c.Request.Context(), actorID, id); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	invites.GET("/:invite_id/qr", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := uuidParam(c, "invite_id")
+		if !ok {
+			return
+		}
+		var in services.InviteQRInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		png, code, err := service.QR(c.Request.Context(), actorID, id, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Header("Content-Disposition", `inline; filename="invite-`+code+`.png"`)
+		c.Data(http.StatusOK, "image/png", png)
+	})
+}
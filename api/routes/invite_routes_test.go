@@ -0,0 +1,170 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testInviteRoutes(t *testing.T, srv *testutil.Server) {
+	admin := srv.AdminToken(t)
+	_, resident := srv.Register(t, "invitee@example.com", "1401", "correct-horse-1")
+
+	create := func(t *testing.T, body map[string]any) services.InviteDTO {
+		t.Helper()
+		var out services.InviteDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/admin/invites", admin, body).JSON(t, &out)
+		return out
+	}
+	register := func(email, unit, code string) *testutil.Response {
+		return srv.Do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": email, "unit_number": unit, "password": "correct-horse-1", "invite_code": code,
+		})
+	}
+	rejects := func(t *testing.T, resp *testutil.Response, field string) {
+		t.Helper()
+		if resp.Status != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400: %s", resp.Status, resp.Body)
+		}
+		if out := resp.Error(t, services.CodeValidation); out.Details[field] == "" {
+			t.Fatalf("expected %s detail, got %+v", field, out.Details)
+		}
+	}
+
+	t.Run("single-use invites work once", func(t *testing.T) {
+		invite := create(t, map[string]any{})
+		if invite.MaxUses != 1 || invite.Status != "active" || len(invite.Code) != 10 || !strings.HasSuffix(invite.Link, invite.Code) {
+			t.Fatalf("unexpected invite: %+v", invite)
+		}
+		// Codes are accepted in any case.
+		if resp := register("single@example.com", "1402", strings.ToLower(invite.Code)); resp.Status != http.StatusCreated {
+			t.Fatalf("register: %d %s", resp.Status, resp.Body)
+		}
+		rejects(t, register("single-again@example.com", "1403", invite.Code), "invite_code")
+
+		var list []services.InviteDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/invites", admin, nil).JSON(t, &list)
+		if len(list) == 0 || list[0].ID != invite.ID || list[0].Uses != 1 || list[0].Status != "used_up" {
+			t.Fatalf("unexpected invites: %+v", list)
+		}
+	})
+
+	t.Run("multi-use and unit-bound invites", func(t *testing.T) {
+		invite := create(t, map[string]any{"max_uses": 2})
+		for _, email := range []string{"multi-1@example.com", "multi-2@example.com"} {
+			if resp := register(email, "1404", invite.Code); resp.Status != http.StatusCreated {
+				t.Fatalf("register %s: %d %s", email, resp.Status, resp.Body)
+			}
+		}
+		rejects(t, register("multi-3@example.com", "1404", invite.Code), "invite_code")
+
+		unit := create(t, map[string]any{"unit_number": " 14B ", "max_uses": 5})
+		if unit.UnitNumber == nil || *unit.UnitNumber != "14B" {
+			t.Fatalf("unexpected unit invite: %+v", unit)
+		}
+		rejects(t, register("wrong-unit@example.com", "15C", unit.Code), "unit_number")
+		if resp := register("right-unit@example.com", "14b", unit.Code); resp.Status != http.StatusCreated {
+			t.Fatalf("register: %d %s", resp.Status, resp.Body)
+		}
+	})
+
+	t.Run("expired and revoked invites are refused", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/admin/invites", admin, map[string]any{"expires_at": past}).
+			Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/admin/invites", admin, map[string]any{"max_uses": 5000}).
+			Error(t, services.CodeValidation)
+		expired := &models.Invite{Code: "EXPIRED" + strings.ToUpper(uuid.NewString()[:8]), MaxUses: 1, ExpiresAt: &past}
+		if err := srv.Repos.Invites.Insert(context.Background(), expired); err != nil {
+			t.Fatalf("insert expired invite: %v", err)
+		}
+		rejects(t, register("late@example.com", "1405", expired.Code), "invite_code")
+
+		invite := create(t, map[string]any{"expires_at": time.Now().Add(24 * time.Hour)})
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/admin/invites/"+invite.ID, admin, nil)
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/admin/invites/"+invite.ID, admin, nil)
+		srv.Expect(t, http.StatusNotFound, http.MethodDelete, "/api/admin/invites/"+uuid.NewString(), admin, nil).Error(t, services.CodeNotFound)
+		rejects(t, register("revoked@example.com", "1406", invite.Code), "invite_code")
+		rejects(t, register("no-code@example.com", "1406", ""), "invite_code")
+
+		var list []services.InviteDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/invites", admin, nil).JSON(t, &list)
+		if list[0].ID != invite.ID || list[0].Status != "revoked" || list[0].RevokedAt == nil {
+			t.Fatalf("unexpected invites: %+v", list)
+		}
+	})
+
+	t.Run("invites render as QR codes", func(t *testing.T) {
+		invite := create(t, map[string]any{"max_uses": 50})
+		resp := srv.Expect(t, http.StatusOK, http.MethodGet, invite.QRURL, admin, nil)
+		if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+			t.Fatalf("content type = %q", ct)
+		}
+		if !bytes.HasPrefix(resp.Body, []byte("\x89PNG\r\n\x1a\n")) {
+			t.Fatalf("body is not a PNG")
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, invite.QRURL+"?size=256", admin, nil)
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, invite.QRURL+"?size=50", admin, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/admin/invites/"+uuid.NewString()+"/qr", admin, nil).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("only admins manage invites", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/admin/invites", resident, map[string]any{}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/admin/invites", resident, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/admin/invites", "", nil)
+	})
+
+	t.Run("open registration needs no invite", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.Registration.RequireInvite = false
+		open := testutil.NewServerWithConfig(t, srv.Repos, cfg)
+		open.Expect(t, http.StatusCreated, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "walk-in@example.com", "unit_number": "1407", "password": "correct-horse-1",
+		})
+		// A code that is given must still be valid.
+		open.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": "walk-in-2@example.com", "unit_number": "1407", "password": "correct-horse-1", "invite_code": "NOPE",
+		}).Error(t, services.CodeValidation)
+	})
+}
+
+func testBootstrapRoutes(t *testing.T, srv *testutil.Server) {
+	cfg := testutil.Config()
+	cfg.Registration.BootstrapAdminEmail = "First.Operator@example.com"
+	boot := testutil.NewServerWithConfig(t, srv.Repos, cfg)
+	register := func(email string) *testutil.Response {
+		return boot.Do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"email": email, "unit_number": "100", "password": "correct-horse-1",
+		})
+	}
+
+	// Other emails still need an invite.
+	if resp := register("not-the-operator@example.com"); resp.Status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", resp.Status, resp.Body)
+	}
+	var out services.AuthResponse
+	resp := register("first.operator@example.com")
+	if resp.Status != http.StatusCreated {
+		t.Fatalf("bootstrap register: %d %s", resp.Status, resp.Body)
+	}
+	resp.JSON(t, &out)
+	// The first operator can issue invites straight away.
+	boot.Expect(t, http.StatusCreated, http.MethodPost, "/api/admin/invites", out.Token, map[string]any{})
+
+	// Once an admin exists the email is an ordinary resident's again.
+	if err := boot.Repos.Users.Delete(context.Background(), uuid.MustParse(out.User.ID)); err != nil {
+		t.Fatalf("delete operator: %v", err)
+	}
+	boot.AdminToken(t)
+	if resp := register("first.operator@example.com"); resp.Status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", resp.Status, resp.Body)
+	}
+}
@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterModerationRoutes registers resident reporting under /reports, the
+// moderator review queue under /moderation and lifting profile hides under
+// /admin/users.
+func RegisterModerationRoutes(r gin.IRouter, service *services.ModerationService, authRequired gin.HandlerFunc) {
+	r.POST("/reports", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.CreateReportInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Report(c.Request.Context(), userUUID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+
+	mod := r.Group("/moderation", authRequired)
+
+	mod.GET("/reports", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.ReportQueueInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		out, err := service.Queue(c.Request.Context(), userUUID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	mod.POST("/reports/:report_id/claim", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		reportID, ok := uuidParam(c, "report_id")
+		if !ok {
+			return
+		}
+		out, err := service.Claim(c.Request.Context(), userUUID, reportID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	mod.POST("/reports/:report_id/resolve", func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		reportID, ok := uuidParam(c, "report_id")
+		if !ok {
+			return
+		}
+		var in services.ResolveReportInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Resolve(c.Request.Context(), userUUID, reportID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	r.POST("/admin/users/:user_id/unhide", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		targetID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		if err := service.UnhideProfile(c.Request.Context(), userUUID, targetID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
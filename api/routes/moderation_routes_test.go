@@ -0,0 +1,208 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testModerationRoutes(t *testing.T, srv *testutil.Server) {
+	authorID, author := srv.Register(t, "mod.author@example.com", "901", "correct-horse-1")
+	_, r1 := srv.Register(t, "mod.r1@example.com", "902", "correct-horse-1")
+	_, r2 := srv.Register(t, "mod.r2@example.com", "903", "correct-horse-1")
+	_, r3 := srv.Register(t, "mod.r3@example.com", "904", "correct-horse-1")
+	_, mod := srv.Register(t, "mod.one@example.com", "905", "correct-horse-1")
+	_, mod2 := srv.Register(t, "mod.two@example.com", "906", "correct-horse-1")
+	srv.MakeModerator(t, "mod.one@example.com")
+	srv.MakeModerator(t, "mod.two@example.com")
+
+	board := createBoard(t, srv, "Moderation")
+	post := createPost(t, srv, author, board, "reported post")
+	var comment services.CommentDTO
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", author, map[string]any{
+		"post_id": post, "content": "reported comment",
+	}).JSON(t, &comment)
+
+	report := func(token, targetType, targetID, reason string) services.ReportDTO {
+		t.Helper()
+		var out services.ReportDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/reports", token, map[string]any{
+			"target_type": targetType, "target_id": targetID, "reason": reason,
+		}).JSON(t, &out)
+		return out
+	}
+	boardPosts := func() []services.PostDTO {
+		t.Helper()
+		var out []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, author, nil).JSON(t, &out)
+		return out
+	}
+
+	t.Run("reporting validates the target", func(t *testing.T) {
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reports", author, map[string]any{
+			"target_type": "post", "target_id": post, "reason": "spam",
+		}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/reports", r1, map[string]any{
+			"target_type": "comment", "target_id": post, "reason": "spam",
+		}).Error(t, services.CodeNotFound)
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reports", r1, map[string]any{
+			"target_type": "message", "target_id": post, "reason": "rude",
+		}).Error(t, services.CodeValidation)
+		if bad.Details["target_type"] == "" || bad.Details["reason"] == "" {
+			t.Fatalf("expected target_type and reason details: %+v", bad.Details)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/reports", "", map[string]any{})
+	})
+
+	var first services.ReportDTO
+	t.Run("distinct reports auto-hide content", func(t *testing.T) {
+		first = report(r1, "post", post, "spam")
+		if first.Status != "open" || first.ClaimedBy != nil {
+			t.Fatalf("unexpected report: %+v", first)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/reports", r1, map[string]any{
+			"target_type": "post", "target_id": post, "reason": "spam",
+		}).Error(t, services.CodeConflict)
+		report(r2, "post", post, "harassment")
+		if len(boardPosts()) != 1 {
+			t.Fatal("post hidden before reaching the threshold")
+		}
+		report(r3, "post", post, "spam")
+		if len(boardPosts()) != 0 {
+			t.Fatal("post should be hidden after three reports")
+		}
+		// A hidden post cannot be read through its comments or reacted to.
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/comments/post/"+post, "", nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/comments", r1, map[string]any{
+			"post_id": post, "content": "still here?",
+		}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/reactions", r1, map[string]any{
+			"post_id": post, "type": "like",
+		}).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("queue is for moderators", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/moderation/reports", r1, nil).Error(t, services.CodeForbidden)
+
+		var queue []services.ReportDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/moderation/reports?status=open&target_type=post&reason=spam", mod, nil).JSON(t, &queue)
+		if len(queue) != 2 || queue[0].ID != first.ID {
+			t.Fatalf("unexpected filtered queue: %+v", queue)
+		}
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/moderation/reports?status=bogus", mod, nil).Error(t, services.CodeValidation)
+	})
+
+	t.Run("claim and dismiss", func(t *testing.T) {
+		var claimed services.ReportDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+first.ID+"/claim", mod, nil).JSON(t, &claimed)
+		if claimed.Status != "claimed" || claimed.ClaimedBy == nil {
+			t.Fatalf("unexpected claimed report: %+v", claimed)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/moderation/reports/"+first.ID+"/claim", mod2, nil).Error(t, services.CodeConflict)
+
+		var mine []services.ReportDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/moderation/reports?assignee=me", mod, nil).JSON(t, &mine)
+		if len(mine) != 1 || mine[0].ID != first.ID {
+			t.Fatalf("unexpected assigned queue: %+v", mine)
+		}
+
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/moderation/reports/"+first.ID+"/resolve", mod2, map[string]any{"action": "dismiss"}).Error(t, services.CodeConflict)
+		var resolved services.ReportDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+first.ID+"/resolve", mod, map[string]any{
+			"action": "dismiss", "note": "not spam",
+		}).JSON(t, &resolved)
+		if resolved.Status != "resolved" || resolved.Resolution == nil || *resolved.Resolution != "dismissed" {
+			t.Fatalf("unexpected resolution: %+v", resolved)
+		}
+		if len(boardPosts()) != 1 {
+			t.Fatal("dismissing should restore the auto-hidden post")
+		}
+		var open []services.ReportDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/moderation/reports?status=open&target_type=post", mod, nil).JSON(t, &open)
+		if len(open) != 0 {
+			t.Fatalf("resolving should close every report on the target: %+v", open)
+		}
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/moderation/reports/"+first.ID+"/resolve", mod, map[string]any{"action": "hide"}).Error(t, services.CodeConflict)
+	})
+
+	t.Run("hide and warn", func(t *testing.T) {
+		rep := report(r2, "comment", comment.ID, "harassment")
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+rep.ID+"/resolve", mod, map[string]any{"action": "hide"})
+		var comments []services.CommentDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/comments/post/"+post, author, nil).JSON(t, &comments)
+		if len(comments) != 0 {
+			t.Fatalf("hidden comment still listed: %+v", comments)
+		}
+		// Dismissing a later report does not undo a moderator's hide.
+		rep = report(r1, "comment", comment.ID, "spam")
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+rep.ID+"/resolve", mod, map[string]any{"action": "dismiss"})
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/comments/post/"+post, author, nil).JSON(t, &comments)
+		if len(comments) != 0 {
+			t.Fatalf("dismissing restored a comment a moderator hid: %+v", comments)
+		}
+
+		rep = report(r3, "post", post, "inappropriate")
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+rep.ID+"/resolve", mod2, map[string]any{
+			"action": "warn", "note": "Please keep it civil",
+		})
+		var warnings []services.WarningDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me/warnings", author, nil).JSON(t, &warnings)
+		if len(warnings) != 1 || warnings[0].Reason != "Please keep it civil" {
+			t.Fatalf("unexpected warnings: %+v", warnings)
+		}
+	})
+
+	t.Run("hidden profiles stay out of the directory until unhidden", func(t *testing.T) {
+		hiddenID, hidden := srv.Register(t, "mod.hidden@example.com", "907", "correct-horse-1")
+		picture := "https://example.com/hidden.png"
+		listed := func() *services.DirectoryUserDTO {
+			t.Helper()
+			var out []services.DirectoryUserDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory", r1, nil).JSON(t, &out)
+			for i := range out {
+				if out[i].ID == hiddenID {
+					return &out[i]
+				}
+			}
+			return nil
+		}
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", hidden, map[string]any{
+			"directory_opt_in": true, "profile_picture_url": picture,
+		})
+		rep := report(r1, "profile", hiddenID, "inappropriate")
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+rep.ID+"/resolve", mod, map[string]any{"action": "hide"})
+		if listed() != nil {
+			t.Fatal("hidden profile still listed")
+		}
+		// Hiding is a flag: the resident's own profile is untouched.
+		var own services.ProfileDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me", hidden, nil).JSON(t, &own)
+		if !own.DirectoryOptIn || own.ProfilePictureURL == nil || *own.ProfilePictureURL != picture {
+			t.Fatalf("hiding changed the profile: %+v", own)
+		}
+		// Dismissing a later report does not undo the moderator's hide.
+		rep = report(r2, "profile", hiddenID, "spam")
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+rep.ID+"/resolve", mod, map[string]any{"action": "dismiss"})
+		if listed() != nil {
+			t.Fatal("dismissing restored a profile a moderator hid")
+		}
+
+		path := "/api/admin/users/" + hiddenID + "/unhide"
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, path, mod, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, path, srv.AdminToken(t), nil)
+		if e := listed(); e == nil || e.ProfilePictureURL == nil || *e.ProfilePictureURL != picture {
+			t.Fatalf("unhidden profile should be listed with its picture: %+v", e)
+		}
+	})
+
+	t.Run("suspend", func(t *testing.T) {
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/moderation/reports/"+first.ID+"/resolve", mod, map[string]any{"action": "ban"}).Error(t, services.CodeValidation)
+		rep := report(r1, "profile", authorID, "harassment")
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/moderation/reports/"+rep.ID+"/resolve", mod, map[string]any{"action": "suspend"})
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "mod.author@example.com", "password": "correct-horse-1",
+		}).Error(t, services.CodeForbidden)
+	})
+}
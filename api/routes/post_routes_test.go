@@ -0,0 +1,69 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testPostRoutes(t *testing.T, srv *testutil.Server) {
+	_, token := srv.Register(t, "poster@example.com", "301", "correct-horse-1")
+	_, adminToken := srv.Register(t, "admin@example.com", "000", "correct-horse-1")
+	srv.MakeAdmin(t, "admin@example.com")
+	board := createBoard(t, srv, "General")
+
+	postID := createPost(t, srv, token, board, "Anyone have a ladder?")
+
+	t.Run("create validates input", func(t *testing.T) {
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/posts", "", map[string]any{
+			"board_id": board, "title": "t", "content": "c",
+		})
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/posts", token, map[string]any{
+			"board_id": "not-a-uuid", "title": "t", "content": "c",
+		}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/posts", token, map[string]any{
+			"board_id": board, "title": " ", "content": "c",
+		}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/posts", token, map[string]any{
+			"board_id": uuid.NewString(), "title": "t", "content": "c",
+		}).Error(t, services.CodeNotFound)
+		tooLong := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/posts", token, map[string]any{
+			"board_id": board, "title": strings.Repeat("x", 201), "content": strings.Repeat("y", 10001),
+		}).Error(t, services.CodeValidation)
+		if tooLong.Details["title"] == "" || tooLong.Details["content"] == "" {
+			t.Fatalf("expected title and content details, got %+v", tooLong.Details)
+		}
+	})
+
+	t.Run("bulletins are admin only", func(t *testing.T) {
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/posts", token, map[string]any{
+			"board_id": board, "title": "Pool closed", "content": "c", "bulletin": true,
+		}).Error(t, services.CodeForbidden)
+		var created services.PostDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", adminToken, map[string]any{
+			"board_id": board, "title": "Pool closed", "content": "Friday", "bulletin": true,
+		}).JSON(t, &created)
+		if !created.IsBulletin {
+			t.Fatalf("expected bulletin post, got %+v", created)
+		}
+
+		var bulletins []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/bulletins", token, nil).JSON(t, &bulletins)
+		if len(bulletins) != 1 || bulletins[0].ID != created.ID {
+			t.Fatalf("unexpected bulletins: %+v", bulletins)
+		}
+	})
+
+	t.Run("list by board", func(t *testing.T) {
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, token, nil).JSON(t, &posts)
+		if len(posts) != 2 || posts[1].ID != postID {
+			t.Fatalf("unexpected posts: %+v", posts)
+		}
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/posts/board/nope", token, nil).Error(t, services.CodeValidation)
+	})
+}
@@ -1,59 +1,123 @@
 package routes
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/cameronsralla/culdechat/middleware"
 	"github.com/cameronsralla/culdechat/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 // RegisterProfileRoutes registers profile and directory endpoints under /profile and /directory.
-func RegisterProfileRoutes(r gin.IRouter) {
-	service := &services.ProfileService{}
-
+// Account deletion is handled by the account service.
+func RegisterProfileRoutes(r gin.IRouter, service *services.ProfileService, accounts *services.AccountService, authRequired gin.HandlerFunc) {
 	profile := r.Group("/profile")
 
-	profile.GET("/me", middleware.AuthRequired(), func(c *gin.Context) {
-		userIDStr := c.GetString("user_id")
-		userUUID, err := uuid.Parse(userIDStr)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
+	profile.GET("/me", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
 			return
 		}
 		out, err := service.Get(c.Request.Context(), userUUID)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(err)
 			return
 		}
 		c.JSON(http.StatusOK, out)
 	})
 
-	profile.PATCH("/me", middleware.AuthRequired(), func(c *gin.Context) {
-		userIDStr := c.GetString("user_id")
-		userUUID, err := uuid.Parse(userIDStr)
+	profile.GET("/me/warnings", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.Warnings(c.Request.Context(), userUUID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	profile.GET("/me/blocks", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.Blocks(c.Request.Context(), userUUID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	// relation adds or removes a block or mute of the :user_id resident.
+	relation := func(apply func(ctx context.Context, userID, targetID uuid.UUID) error) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			userUUID, ok := currentUserID(c)
+			if !ok {
+				return
+			}
+			targetID, ok := uuidParam(c, "user_id")
+			if !ok {
+				return
+			}
+			if err := apply(c.Request.Context(), userUUID, targetID); err != nil {
+				_ = c.Error(err)
+				return
+			}
+			c.Status(http.StatusNoContent)
+		}
+	}
+	profile.PUT("/me/blocks/:user_id", authRequired, relation(service.Block))
+	profile.DELETE("/me/blocks/:user_id", authRequired, relation(service.Unblock))
+	profile.PUT("/me/mutes/:user_id", authRequired, relation(service.Mute))
+	profile.DELETE("/me/mutes/:user_id", authRequired, relation(service.Unmute))
+
+	profile.PATCH("/me", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
 			return
 		}
 		var in services.UpdateProfileInput
-		if err := c.ShouldBindJSON(&in); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !bindJSON(c, &in) {
 			return
 		}
 		out, err := service.Update(c.Request.Context(), userUUID, in)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(err)
 			return
 		}
 		c.JSON(http.StatusOK, out)
 	})
 
+	profile.DELETE("/me", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.DeleteAccountInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := accounts.RequestDeletion(c.Request.Context(), userUUID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusAccepted, out)
+	})
+
 	r.GET("/directory", func(c *gin.Context) {
-		out, err := service.ListDirectory(c.Request.Context())
+		var in services.DirectoryInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		out, err := service.ListDirectory(c.Request.Context(), optionalUserID(c), in)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			_ = c.Error(err)
 			return
 		}
 		c.JSON(http.StatusOK, out)
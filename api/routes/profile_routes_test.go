@@ -0,0 +1,457 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testProfileRoutes(t *testing.T, srv *testutil.Server) {
+	id, token := srv.Register(t, "profile@example.com", "601", "correct-horse-1")
+
+	var profile services.ProfileDTO
+	srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me", token, nil).JSON(t, &profile)
+	if profile.ID != id || profile.UnitNumber != "601" || profile.DirectoryOptIn || profile.ProfilePictureURL != nil {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+
+	pic := "https://example.com/me.png"
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{
+		"profile_picture_url": pic, "directory_opt_in": true,
+	}).JSON(t, &profile)
+	if !profile.DirectoryOptIn || profile.ProfilePictureURL == nil || *profile.ProfilePictureURL != pic {
+		t.Fatalf("profile not updated: %+v", profile)
+	}
+
+	invalid := srv.Expect(t, http.StatusBadRequest, http.MethodPatch, "/api/profile/me", token, map[string]any{
+		"profile_picture_url": "javascript:alert(1)",
+	}).Error(t, services.CodeValidation)
+	if invalid.Details["profile_picture_url"] == "" {
+		t.Fatalf("expected profile_picture_url detail, got %+v", invalid.Details)
+	}
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{"profile_picture_url": ""}).JSON(t, &profile)
+	if profile.ProfilePictureURL != nil {
+		t.Fatalf("empty profile_picture_url should clear the picture: %+v", profile)
+	}
+
+	t.Run("profile details", func(t *testing.T) {
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{
+			"display_name": " Sam Rivera ", "pronouns": "they/them", "bio": "Plant person.",
+			"move_in_date": "2024-06-01", "interests": []string{"Gardening", "books", "gardening"},
+		}).JSON(t, &profile)
+		if profile.DisplayName == nil || *profile.DisplayName != "Sam Rivera" || profile.Pronouns == nil || profile.Bio == nil {
+			t.Fatalf("profile details not saved: %+v", profile)
+		}
+		if profile.MoveInDate == nil || *profile.MoveInDate != "2024-06-01" {
+			t.Fatalf("unexpected move_in_date: %v", profile.MoveInDate)
+		}
+		if len(profile.Interests) != 2 || profile.Interests[0] != "gardening" || profile.Interests[1] != "books" {
+			t.Fatalf("unexpected interests: %v", profile.Interests)
+		}
+
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPatch, "/api/profile/me", token, map[string]any{
+			"move_in_date": "June 2024", "bio": strings.Repeat("x", 501),
+		}).Error(t, services.CodeValidation)
+		if bad.Details["move_in_date"] == "" || bad.Details["bio"] == "" {
+			t.Fatalf("expected move_in_date and bio details, got %+v", bad.Details)
+		}
+
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{
+			"pronouns": "", "move_in_date": "", "interests": []string{},
+		}).JSON(t, &profile)
+		if profile.Pronouns != nil || profile.MoveInDate != nil || len(profile.Interests) != 0 || profile.DisplayName == nil {
+			t.Fatalf("expected pronouns, move_in_date and interests cleared: %+v", profile)
+		}
+	})
+
+	t.Run("authors are summarized on posts and comments", func(t *testing.T) {
+		board := createBoard(t, srv, "Profiles")
+		post := createPost(t, srv, token, board, "Hello neighbors")
+		_, neighbor := srv.Register(t, "neighbor@example.com", "602", "correct-horse-1")
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", neighbor, map[string]any{"post_id": post, "content": "Welcome!"})
+
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, neighbor, nil).JSON(t, &posts)
+		if len(posts) != 1 || posts[0].Author == nil || posts[0].Author.ID != id {
+			t.Fatalf("unexpected posts: %+v", posts)
+		}
+		if a := posts[0].Author; a.DisplayName == nil || *a.DisplayName != "Sam Rivera" || a.UnitNumber != nil {
+			t.Fatalf("unexpected author summary: %+v", a)
+		}
+
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{"privacy": map[string]any{"unit": "everyone"}})
+		var comments []services.CommentDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/comments/post/"+post, token, nil).JSON(t, &comments)
+		if len(comments) != 1 || comments[0].Author == nil || comments[0].Author.DisplayName != nil {
+			t.Fatalf("unexpected comments: %+v", comments)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, neighbor, nil).JSON(t, &posts)
+		if a := posts[0].Author; a.UnitNumber == nil || *a.UnitNumber != "601" {
+			t.Fatalf("shared units should be shown: %+v", a)
+		}
+	})
+
+	srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/profile/me", "", nil)
+	srv.Expect(t, http.StatusUnauthorized, http.MethodPatch, "/api/profile/me", "", map[string]any{})
+}
+
+func testDirectoryRoutes(t *testing.T, srv *testutil.Server) {
+	hiddenID, hidden := srv.Register(t, "hidden@example.com", "702", "correct-horse-1")
+	listedID, listed := srv.Register(t, "listed@example.com", "701", "correct-horse-1")
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", listed, map[string]any{"directory_opt_in": true})
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", hidden, map[string]any{"directory_opt_in": false})
+
+	directory := func(token string) map[string]services.DirectoryUserDTO {
+		t.Helper()
+		var entries []services.DirectoryUserDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory", token, nil).JSON(t, &entries)
+		out := map[string]services.DirectoryUserDTO{}
+		for _, e := range entries {
+			out[e.ID] = e
+		}
+		return out
+	}
+
+	entries := directory(hidden)
+	if _, ok := entries[listedID]; !ok || len(entries) == 0 {
+		t.Fatalf("directory should list opted-in users: %+v", entries)
+	}
+	if _, ok := entries[hiddenID]; ok {
+		t.Fatalf("directory should list opted-in users only: %+v", entries)
+	}
+	if e := entries[listedID]; e.UnitNumber != nil || e.Floor == nil || *e.Floor != "7" {
+		t.Fatalf("units should be private by default, floors shown: %+v", e)
+	}
+	if self := directory(listed)[listedID]; self.UnitNumber == nil || *self.UnitNumber != "701" {
+		t.Fatalf("residents should see their own unit: %+v", self)
+	}
+	srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/directory", "", nil)
+
+	t.Run("privacy settings", func(t *testing.T) {
+		var profile services.ProfileDTO
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", listed, map[string]any{
+			"display_name": "Pat", "bio": "Hi!", "contact_method": "Text me",
+			"privacy": map[string]any{"unit": "floor", "bio": "nobody", "contact": "floor", "avatar": "nobody"},
+		}).JSON(t, &profile)
+		if profile.Privacy.Unit != "floor" || profile.Privacy.Name != "everyone" || profile.Privacy.Avatar != "nobody" {
+			t.Fatalf("unexpected privacy: %+v", profile.Privacy)
+		}
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPatch, "/api/profile/me", listed, map[string]any{
+			"privacy": map[string]any{"unit": "friends"},
+		}).Error(t, services.CodeValidation)
+		if bad.Details["privacy.unit"] == "" {
+			t.Fatalf("expected privacy.unit detail, got %+v", bad.Details)
+		}
+
+		neighbor := directory(hidden)[listedID]
+		if neighbor.UnitNumber == nil || *neighbor.UnitNumber != "701" || neighbor.ContactMethod == nil || neighbor.Bio != nil {
+			t.Fatalf("same-floor view: %+v", neighbor)
+		}
+		_, downstairs := srv.Register(t, "downstairs@example.com", "502", "correct-horse-1")
+		far := directory(downstairs)[listedID]
+		if far.DisplayName == nil || *far.DisplayName != "Pat" || far.UnitNumber != nil || far.ContactMethod != nil || far.Floor == nil {
+			t.Fatalf("other-floor view: %+v", far)
+		}
+
+		board := createBoard(t, srv, "Privacy")
+		post := createPost(t, srv, listed, board, "Floor seven meetup")
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, downstairs, nil).JSON(t, &posts)
+		if a := posts[0].Author; posts[0].ID != post || a.UnitNumber != nil || a.Floor == nil || *a.Floor != "7" {
+			t.Fatalf("author summary ignores privacy: %+v", a)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, hidden, nil).JSON(t, &posts)
+		if a := posts[0].Author; a.UnitNumber == nil || a.ProfilePictureURL != nil {
+			t.Fatalf("same-floor author summary: %+v", a)
+		}
+	})
+
+	t.Run("search and filters", func(t *testing.T) {
+		join := func(email, unit string, profile map[string]any) string {
+			id, token := srv.Register(t, email, unit, "correct-horse-1")
+			profile["directory_opt_in"] = true
+			srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, profile)
+			return id
+		}
+		alvarez := join("alvarez@example.com", "1101", map[string]any{
+			"display_name": "Jordan Alvarez", "building": "North", "interests": []string{"chess"},
+			"privacy": map[string]any{"unit": "everyone"},
+		})
+		lee := join("lee@example.com", "1102", map[string]any{
+			"display_name": "Jordan Lee", "building": "South", "interests": []string{"chess", "yoga"},
+			"privacy": map[string]any{"bio": "nobody"},
+		})
+		join("alvarado@example.com", "1205", map[string]any{"display_name": "Morgan Alvarado"})
+
+		search := func(query string) []string {
+			t.Helper()
+			var entries []services.DirectoryUserDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory?"+query, hidden, nil).JSON(t, &entries)
+			ids := make([]string, 0, len(entries))
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			return ids
+		}
+		expect := func(query string, want ...string) {
+			t.Helper()
+			if got := search(query); !slices.Equal(got, want) {
+				t.Fatalf("%s: got %v, want %v", query, got, want)
+			}
+		}
+
+		expect("q=jordan", lee, alvarez)
+		expect("q=Alvarez", alvarez)
+		if got := search("q=alvares"); len(got) == 0 || got[0] != alvarez {
+			t.Fatalf("fuzzy name search should find Alvarez first: %v", got)
+		}
+		expect("q=110", alvarez)
+		expect("floor=11", alvarez, lee)
+		expect("building=north", alvarez)
+		expect("interest=chess", alvarez)
+		expect("interest=yoga")
+		expect("q=jordan&limit=1&offset=1", alvarez)
+		srv.Expect(t, http.StatusBadRequest, http.MethodGet, "/api/directory?limit=101", hidden, nil).Error(t, services.CodeValidation)
+	})
+}
+
+func testBlockRoutes(t *testing.T, srv *testutil.Server) {
+	aliceID, alice := srv.Register(t, "alice@example.com", "801", "correct-horse-1")
+	bobID, bob := srv.Register(t, "bob@example.com", "802", "correct-horse-1")
+	carolID, carol := srv.Register(t, "carol@example.com", "803", "correct-horse-1")
+	board := createBoard(t, srv, "Blocks")
+	fromBob := createPost(t, srv, bob, board, "Bob's post")
+	fromCarol := createPost(t, srv, carol, board, "Carol's post")
+	fromAlice := createPost(t, srv, alice, board, "Alice's post")
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", bob, map[string]any{"post_id": fromCarol, "content": "Bob was here"})
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", carol, map[string]any{"post_id": fromCarol, "content": "Carol was here"})
+	srv.Expect(t, http.StatusOK, http.MethodPut, "/api/boards/"+board+"/subscription", alice, map[string]any{"level": "all"})
+	srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", bob, map[string]any{"directory_opt_in": true})
+
+	postIDs := func(path, token string) []string {
+		t.Helper()
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, path, token, nil).JSON(t, &posts)
+		ids := make([]string, 0, len(posts))
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	commenters := func(token string) []string {
+		t.Helper()
+		var comments []services.CommentDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/comments/post/"+fromCarol, token, nil).JSON(t, &comments)
+		ids := make([]string, 0, len(comments))
+		for _, c := range comments {
+			ids = append(ids, c.AuthorID)
+		}
+		return ids
+	}
+	commentCount := func(token string) int {
+		t.Helper()
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, token, nil).JSON(t, &posts)
+		for _, p := range posts {
+			if p.ID == fromCarol {
+				return p.CommentCount
+			}
+		}
+		t.Fatalf("carol's post is not listed: %+v", posts)
+		return 0
+	}
+	listed := func(token, id string) bool {
+		t.Helper()
+		var entries []services.DirectoryUserDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/directory", token, nil).JSON(t, &entries)
+		return slices.ContainsFunc(entries, func(e services.DirectoryUserDTO) bool { return e.ID == id })
+	}
+
+	t.Run("blocks hide both ways", func(t *testing.T) {
+		srv.Expect(t, http.StatusNoContent, http.MethodPut, "/api/profile/me/blocks/"+bobID, alice, nil)
+		if got := postIDs("/api/posts/board/"+board, alice); slices.Contains(got, fromBob) || len(got) != 2 {
+			t.Fatalf("alice should not see bob's post: %v", got)
+		}
+		if got := postIDs("/api/posts/feed", alice); slices.Contains(got, fromBob) {
+			t.Fatalf("alice's feed should not include bob: %v", got)
+		}
+		if got := postIDs("/api/posts/board/"+board, bob); slices.Contains(got, fromAlice) {
+			t.Fatalf("bob should not see alice's post: %v", got)
+		}
+		if got := commenters(alice); !slices.Equal(got, []string{carolID}) {
+			t.Fatalf("alice should only see carol's comment: %v", got)
+		}
+		if n := commentCount(alice); n != 1 {
+			t.Fatalf("alice's comment_count = %d, want 1 to match the list", n)
+		}
+		if n := commentCount(carol); n != 2 {
+			t.Fatalf("carol's comment_count = %d, want 2", n)
+		}
+		if listed(alice, bobID) {
+			t.Fatal("bob should be hidden from alice's directory")
+		}
+		if !listed(carol, bobID) {
+			t.Fatal("blocks should not affect other residents")
+		}
+	})
+
+	t.Run("mutes hide posts one way", func(t *testing.T) {
+		srv.Expect(t, http.StatusNoContent, http.MethodPut, "/api/profile/me/mutes/"+carolID, alice, nil)
+		if got := postIDs("/api/posts/board/"+board, alice); !slices.Equal(got, []string{fromAlice}) {
+			t.Fatalf("alice should only see her own post: %v", got)
+		}
+		if got := postIDs("/api/posts/board/"+board, carol); !slices.Contains(got, fromAlice) {
+			t.Fatalf("muting is one-way: %v", got)
+		}
+		if got := commenters(alice); !slices.Equal(got, []string{carolID}) {
+			t.Fatalf("mutes should not hide comments: %v", got)
+		}
+	})
+
+	t.Run("manage blocks and mutes", func(t *testing.T) {
+		var blocks []services.BlockDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me/blocks", alice, nil).JSON(t, &blocks)
+		kinds := map[string]string{}
+		for _, b := range blocks {
+			kinds[b.UserID] = b.Kind
+		}
+		if len(blocks) != 2 || kinds[bobID] != "block" || kinds[carolID] != "mute" {
+			t.Fatalf("unexpected blocks: %+v", blocks)
+		}
+
+		var admin services.ProfileDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me", srv.AdminToken(t), nil).JSON(t, &admin)
+		srv.Expect(t, http.StatusForbidden, http.MethodPut, "/api/profile/me/blocks/"+admin.ID, alice, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusNoContent, http.MethodPut, "/api/profile/me/mutes/"+admin.ID, alice, nil)
+		srv.Expect(t, http.StatusConflict, http.MethodPut, "/api/profile/me/mutes/"+bobID, alice, nil).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPut, "/api/profile/me/blocks/"+aliceID, alice, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPut, "/api/profile/me/blocks/"+uuid.NewString(), alice, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPut, "/api/profile/me/blocks/nope", alice, nil).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/profile/me/blocks", "", nil)
+
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/profile/me/blocks/"+bobID, alice, nil)
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/profile/me/mutes/"+carolID, alice, nil)
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/profile/me/mutes/"+carolID, alice, nil)
+		if got := postIDs("/api/posts/board/"+board, alice); len(got) != 3 {
+			t.Fatalf("unblocking and unmuting should restore posts: %v", got)
+		}
+	})
+}
+
+func testAccountDeletionRoutes(t *testing.T, srv *testutil.Server) {
+	ctx := context.Background()
+	login := func(email string) *testutil.Response {
+		t.Helper()
+		return srv.Do(t, http.MethodPost, "/api/auth/login", "", map[string]any{"email": email, "password": "correct-horse-1"})
+	}
+	deleteAccount := func(token string) services.AccountDeletionDTO {
+		t.Helper()
+		var out services.AccountDeletionDTO
+		srv.Expect(t, http.StatusAccepted, http.MethodDelete, "/api/profile/me", token, map[string]any{"password": "correct-horse-1"}).JSON(t, &out)
+		return out
+	}
+	board := createBoard(t, srv, "Leaving")
+
+	t.Run("requires the password", func(t *testing.T) {
+		_, token := srv.Register(t, "leaver@example.com", "901", "correct-horse-1")
+		srv.Expect(t, http.StatusBadRequest, http.MethodDelete, "/api/profile/me", token, map[string]any{}).Error(t, services.CodeValidation)
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodDelete, "/api/profile/me", token, map[string]any{"password": "wrong-horse-1"}).Error(t, services.CodeValidation)
+		if bad.Details["password"] == "" {
+			t.Fatalf("expected password detail, got %+v", bad.Details)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodDelete, "/api/profile/me", "", map[string]any{"password": "correct-horse-1"})
+
+		srv.Register(t, "leaving.admin@example.com", "902", "correct-horse-1")
+		srv.MakeAdmin(t, "leaving.admin@example.com")
+		var auth services.AuthResponse
+		login("leaving.admin@example.com").JSON(t, &auth)
+		srv.Expect(t, http.StatusForbidden, http.MethodDelete, "/api/profile/me", auth.Token, map[string]any{"password": "correct-horse-1"}).Error(t, services.CodeForbidden)
+	})
+
+	t.Run("logging in during the grace period restores the account", func(t *testing.T) {
+		_, token := srv.Register(t, "undecided@example.com", "903", "correct-horse-1")
+		out := deleteAccount(token)
+		if out.Status != "inactive" || time.Until(out.DeleteAfter) < srv.Config.Retention.DeletionGrace-time.Minute {
+			t.Fatalf("unexpected deletion: %+v", out)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/profile/me", token, nil)
+
+		var auth services.AuthResponse
+		if resp := login("undecided@example.com"); resp.Status != http.StatusOK {
+			t.Fatalf("login during grace period = %d: %s", resp.Status, resp.Body)
+		} else {
+			resp.JSON(t, &auth)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/profile/me", auth.Token, nil)
+		if n, err := srv.Services.Retention.Purge(ctx, time.Now().Add(srv.Config.Retention.DeletionGrace+time.Hour)); err != nil || n != 0 {
+			t.Fatalf("restored account should not be purged: n=%d err=%v", n, err)
+		}
+	})
+
+	t.Run("anonymizes after the grace period", func(t *testing.T) {
+		id, token := srv.Register(t, "gone@example.com", "904", "correct-horse-1")
+		srv.Expect(t, http.StatusOK, http.MethodPatch, "/api/profile/me", token, map[string]any{"display_name": "Gone Girl", "directory_opt_in": true})
+		post := createPost(t, srv, token, board, "Still here")
+		deleteAccount(token)
+
+		if n, err := srv.Services.Retention.Purge(ctx, time.Now()); err != nil || n != 0 {
+			t.Fatalf("purged during the grace period: n=%d err=%v", n, err)
+		}
+		if n, err := srv.Services.Retention.Purge(ctx, time.Now().Add(srv.Config.Retention.DeletionGrace+time.Minute)); err != nil || n != 1 {
+			t.Fatalf("expected one purge: n=%d err=%v", n, err)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/login", "", map[string]any{"email": "gone@example.com", "password": "correct-horse-1"})
+
+		u, err := srv.Repos.Users.GetByID(ctx, uuid.MustParse(id))
+		if err != nil || u == nil {
+			t.Fatalf("anonymized user should remain: %v", err)
+		}
+		if u.Status != "deleted" || u.Email == "gone@example.com" || u.DisplayName != nil || u.HashedPassword != "" {
+			t.Fatalf("user not anonymized: %+v", u)
+		}
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, srv.AdminToken(t), nil).JSON(t, &posts)
+		if len(posts) != 1 || posts[0].ID != post || posts[0].Author == nil || posts[0].Author.DisplayName != nil {
+			t.Fatalf("expected the post without attribution: %+v", posts)
+		}
+
+		var events []services.AuditEventDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/audit?action=account.purge&target_id="+id, srv.AdminToken(t), nil).JSON(t, &events)
+		if len(events) != 1 || events[0].ActorID != nil || events[0].Details["mode"] != "anonymize" {
+			t.Fatalf("unexpected purge audit: %+v", events)
+		}
+	})
+
+	t.Run("deletes content in delete mode", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.Retention.Mode = config.RetentionDelete
+		cfg.Retention.DeletionGrace = 0
+		deleting := testutil.NewServerWithConfig(t, srv.Repos, cfg)
+
+		id, token := srv.Register(t, "erased@example.com", "905", "correct-horse-1")
+		_, other := srv.Register(t, "bystander@example.com", "906", "correct-horse-1")
+		post := createPost(t, srv, token, board, "Erase me")
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", other, map[string]any{"post_id": post, "content": "Bye"})
+		deleteAccount(token)
+
+		if n, err := deleting.Services.Retention.Purge(ctx, time.Now()); err != nil || n != 1 {
+			t.Fatalf("expected one purge: n=%d err=%v", n, err)
+		}
+		if u, err := srv.Repos.Users.GetByID(ctx, uuid.MustParse(id)); err != nil || u != nil {
+			t.Fatalf("user should be gone: %+v %v", u, err)
+		}
+		var posts []services.PostDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, other, nil).JSON(t, &posts)
+		if slices.ContainsFunc(posts, func(p services.PostDTO) bool { return p.ID == post }) {
+			t.Fatalf("deleted resident's post should be gone: %+v", posts)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testRateLimits(t *testing.T, repos *models.Repos) {
+	t.Run("login is limited per client", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.RateLimit.Login.Requests = 3
+		srv := testutil.NewServerWithConfig(t, repos, cfg)
+
+		creds := map[string]string{"email": "nobody@example.com", "password": "wrong-pass-1"}
+		for i := 0; i < 3; i++ {
+			srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/login", "", creds)
+		}
+		resp := srv.Expect(t, http.StatusTooManyRequests, http.MethodPost, "/api/auth/login", "", creds)
+		resp.Error(t, services.CodeRateLimited)
+		assertRetryAfter(t, resp, 20*time.Second)
+
+		// No proxy is trusted by default, so a forged X-Forwarded-For lands in the same bucket.
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL+"/api/auth/login",
+			strings.NewReader(`{"email":"nobody@example.com","password":"wrong-pass-1"}`))
+		if err != nil {
+			t.Fatalf("build request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		spoofed, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("POST /api/auth/login: %v", err)
+		}
+		spoofed.Body.Close()
+		if spoofed.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("spoofed X-Forwarded-For status = %d, want %d", spoofed.StatusCode, http.StatusTooManyRequests)
+		}
+	})
+
+	t.Run("post creation is limited per user", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.RateLimit.Posts.Requests = 2
+		srv := testutil.NewServerWithConfig(t, repos, cfg)
+
+		_, first := srv.Register(t, "limited.one@example.com", "801", "correct-horse-1")
+		_, second := srv.Register(t, "limited.two@example.com", "802", "correct-horse-1")
+		board := createBoard(t, srv, "Rate Limited")
+		createPost(t, srv, first, board, "one")
+		createPost(t, srv, first, board, "two")
+		srv.Expect(t, http.StatusTooManyRequests, http.MethodPost, "/api/posts", first, map[string]any{
+			"board_id": board, "title": "three", "content": "three body",
+		}).Error(t, services.CodeRateLimited)
+		createPost(t, srv, second, board, "other user")
+	})
+
+	t.Run("repeated failed logins lock the account", func(t *testing.T) {
+		cfg := testutil.Config()
+		cfg.Lockout.Threshold = 3
+		cfg.Lockout.Base = time.Minute
+		srv := testutil.NewServerWithConfig(t, repos, cfg)
+
+		email := "lockout@example.com"
+		srv.Register(t, email, "803", "correct-horse-1")
+		good := map[string]string{"email": email, "password": "correct-horse-1"}
+		bad := map[string]string{"email": email, "password": "wrong-pass-1"}
+
+		// A successful login resets the failure count.
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/login", "", bad)
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/auth/login", "", good)
+		for i := 0; i < 3; i++ {
+			srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/auth/login", "", bad)
+		}
+		resp := srv.Expect(t, http.StatusTooManyRequests, http.MethodPost, "/api/auth/login", "", good)
+		resp.Error(t, services.CodeRateLimited)
+		assertRetryAfter(t, resp, time.Minute)
+	})
+}
+
+// assertRetryAfter checks that the Retry-After header is set and no longer than max.
+func assertRetryAfter(t *testing.T, resp *testutil.Response, max time.Duration) {
+	t.Helper()
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 || time.Duration(secs)*time.Second > max {
+		t.Fatalf("Retry-After = %q, want 1..%s", resp.Header.Get("Retry-After"), max)
+	}
+}
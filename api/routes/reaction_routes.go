@@ -3,47 +3,123 @@ package routes
 import (
 	"net/http"
 
-	"github.com/cameronsralla/culdechat/middleware"
 	"github.com/cameronsralla/culdechat/services"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
-// RegisterReactionRoutes registers reaction endpoints under /reactions.
-func RegisterReactionRoutes(r gin.IRouter) {
-	service := &services.ReactionService{}
-
+// RegisterReactionRoutes registers reaction endpoints and the reaction catalog under /reactions.
+func RegisterReactionRoutes(r gin.IRouter, service *services.ReactionService, authRequired gin.HandlerFunc) {
 	grp := r.Group("/reactions")
 
-	grp.POST("", middleware.AuthRequired(), func(c *gin.Context) {
+	grp.POST("", authRequired, func(c *gin.Context) {
 		var in services.ReactInput
-		if err := c.ShouldBindJSON(&in); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !bindJSON(c, &in) {
 			return
 		}
-		userIDStr := c.GetString("user_id")
-		userUUID, err := uuid.Parse(userIDStr)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
+		userUUID, ok := currentUserID(c)
+		if !ok {
 			return
 		}
 		if err := service.Upsert(c.Request.Context(), userUUID, in); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(err)
 			return
 		}
 		c.Status(http.StatusNoContent)
 	})
 
-	grp.DELETE("/:post_id", middleware.AuthRequired(), func(c *gin.Context) {
+	grp.DELETE("/:post_id", authRequired, func(c *gin.Context) {
 		postID := c.Param("post_id")
-		userIDStr := c.GetString("user_id")
-		userUUID, err := uuid.Parse(userIDStr)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
+		userUUID, ok := currentUserID(c)
+		if !ok {
 			return
 		}
 		if err := service.Remove(c.Request.Context(), userUUID, postID); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	grp.DELETE("/comment/:comment_id", authRequired, func(c *gin.Context) {
+		commentID := c.Param("comment_id")
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		if err := service.RemoveFromComment(c.Request.Context(), userUUID, commentID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	grp.GET("/post/:post_id", func(c *gin.Context) {
+		postID, ok := uuidParam(c, "post_id")
+		if !ok {
+			return
+		}
+		var in services.ListReactionsInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		out, err := service.ListByPost(c.Request.Context(), postID, optionalUserID(c), in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.GET("/comment/:comment_id", func(c *gin.Context) {
+		commentID, ok := uuidParam(c, "comment_id")
+		if !ok {
+			return
+		}
+		var in services.ListReactionsInput
+		if !bindQuery(c, &in) {
+			return
+		}
+		out, err := service.ListByComment(c.Request.Context(), commentID, optionalUserID(c), in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.GET("/catalog", func(c *gin.Context) {
+		out, err := service.Catalog(c.Request.Context())
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	grp.POST("/catalog", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var in services.ReactionTypeInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.AddType(c.Request.Context(), userUUID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, out)
+	})
+
+	grp.DELETE("/catalog/:shortcode", authRequired, func(c *gin.Context) {
+		userUUID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		if err := service.RemoveType(c.Request.Context(), userUUID, c.Param("shortcode")); err != nil {
+			_ = c.Error(err)
 			return
 		}
 		c.Status(http.StatusNoContent)
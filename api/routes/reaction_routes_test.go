@@ -0,0 +1,154 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+	"github.com/google/uuid"
+)
+
+func testReactionRoutes(t *testing.T, srv *testutil.Server) {
+	_, token := srv.Register(t, "reactor@example.com", "501", "correct-horse-1")
+	_, other := srv.Register(t, "reactor2@example.com", "502", "correct-horse-1")
+	board := createBoard(t, srv, "Reactions")
+	post := createPost(t, srv, token, board, "React to me")
+	postID := uuid.MustParse(post)
+
+	counts := func(t *testing.T) map[string]int64 {
+		t.Helper()
+		rows, err := srv.Repos.Reactions.Count(context.Background(), models.ReactionOnPost, postID)
+		if err != nil {
+			t.Fatalf("count reactions: %v", err)
+		}
+		out := map[string]int64{}
+		for _, r := range rows {
+			out[r.Type] = r.Count
+		}
+		return out
+	}
+
+	srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post, "type": "Like"})
+	srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", other, map[string]any{"post_id": post, "type": "like"})
+	if got := counts(t); got["like"] != 2 {
+		t.Fatalf("expected 2 likes, got %v", got)
+	}
+
+	t.Run("one reaction per user", func(t *testing.T) {
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post, "type": "laugh"})
+		if got := counts(t); got["like"] != 1 || got["laugh"] != 1 {
+			t.Fatalf("expected like=1 laugh=1, got %v", got)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/reactions/"+post, token, nil)
+		if got := counts(t); got["laugh"] != 0 || got["like"] != 1 {
+			t.Fatalf("expected only the other user's like, got %v", got)
+		}
+		srv.Expect(t, http.StatusBadRequest, http.MethodDelete, "/api/reactions/bad", token, nil).Error(t, services.CodeValidation)
+	})
+
+	t.Run("validates input", func(t *testing.T) {
+		srv.Expect(t, http.StatusUnauthorized, http.MethodPost, "/api/reactions", "", map[string]any{"post_id": post, "type": "like"})
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post}).Error(t, services.CodeValidation)
+		bad := srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post, "type": "poop"}).Error(t, services.CodeValidation)
+		if bad.Details["type"] == "" {
+			t.Fatalf("expected type detail, got %+v", bad.Details)
+		}
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": uuid.NewString(), "type": "like"}).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("comments take one reaction per user", func(t *testing.T) {
+		var comment services.CommentDTO
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/comments", other, map[string]any{"post_id": post, "content": "Nice"}).JSON(t, &comment)
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", token, map[string]any{"comment_id": comment.ID, "type": "wow"})
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", token, map[string]any{"comment_id": comment.ID, "type": "love"})
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", other, map[string]any{"comment_id": comment.ID, "type": "love"})
+
+		var who []services.ReactionDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/reactions/comment/"+comment.ID, token, nil).JSON(t, &who)
+		if len(who) != 2 || who[0].Type != "love" || who[1].Type != "love" {
+			t.Fatalf("unexpected comment reactions: %+v", who)
+		}
+		if got := counts(t); got["love"] != 0 {
+			t.Fatalf("comment reactions leaked into post counts: %v", got)
+		}
+
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/reactions/comment/"+comment.ID, token, nil)
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/reactions/comment/"+comment.ID, token, nil).JSON(t, &who)
+		if len(who) != 1 || who[0].UserID == "" {
+			t.Fatalf("unexpected comment reactions after remove: %+v", who)
+		}
+
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post, "comment_id": comment.ID, "type": "like"}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusNotFound, http.MethodPost, "/api/reactions", token, map[string]any{"comment_id": uuid.NewString(), "type": "like"}).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusNotFound, http.MethodGet, "/api/reactions/comment/"+uuid.NewString(), token, nil).Error(t, services.CodeNotFound)
+	})
+
+	t.Run("list who reacted with what", func(t *testing.T) {
+		var who []services.ReactionDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/reactions/post/"+post, token, nil).JSON(t, &who)
+		if len(who) != 1 || who[0].Type != "like" {
+			t.Fatalf("unexpected post reactions: %+v", who)
+		}
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/reactions/post/"+post+"?type=laugh", token, nil).JSON(t, &who)
+		if len(who) != 0 {
+			t.Fatalf("type filter ignored: %+v", who)
+		}
+		srv.Expect(t, http.StatusUnauthorized, http.MethodGet, "/api/reactions/post/"+post, "", nil)
+	})
+
+	t.Run("catalog is admin managed", func(t *testing.T) {
+		admin := srv.AdminToken(t)
+		var catalog []services.ReactionTypeDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/reactions/catalog", token, nil).JSON(t, &catalog)
+		if len(catalog) != 6 || catalog[0].Shortcode != "like" || catalog[0].Emoji == "" {
+			t.Fatalf("unexpected default catalog: %+v", catalog)
+		}
+
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/reactions/catalog", token, map[string]any{"shortcode": "party", "emoji": "🎉"}).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reactions/catalog", admin, map[string]any{"shortcode": "party time", "emoji": "🎉"}).Error(t, services.CodeValidation)
+		srv.Expect(t, http.StatusConflict, http.MethodPost, "/api/reactions/catalog", admin, map[string]any{"shortcode": "like", "emoji": "👍"}).Error(t, services.CodeConflict)
+		srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/reactions/catalog", admin, map[string]any{"shortcode": "Party", "emoji": "🎉"})
+		srv.Expect(t, http.StatusNoContent, http.MethodPost, "/api/reactions", token, map[string]any{"post_id": post, "type": "party"})
+
+		srv.Expect(t, http.StatusForbidden, http.MethodDelete, "/api/reactions/catalog/party", token, nil).Error(t, services.CodeForbidden)
+		srv.Expect(t, http.StatusNoContent, http.MethodDelete, "/api/reactions/catalog/party", admin, nil)
+		srv.Expect(t, http.StatusNotFound, http.MethodDelete, "/api/reactions/catalog/party", admin, nil).Error(t, services.CodeNotFound)
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/reactions", other, map[string]any{"post_id": post, "type": "party"}).Error(t, services.CodeValidation)
+		if got := counts(t); got["party"] != 1 {
+			t.Fatalf("retired reactions should be kept: %v", got)
+		}
+	})
+
+	t.Run("post listings carry counts and my reaction", func(t *testing.T) {
+		listed := func(token string) services.PostDTO {
+			t.Helper()
+			var posts []services.PostDTO
+			srv.Expect(t, http.StatusOK, http.MethodGet, "/api/posts/board/"+board, token, nil).JSON(t, &posts)
+			if len(posts) != 1 {
+				t.Fatalf("unexpected posts: %+v", posts)
+			}
+			return posts[0]
+		}
+		mine := listed(token)
+		if mine.ReactionCounts["like"] != 1 || mine.ReactionCounts["party"] != 1 || mine.CommentCount != 1 {
+			t.Fatalf("unexpected counts: %+v", mine)
+		}
+		if mine.MyReaction == nil || *mine.MyReaction != "party" {
+			t.Fatalf("expected my_reaction party, got %v", mine.MyReaction)
+		}
+		if theirs := listed(other); theirs.MyReaction == nil || *theirs.MyReaction != "like" {
+			t.Fatalf("expected my_reaction like, got %v", theirs.MyReaction)
+		}
+
+		_, fresh := srv.Register(t, "reactor3@example.com", "503", "correct-horse-1")
+		if got := listed(fresh); got.MyReaction != nil {
+			t.Fatalf("expected no reaction, got %q", *got.MyReaction)
+		}
+	})
+}
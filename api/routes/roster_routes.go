@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRosterRoutes registers the unit registry and roster under
+// /admin/units and /admin/roster, and the review queue for registrations
+// that did not match the roster under /admin/verifications.
+func RegisterRosterRoutes(r gin.IRouter, service *services.RosterService, authRequired gin.HandlerFunc) {
+	admin := r.Group("/admin", authRequired)
+
+	admin.GET("/units", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.ListUnits(c.Request.Context(), actorID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.POST("/roster", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		body, ok := readBody(c)
+		if !ok {
+			return
+		}
+		out, err := service.Import(c.Request.Context(), actorID, bytes.NewReader(body))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.GET("/roster", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		var buf bytes.Buffer
+		if err := service.Export(c.Request.Context(), actorID, &buf); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="roster.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	})
+
+	admin.GET("/verifications", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		out, err := service.ListForReview(c.Request.Context(), actorID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	admin.POST("/users/:user_id/verify", func(c *gin.Context) {
+		actorID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		userID, ok := uuidParam(c, "user_id")
+		if !ok {
+			return
+		}
+		var in services.VerifyResidentInput
+		if !bindJSON(c, &in) {
+			return
+		}
+		out, err := service.Verify(c.Request.Context(), actorID, userID, in)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
@@ -0,0 +1,226 @@
+package routes_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+func testRosterRoutes(t *testing.T, srv *testutil.Server) {
+	admin := srv.AdminToken(t)
+	_, early := srv.Register(t, "early.bird@example.com", "1501", "correct-horse-1")
+	_, resident := srv.Register(t, "roster.snoop@example.com", "1502", "correct-horse-1")
+
+	verification := func(t *testing.T, token string) string {
+		t.Helper()
+		var me services.MeDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/auth/me", token, nil).JSON(t, &me)
+		return me.Verification
+	}
+	units := func(t *testing.T) map[string]services.UnitDTO {
+		t.Helper()
+		var list []services.UnitDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/units", admin, nil).JSON(t, &list)
+		out := map[string]services.UnitDTO{}
+		for _, u := range list {
+			out[u.Building+"/"+u.UnitNumber] = u
+		}
+		return out
+	}
+
+	t.Run("without a registry residents stay unverified", func(t *testing.T) {
+		if got := verification(t, early); got != "unverified" {
+			t.Fatalf("verification = %q, want unverified", got)
+		}
+	})
+
+	t.Run("admins import a roster", func(t *testing.T) {
+		roster := "Building,Floor,Unit_Number,Occupancy,Email\n" +
+			"North,2,201,occupied,Roster.One@example.com\n" +
+			"North,2,201,occupied,roster.two@example.com\n" +
+			"North,3,301,,\n" +
+			"South,3,301,occupied,\n" +
+			"North,4,401,vacant,\n" +
+			",5,501,occupied,\n"
+		var out services.RosterImportDTO
+		postCSV(t, srv, admin, "/api/admin/roster", roster).expect(t, http.StatusOK).JSON(t, &out)
+		if out.Rows != 6 || out.UnitsCreated != 5 || out.UnitsUpdated != 0 || out.EmailsAdded != 2 {
+			t.Fatalf("unexpected import: %+v", out)
+		}
+		registry := units(t)
+		if len(registry) != 5 || len(registry["North/201"].RosterEmails) != 2 || registry["North/401"].Occupancy != "vacant" {
+			t.Fatalf("unexpected units: %+v", registry)
+		}
+
+		// Importing again updates units and moves emails without duplicating them.
+		postCSV(t, srv, admin, "/api/admin/roster", "building,unit_number,occupancy\nNorth,401,occupied\n").expect(t, http.StatusOK).JSON(t, &out)
+		if out.UnitsCreated != 0 || out.UnitsUpdated != 1 {
+			t.Fatalf("unexpected re-import: %+v", out)
+		}
+		postCSV(t, srv, admin, "/api/admin/roster", "building,unit_number,occupancy\nNorth,401,vacant\n").expect(t, http.StatusOK)
+	})
+
+	t.Run("bad rosters are rejected whole", func(t *testing.T) {
+		bad := postCSV(t, srv, admin, "/api/admin/roster", "unit_number,email\n,a@example.com\n601,not-an-email\n602,b@example.com\n").
+			expect(t, http.StatusBadRequest).Error(t, services.CodeValidation)
+		if bad.Details["line 2"] == "" || bad.Details["line 3"] == "" || bad.Details["line 4"] != "" {
+			t.Fatalf("unexpected details: %+v", bad.Details)
+		}
+		if _, ok := units(t)["/602"]; ok {
+			t.Fatalf("a rejected roster was partly imported")
+		}
+		postCSV(t, srv, admin, "/api/admin/roster", "unit,email\n1,a@example.com\n").expect(t, http.StatusBadRequest).Error(t, services.CodeValidation)
+		postCSV(t, srv, admin, "/api/admin/roster", "").expect(t, http.StatusBadRequest).Error(t, services.CodeValidation)
+		postCSV(t, srv, admin, "/api/admin/roster", "unit_number,occupancy\n603,sold\n").expect(t, http.StatusBadRequest).Error(t, services.CodeValidation)
+		postCSV(t, srv, resident, "/api/admin/roster", "unit_number\n604\n").expect(t, http.StatusForbidden).Error(t, services.CodeForbidden)
+	})
+
+	var flagged string
+	t.Run("registration verifies residents against the roster", func(t *testing.T) {
+		cases := []struct {
+			email, unit, want string
+		}{
+			{"roster.one@example.com", "201", "verified"},
+			{"roster.two@example.com", "999", "review"},
+			{"stranger@example.com", "201", "review"},
+			{"walk.in@example.com", "501", "review"},
+			{"squatter@example.com", "401", "review"},
+			{"either@example.com", "301", "review"},
+			{"lost.unit@example.com", "777", "review"},
+		}
+		for _, tc := range cases {
+			id, token := srv.Register(t, tc.email, tc.unit, "correct-horse-1")
+			if got := verification(t, token); got != tc.want {
+				t.Errorf("%s in %s: verification = %q, want %q", tc.email, tc.unit, got, tc.want)
+			}
+			if tc.email == "roster.two@example.com" {
+				flagged = id
+			}
+		}
+
+		var queue []services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/verifications", admin, nil).JSON(t, &queue)
+		notes := map[string]string{}
+		for _, a := range queue {
+			if a.VerificationNote == nil {
+				t.Fatalf("flagged account without a note: %+v", a)
+			}
+			notes[a.Email] = *a.VerificationNote
+		}
+		if len(notes) != 6 || !strings.Contains(notes["roster.two@example.com"], "201") || !strings.Contains(notes["squatter@example.com"], "vacant") ||
+			!strings.Contains(notes["walk.in@example.com"], "no emails") {
+			t.Fatalf("unexpected review queue: %+v", notes)
+		}
+		srv.Expect(t, http.StatusForbidden, http.MethodGet, "/api/admin/verifications", resident, nil).Error(t, services.CodeForbidden)
+	})
+
+	t.Run("admins verify flagged residents", func(t *testing.T) {
+		srv.Expect(t, http.StatusBadRequest, http.MethodPost, "/api/admin/users/"+flagged+"/verify", admin, map[string]any{
+			"unit_id": "00000000-0000-0000-0000-00000000abcd",
+		}).Error(t, services.CodeValidation)
+		var out services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodPost, "/api/admin/users/"+flagged+"/verify", admin, map[string]any{
+			"unit_id": units(t)["North/201"].ID,
+		}).JSON(t, &out)
+		if out.Verification != "verified" || out.VerificationNote != nil {
+			t.Fatalf("unexpected account: %+v", out)
+		}
+		var queue []services.AccountDTO
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/verifications", admin, nil).JSON(t, &queue)
+		for _, a := range queue {
+			if a.ID == flagged {
+				t.Fatalf("verified resident still in the queue")
+			}
+		}
+		srv.Expect(t, http.StatusForbidden, http.MethodPost, "/api/admin/users/"+flagged+"/verify", resident, map[string]any{}).
+			Error(t, services.CodeForbidden)
+	})
+
+	t.Run("auto-verify units pre-approve residents without roster emails", func(t *testing.T) {
+		postCSV(t, srv, admin, "/api/admin/roster", "unit_number,auto_verify\n702,maybe\n").expect(t, http.StatusBadRequest).Error(t, services.CodeValidation)
+		postCSV(t, srv, admin, "/api/admin/roster", "unit_number,occupancy,auto_verify\n703,vacant,true\n").expect(t, http.StatusBadRequest).Error(t, services.CodeValidation)
+
+		postCSV(t, srv, admin, "/api/admin/roster", "building,unit_number,auto_verify\nEast,701,true\n").expect(t, http.StatusOK)
+		if !units(t)["East/701"].AutoVerify {
+			t.Fatalf("East/701 should auto-verify: %+v", units(t)["East/701"])
+		}
+		_, token := srv.Register(t, "east.neighbour@example.com", "701", "correct-horse-1")
+		if got := verification(t, token); got != "verified" {
+			t.Fatalf("verification = %q, want verified", got)
+		}
+		// Units without the opt-in still go to review.
+		_, token = srv.Register(t, "walk.in.again@example.com", "501", "correct-horse-1")
+		if got := verification(t, token); got != "review" {
+			t.Fatalf("verification = %q, want review", got)
+		}
+		// Files without the column leave the setting alone.
+		postCSV(t, srv, admin, "/api/admin/roster", "building,unit_number\nEast,701\n").expect(t, http.StatusOK)
+		if !units(t)["East/701"].AutoVerify {
+			t.Fatal("importing without auto_verify cleared it")
+		}
+	})
+
+	t.Run("the roster exports as CSV", func(t *testing.T) {
+		resp := srv.Expect(t, http.StatusOK, http.MethodGet, "/api/admin/roster", admin, nil)
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Fatalf("content type = %q", ct)
+		}
+		body := string(resp.Body)
+		for _, line := range []string{
+			"building,floor,unit_number,occupancy,email,auto_verify\n",
+			"North,2,201,occupied,roster.one@example.com,false\n",
+			"North,4,401,vacant,,false\n",
+			",5,501,occupied,,false\n",
+			"East,,701,occupied,,true\n",
+		} {
+			if !strings.Contains(body, line) {
+				t.Fatalf("export is missing %q:\n%s", line, body)
+			}
+		}
+		// An export imports back without changes.
+		var out services.RosterImportDTO
+		postCSV(t, srv, admin, "/api/admin/roster", body).expect(t, http.StatusOK).JSON(t, &out)
+		if out.UnitsCreated != 0 || out.EmailsAdded != 0 {
+			t.Fatalf("re-importing the export changed the roster: %+v", out)
+		}
+	})
+}
+
+// csvResponse wraps a raw response so tests can reuse the testutil assertions.
+type csvResponse struct {
+	*testutil.Response
+}
+
+func (r csvResponse) expect(t *testing.T, status int) *testutil.Response {
+	t.Helper()
+	if r.Status != status {
+		t.Fatalf("status %d, want %d: %s", r.Status, status, r.Body)
+	}
+	return r.Response
+}
+
+// postCSV sends body as a text/csv request.
+func postCSV(t *testing.T, srv *testutil.Server, token, path, body string) csvResponse {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	return csvResponse{&testutil.Response{Status: resp.StatusCode, Header: resp.Header, Body: data}}
+}
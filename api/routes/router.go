@@ -3,17 +3,61 @@ package routes
 import (
 	"net/http"
 
+	"github.com/cameronsralla/culdechat/config"
 	"github.com/cameronsralla/culdechat/middleware"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// Deps holds everything the router needs to serve requests.
+type Deps struct {
+	Config   *config.Config
+	Tokens   *utils.TokenIssuer
+	Services *services.Services
+	// RateLimiter throttles abuse-prone routes; nil disables rate limiting.
+	RateLimiter *middleware.RateLimiter
+}
+
+// Limits holds the rate limiting middleware for routes that need it.
+type Limits struct {
+	Login         gin.HandlerFunc
+	Register      gin.HandlerFunc
+	CreatePost    gin.HandlerFunc
+	CreateComment gin.HandlerFunc
+}
+
 // NewRouter constructs the gin.Engine with all routes and middleware registered.
-func NewRouter() *gin.Engine {
+func NewRouter(deps Deps) *gin.Engine {
 	router := gin.New()
-	router.Use(gin.Recovery())
+	// Only the configured proxies may set the client address through
+	// X-Forwarded-For; otherwise anyone could dodge per-IP rate limits.
+	if err := router.SetTrustedProxies(deps.Config.HTTP.TrustedProxies); err != nil {
+		utils.Errorf("invalid http.trusted_proxies, trusting none: %v", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+	router.Use(middleware.RequestID())
 	router.Use(middleware.RequestLogger())
+	router.Use(middleware.ErrorHandler())
+	router.Use(middleware.Recovery())
 
-	api := router.Group("/api")
+	// Every /api route needs an active session except the configured allow-list;
+	// authRequired additionally guards routes that act for the caller.
+	session := middleware.RequireSession(deps.Tokens, deps.Services.Accounts, deps.Services.Communities, deps.Config.Access.PublicRoutes)
+	authRequired := middleware.RequireUser()
+	policies := deps.Config.RateLimit
+	limits := Limits{
+		Login:         deps.RateLimiter.Limit("login", policies.Login, middleware.ClientIPKey),
+		Register:      deps.RateLimiter.Limit("register", policies.Register, middleware.ClientIPKey),
+		CreatePost:    deps.RateLimiter.Limit("posts", policies.Posts, middleware.UserKey),
+		CreateComment: deps.RateLimiter.Limit("comments", policies.Comments, middleware.UserKey),
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		_ = c.Error(services.NotFoundError("route not found"))
+	})
+
+	api := router.Group("/api", session)
 
 	// Health endpoint under /api
 	api.GET("/health", func(c *gin.Context) {
@@ -21,12 +65,35 @@ func NewRouter() *gin.Engine {
 	})
 
 	// Register sub-route groups under /api
-	RegisterAuthRoutes(api)
-	RegisterBoardRoutes(api)
-	RegisterPostRoutes(api)
-	RegisterCommentRoutes(api)
-	RegisterReactionRoutes(api)
-	RegisterProfileRoutes(api)
+	svcs := deps.Services
+	RegisterAuthRoutes(api, svcs.Auth, authRequired, limits)
+	RegisterBoardRoutes(api, svcs.Boards, authRequired)
+	RegisterPostRoutes(api, svcs.Posts, authRequired, limits)
+	RegisterCommentRoutes(api, svcs.Comments, authRequired, limits)
+	RegisterReactionRoutes(api, svcs.Reactions, authRequired)
+	RegisterProfileRoutes(api, svcs.Profiles, svcs.Accounts, authRequired)
+	RegisterExportRoutes(api, svcs.Exports, authRequired)
+	RegisterModerationRoutes(api, svcs.Moderation, authRequired)
+	RegisterAdminRoutes(api, svcs.Accounts, svcs.Audit, authRequired)
+	RegisterCommunityRoutes(api, svcs.Communities, authRequired)
+	RegisterInviteRoutes(api, svcs.Invites, authRequired)
+	RegisterRosterRoutes(api, svcs.Roster, authRequired)
+	RegisterHouseholdRoutes(api, svcs.Households, authRequired)
 
+	warnUnknownRoutes(router, deps.Config.Access.PublicRoutes)
 	return router
 }
+
+// warnUnknownRoutes logs allow-list entries that match no registered route,
+// which usually means a typo in the configuration.
+func warnUnknownRoutes(router *gin.Engine, public []string) {
+	known := map[string]bool{}
+	for _, r := range router.Routes() {
+		known[r.Method+" "+r.Path] = true
+	}
+	for _, route := range public {
+		if !known[route] {
+			utils.Warnf("access.public_routes entry %q matches no route", route)
+		}
+	}
+}
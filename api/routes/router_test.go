@@ -0,0 +1,89 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/models/memory"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/testutil"
+)
+
+// TestRouterPostgres exercises every route against a disposable Postgres database.
+func TestRouterPostgres(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping Postgres integration tests in short mode")
+	}
+	pool := testutil.NewDatabase(t)
+	testRouter(t, models.NewPgRepos(pool))
+}
+
+// TestRouterMemory runs the same suite against the in-memory repositories.
+func TestRouterMemory(t *testing.T) {
+	testRouter(t, memory.NewRepos())
+}
+
+func testRouter(t *testing.T, repos *models.Repos) {
+	srv := testutil.NewServer(t, repos)
+
+	t.Run("health", func(t *testing.T) {
+		var out map[string]string
+		srv.Expect(t, http.StatusOK, http.MethodGet, "/api/health", "", nil).JSON(t, &out)
+		if out["status"] != "ok" {
+			t.Fatalf("health status = %q", out["status"])
+		}
+	})
+	t.Run("errors use the envelope", func(t *testing.T) {
+		resp := srv.Do(t, http.MethodGet, "/api/does-not-exist", "", nil)
+		if resp.Status != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", resp.Status)
+		}
+		resp.Error(t, services.CodeNotFound)
+	})
+	// Runs first, while the default community has no admin.
+	t.Run("bootstrap admin", func(t *testing.T) { testBootstrapRoutes(t, srv) })
+	t.Run("auth", func(t *testing.T) { testAuthRoutes(t, srv) })
+	t.Run("boards", func(t *testing.T) { testBoardRoutes(t, srv) })
+	t.Run("posts", func(t *testing.T) { testPostRoutes(t, srv) })
+	t.Run("subscriptions", func(t *testing.T) { testSubscriptionRoutes(t, srv) })
+	t.Run("board access", func(t *testing.T) { testBoardAccessRoutes(t, srv) })
+	t.Run("public read", func(t *testing.T) { testPublicReadRoutes(t, srv) })
+	t.Run("comments", func(t *testing.T) { testCommentRoutes(t, srv) })
+	t.Run("reactions", func(t *testing.T) { testReactionRoutes(t, srv) })
+	t.Run("profile", func(t *testing.T) { testProfileRoutes(t, srv) })
+	t.Run("directory", func(t *testing.T) { testDirectoryRoutes(t, srv) })
+	t.Run("blocks", func(t *testing.T) { testBlockRoutes(t, srv) })
+	t.Run("account deletion", func(t *testing.T) { testAccountDeletionRoutes(t, srv) })
+	t.Run("data exports", func(t *testing.T) { testExportRoutes(t, srv) })
+	t.Run("invites", func(t *testing.T) { testInviteRoutes(t, srv) })
+	t.Run("roster", func(t *testing.T) { testRosterRoutes(t, srv) })
+	t.Run("households", func(t *testing.T) { testHouseholdRoutes(t, srv) })
+	t.Run("communities", func(t *testing.T) { testCommunityRoutes(t, srv) })
+	t.Run("moderation", func(t *testing.T) { testModerationRoutes(t, srv) })
+	t.Run("admin", func(t *testing.T) { testAdminRoutes(t, srv) })
+	t.Run("audit", func(t *testing.T) { testAuditRoutes(t, srv) })
+	t.Run("rate limits", func(t *testing.T) { testRateLimits(t, repos) })
+}
+
+// createBoard creates a board as the shared admin and returns its id.
+func createBoard(t *testing.T, srv *testutil.Server, name string) string {
+	t.Helper()
+	var out struct {
+		ID string `json:"id"`
+	}
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/boards", srv.AdminToken(t), map[string]any{"name": name}).JSON(t, &out)
+	return out.ID
+}
+
+// createPost creates a post through the API and returns its id.
+func createPost(t *testing.T, srv *testutil.Server, token, boardID, title string) string {
+	t.Helper()
+	var out struct {
+		ID string `json:"id"`
+	}
+	srv.Expect(t, http.StatusCreated, http.MethodPost, "/api/posts", token, map[string]any{
+		"board_id": boardID, "title": title, "content": title + " body",
+	}).JSON(t, &out)
+	return out.ID
+}
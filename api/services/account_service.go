@@ -0,0 +1,495 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// accountCacheTTL bounds how long another instance may keep honoring a token
+// after a suspension; changes made through this instance apply immediately.
+const accountCacheTTL = 30 * time.Second
+
+// accountCacheSize is how many accounts the cache holds before expired entries
+// are swept, so accounts that stop making requests do not stay cached forever.
+const accountCacheSize = 1024
+
+// Account actions recorded in the account history.
+const (
+	actionSuspend   = "suspend"
+	actionBan       = "ban"
+	actionReinstate = "reinstate"
+	actionDelete    = "delete"
+	actionRestore   = "restore"
+	actionMoveOut   = "move_out"
+)
+
+var auditByAccountAction = map[string]string{
+	actionSuspend:   auditAccountSuspend,
+	actionBan:       auditAccountBan,
+	actionReinstate: auditAccountReinstate,
+	actionDelete:    auditAccountDelete,
+	actionRestore:   auditAccountRestore,
+	actionMoveOut:   auditAccountMoveOut,
+}
+
+// AccountService lets admins suspend, ban and reinstate residents, lets
+// residents delete their own account, and checks on every authenticated
+// request that the account may still use the API.
+type AccountService struct {
+	users   models.UserRepo
+	actions models.AccountActionRepo
+	audit   *AuditService
+	// deletionGrace is how long a deleted account can be restored by logging in.
+	deletionGrace time.Duration
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedAccount
+}
+
+type cachedAccount struct {
+	user      *models.User
+	fetchedAt time.Time
+}
+
+// NewAccountService returns an AccountService backed by the given repositories.
+func NewAccountService(users models.UserRepo, actions models.AccountActionRepo, audit *AuditService, deletionGrace time.Duration) *AccountService {
+	return &AccountService{users: users, actions: actions, audit: audit, deletionGrace: deletionGrace, cache: map[uuid.UUID]cachedAccount{}}
+}
+
+type SuspendUserInput struct {
+	Reason string     `json:"reason" validate:"required,max=500"`
+	Until  *time.Time `json:"until" validate:"required"`
+}
+
+type AccountActionInput struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// UpdateRolesInput grants or revokes roles; omitted fields are unchanged.
+type UpdateRolesInput struct {
+	IsAdmin     *bool `json:"is_admin"`
+	IsModerator *bool `json:"is_moderator"`
+}
+
+type AccountDTO struct {
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	UnitNumber     string     `json:"unit_number"`
+	IsAdmin        bool       `json:"is_admin"`
+	IsModerator    bool       `json:"is_moderator"`
+	Status         string     `json:"status"`
+	StatusReason   *string    `json:"status_reason"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	// Verification is unverified, verified or review; VerificationNote says
+	// why a registration did not match the roster.
+	Verification     string  `json:"verification"`
+	VerificationNote *string `json:"verification_note"`
+}
+
+// DeleteAccountInput confirms a resident's request to delete their account.
+type DeleteAccountInput struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// AccountDeletionDTO tells a resident when their account will be purged.
+type AccountDeletionDTO struct {
+	Status      string    `json:"status"`
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+type AccountActionDTO struct {
+	ID        string     `json:"id"`
+	Action    string     `json:"action"`
+	Reason    string     `json:"reason"`
+	Until     *time.Time `json:"until"`
+	ActorID   *string    `json:"actor_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func toAccountActionDTO(a models.AccountAction) AccountActionDTO {
+	return AccountActionDTO{
+		ID:        a.ID.String(),
+		Action:    a.Action,
+		Reason:    a.Reason,
+		Until:     a.Until,
+		ActorID:   uuidString(a.ActorID),
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+func toAccountDTO(u *models.User) *AccountDTO {
+	return &AccountDTO{
+		ID:               u.ID.String(),
+		Email:            u.Email,
+		UnitNumber:       u.UnitNumber,
+		IsAdmin:          u.IsAdmin,
+		IsModerator:      u.IsModerator,
+		Status:           effectiveStatus(u),
+		StatusReason:     u.StatusReason,
+		SuspendedUntil:   u.SuspendedUntil,
+		Verification:     u.Verification,
+		VerificationNote: u.VerificationNote,
+	}
+}
+
+// effectiveStatus treats a suspension whose end has passed as active.
+func effectiveStatus(u *models.User) string {
+	if u.Status == models.UserSuspended && u.SuspendedUntil != nil && !time.Now().Before(*u.SuspendedUntil) {
+		return models.UserActive
+	}
+	return u.Status
+}
+
+// accountStatusError explains why an account may not log in or use its tokens.
+func accountStatusError(u *models.User) error {
+	switch effectiveStatus(u) {
+	case models.UserActive:
+		return nil
+	case models.UserSuspended:
+		msg := "account is suspended"
+		if u.SuspendedUntil != nil {
+			msg += " until " + u.SuspendedUntil.UTC().Format(time.RFC3339)
+		}
+		return ForbiddenError(msg)
+	case models.UserBanned:
+		return ForbiddenError("account is banned")
+	case models.UserMovedOut:
+		return ForbiddenError("account was closed when the household moved out")
+	default:
+		return ForbiddenError("account is not active")
+	}
+}
+
+// CheckAccount confirms that a token's user still exists in the request's
+// community, has not had its tokens revoked and is active. Results are cached
+// briefly per user.
+func (s *AccountService) CheckAccount(ctx context.Context, userID uuid.UUID, tokenVersion int) error {
+	u, err := s.cachedUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if communityID, scoped := models.CommunityScope(ctx); u == nil || (scoped && u.CommunityID != communityID) {
+		return UnauthorizedError("account no longer exists")
+	}
+	if u.TokenVersion != tokenVersion {
+		return UnauthorizedError("token has been revoked")
+	}
+	return accountStatusError(u)
+}
+
+func (s *AccountService) cachedUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[id]
+	if ok && time.Since(entry.fetchedAt) >= accountCacheTTL {
+		delete(s.cache, id)
+		ok = false
+	}
+	s.mu.Unlock()
+	if ok {
+		return entry.user, nil
+	}
+
+	u, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if len(s.cache) >= accountCacheSize {
+		s.sweepCache()
+	}
+	s.cache[id] = cachedAccount{user: u, fetchedAt: time.Now()}
+	s.mu.Unlock()
+	return u, nil
+}
+
+// sweepCache drops expired accounts, or every account if none have expired
+// yet. Callers must hold mu.
+func (s *AccountService) sweepCache() {
+	for id, entry := range s.cache {
+		if time.Since(entry.fetchedAt) >= accountCacheTTL {
+			delete(s.cache, id)
+		}
+	}
+	if len(s.cache) >= accountCacheSize {
+		clear(s.cache)
+	}
+}
+
+func (s *AccountService) invalidate(id uuid.UUID) {
+	s.mu.Lock()
+	delete(s.cache, id)
+	s.mu.Unlock()
+}
+
+// Get returns a resident's account status for admins.
+func (s *AccountService) Get(ctx context.Context, actorID, userID uuid.UUID) (*AccountDTO, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toAccountDTO(u), nil
+}
+
+// History lists every suspension, ban, reinstatement, deletion request and move-out of a resident, newest first.
+func (s *AccountService) History(ctx context.Context, actorID, userID uuid.UUID) ([]AccountActionDTO, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if _, err := s.loadUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	actions, err := s.actions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AccountActionDTO, 0, len(actions))
+	for _, a := range actions {
+		out = append(out, toAccountActionDTO(a))
+	}
+	return out, nil
+}
+
+// Suspend blocks a resident until in.Until and revokes their tokens. A banned
+// resident must be reinstated first so the ban is not replaced by a suspension
+// that ends on its own.
+func (s *AccountService) Suspend(ctx context.Context, actorID, userID uuid.UUID, in SuspendUserInput) (*AccountDTO, error) {
+	in.Reason = strings.TrimSpace(in.Reason)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if !in.Until.After(time.Now()) {
+		return nil, FieldError("until", "must be in the future")
+	}
+	u, err := s.restrictable(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Status == models.UserBanned {
+		return nil, ConflictError("account is banned", nil)
+	}
+	if err := s.setStatus(ctx, &actorID, u, actionSuspend, in.Reason, in.Until); err != nil {
+		return nil, err
+	}
+	return toAccountDTO(u), nil
+}
+
+// Ban blocks a resident indefinitely and revokes their tokens.
+func (s *AccountService) Ban(ctx context.Context, actorID, userID uuid.UUID, in AccountActionInput) (*AccountDTO, error) {
+	in.Reason = strings.TrimSpace(in.Reason)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	u, err := s.restrictable(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setStatus(ctx, &actorID, u, actionBan, in.Reason, nil); err != nil {
+		return nil, err
+	}
+	return toAccountDTO(u), nil
+}
+
+// Reinstate lifts a suspension or ban, or reopens an account closed by a
+// household move-out. The resident must log in again.
+func (s *AccountService) Reinstate(ctx context.Context, actorID, userID uuid.UUID, in AccountActionInput) (*AccountDTO, error) {
+	in.Reason = strings.TrimSpace(in.Reason)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Status != models.UserSuspended && u.Status != models.UserBanned && u.Status != models.UserMovedOut {
+		return nil, ConflictError("account is not suspended, banned or moved out", nil)
+	}
+	if err := s.setStatus(ctx, &actorID, u, actionReinstate, in.Reason, nil); err != nil {
+		return nil, err
+	}
+	return toAccountDTO(u), nil
+}
+
+// RequestDeletion deactivates the caller's account and revokes their tokens
+// once they confirm their password. Logging in again within the grace period
+// restores it; afterwards the retention worker purges it. Admins must hand
+// their role to someone else first so an admin always remains.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uuid.UUID, in DeleteAccountInput) (*AccountDeletionDTO, error) {
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPassword(u.HashedPassword, in.Password) {
+		return nil, FieldError("password", "is incorrect")
+	}
+	if u.IsAdmin {
+		return nil, ForbiddenError("admins must give up their admin role before deleting their account")
+	}
+	if err := s.setStatus(ctx, &userID, u, actionDelete, "requested by resident", nil); err != nil {
+		return nil, err
+	}
+	return &AccountDeletionDTO{Status: u.Status, DeleteAfter: u.DeletionRequestedAt.Add(s.deletionGrace)}, nil
+}
+
+// UpdateRoles grants or revokes the admin and moderator roles. Admins cannot
+// remove their own admin role, so at least one admin always remains.
+func (s *AccountService) UpdateRoles(ctx context.Context, actorID, userID uuid.UUID, in UpdateRolesInput) (*AccountDTO, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if in.IsAdmin == nil && in.IsModerator == nil {
+		return nil, ValidationError("provide is_admin or is_moderator", nil)
+	}
+	if actorID == userID && in.IsAdmin != nil && !*in.IsAdmin {
+		return nil, ForbiddenError("you cannot remove your own admin role")
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{}
+	if in.IsAdmin != nil && *in.IsAdmin != u.IsAdmin {
+		details["is_admin"] = strconv.FormatBool(*in.IsAdmin)
+		u.IsAdmin = *in.IsAdmin
+	}
+	if in.IsModerator != nil && *in.IsModerator != u.IsModerator {
+		details["is_moderator"] = strconv.FormatBool(*in.IsModerator)
+		u.IsModerator = *in.IsModerator
+	}
+	if len(details) == 0 {
+		return toAccountDTO(u), nil
+	}
+	if err := s.users.SetRoles(ctx, u.ID, u.IsAdmin, u.IsModerator); err != nil {
+		return nil, err
+	}
+	s.invalidate(u.ID)
+	if err := s.audit.Record(ctx, &actorID, auditUserRoles, "user", &u.ID, details); err != nil {
+		return nil, err
+	}
+	utils.Infof("account roles changed id=%s by=%s admin=%t moderator=%t", u.ID, actorID, u.IsAdmin, u.IsModerator)
+	return toAccountDTO(u), nil
+}
+
+// restrictable loads a user an admin may suspend or ban: not themselves, not
+// another admin and not an account waiting out its deletion, whose purge a
+// new status would cancel.
+func (s *AccountService) restrictable(ctx context.Context, actorID, userID uuid.UUID) (*models.User, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if actorID == userID {
+		return nil, ForbiddenError("you cannot restrict your own account")
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsAdmin {
+		return nil, ForbiddenError("admins cannot be suspended or banned")
+	}
+	if u.DeletionRequestedAt != nil {
+		return nil, ConflictError("account is pending deletion", nil)
+	}
+	return u, nil
+}
+
+// setStatus applies an account action, records it in the history and drops
+// the cached account so the change takes effect on the next request.
+func (s *AccountService) setStatus(ctx context.Context, actorID *uuid.UUID, u *models.User, action, reason string, until *time.Time) error {
+	revoke := true
+	switch action {
+	case actionSuspend:
+		u.Status, u.StatusReason, u.SuspendedUntil = models.UserSuspended, &reason, until
+	case actionBan:
+		u.Status, u.StatusReason, u.SuspendedUntil = models.UserBanned, &reason, nil
+	case actionReinstate:
+		u.Status, u.StatusReason, u.SuspendedUntil = models.UserActive, nil, nil
+		revoke = false
+	case actionDelete:
+		now := time.Now().UTC()
+		u.Status, u.StatusReason, u.SuspendedUntil = models.UserInactive, &reason, nil
+		u.DeletionRequestedAt = &now
+	case actionRestore:
+		u.Status, u.StatusReason, u.DeletionRequestedAt = models.UserActive, nil, nil
+		revoke = false
+	case actionMoveOut:
+		// The household itself is dissolved by the caller.
+		u.Status, u.StatusReason, u.SuspendedUntil = models.UserMovedOut, &reason, nil
+		u.HouseholdID = nil
+	default:
+		return fmt.Errorf("unknown account action %q", action)
+	}
+	if err := s.users.SetStatus(ctx, u, revoke); err != nil {
+		return err
+	}
+	s.invalidate(u.ID)
+	if err := s.actions.Insert(ctx, &models.AccountAction{UserID: u.ID, ActorID: actorID, Action: action, Reason: reason, Until: until}); err != nil {
+		return err
+	}
+	details := map[string]string{"reason": reason}
+	if until != nil {
+		details["until"] = until.UTC().Format(time.RFC3339)
+	}
+	if err := s.audit.Record(ctx, actorID, auditByAccountAction[action], "user", &u.ID, details); err != nil {
+		return err
+	}
+	utils.Infof("account %s id=%s by=%s reason=%q", action, u.ID, actorLabel(actorID), reason)
+	return nil
+}
+
+// liftExpiredSuspension reactivates a user whose suspension has ended.
+func (s *AccountService) liftExpiredSuspension(ctx context.Context, u *models.User) error {
+	if u.Status != models.UserSuspended || effectiveStatus(u) != models.UserActive {
+		return nil
+	}
+	return s.setStatus(ctx, nil, u, actionReinstate, "suspension expired", nil)
+}
+
+// cancelDeletion restores an account whose owner logs in before the
+// retention worker has purged it.
+func (s *AccountService) cancelDeletion(ctx context.Context, u *models.User) error {
+	if u.Status != models.UserInactive || u.DeletionRequestedAt == nil {
+		return nil
+	}
+	return s.setStatus(ctx, &u.ID, u, actionRestore, "logged in during the deletion grace period", nil)
+}
+
+func (s *AccountService) loadUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	u, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *AccountService) requireAdmin(ctx context.Context, id uuid.UUID) error {
+	return requireAdmin(ctx, s.users, id)
+}
+
+// actorLabel names who made a change in logs; nil means the system did.
+func actorLabel(id *uuid.UUID) string {
+	if id == nil {
+		return "system"
+	}
+	return id.String()
+}
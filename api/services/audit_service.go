@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+// Audited actions, named <target>.<verb>.
+const (
+	auditBulletinCreate      = "bulletin.create"
+	auditBoardCreate         = "board.create"
+	auditBoardUpdate         = "board.update"
+	auditBoardArchive        = "board.archive"
+	auditBoardUnarchive      = "board.unarchive"
+	auditBoardReorder        = "board.reorder"
+	auditBoardDelete         = "board.delete"
+	auditBoardMemberAdd      = "board.member_add"
+	auditBoardMemberApprove  = "board.member_approve"
+	auditBoardMemberRemove   = "board.member_remove"
+	auditReactionTypeCreate  = "reaction_type.create"
+	auditReactionTypeDelete  = "reaction_type.delete"
+	auditUserRoles           = "user.roles"
+	auditAccountSuspend      = "account.suspend"
+	auditAccountBan          = "account.ban"
+	auditAccountReinstate    = "account.reinstate"
+	auditAccountDelete       = "account.delete"
+	auditAccountRestore      = "account.restore"
+	auditAccountPurge        = "account.purge"
+	auditAccountMoveOut      = "account.move_out"
+	auditReportClaim         = "report.claim"
+	auditReportResolve       = "report.resolve"
+	auditModerationAutoHide  = "moderation.auto_hide"
+	auditProfileUnhide       = "moderation.profile_unhide"
+	auditCommunityCreate     = "community.create"
+	auditCommunityAdminGrant = "community.admin_grant"
+	auditInviteCreate        = "invite.create"
+	auditInviteRevoke        = "invite.revoke"
+	auditRosterImport        = "roster.import"
+	auditUserVerify          = "user.verify"
+	auditHouseholdMoveOut    = "household.move_out"
+)
+
+// Audit query limits. Exports are capped so a single request stays bounded.
+const (
+	defaultAuditLimit = 100
+	maxAuditExport    = 10000
+)
+
+// AuditService records privileged actions and lets admins search and export them.
+type AuditService struct {
+	events models.AuditRepo
+	users  models.UserRepo
+}
+
+// NewAuditService returns an AuditService backed by the given repositories.
+func NewAuditService(events models.AuditRepo, users models.UserRepo) *AuditService {
+	return &AuditService{events: events, users: users}
+}
+
+// AuditQueryInput filters the audit log. IDs are UUIDs and From/To are RFC 3339
+// timestamps; both are parsed in filter so every bad field is reported at once.
+type AuditQueryInput struct {
+	ActorID    string `form:"actor_id" json:"actor_id"`
+	Action     string `form:"action" json:"action" validate:"omitempty,max=64"`
+	TargetType string `form:"target_type" json:"target_type" validate:"omitempty,max=32"`
+	TargetID   string `form:"target_id" json:"target_id"`
+	From       string `form:"from" json:"from"`
+	To         string `form:"to" json:"to"`
+	Limit      int    `form:"limit" json:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+type AuditEventDTO struct {
+	ID         string            `json:"id"`
+	ActorID    *string           `json:"actor_id"`
+	Action     string            `json:"action"`
+	TargetType string            `json:"target_type"`
+	TargetID   *string           `json:"target_id"`
+	Details    map[string]string `json:"details"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func toAuditEventDTO(e *models.AuditEvent) AuditEventDTO {
+	return AuditEventDTO{
+		ID:         e.ID.String(),
+		ActorID:    uuidString(e.ActorID),
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   uuidString(e.TargetID),
+		Details:    e.Details,
+		CreatedAt:  e.CreatedAt,
+	}
+}
+
+func uuidString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
+
+// Record appends an event to the audit log. actorID is nil for system actions.
+func (s *AuditService) Record(ctx context.Context, actorID *uuid.UUID, action, targetType string, targetID *uuid.UUID, details map[string]string) error {
+	return s.events.Insert(ctx, &models.AuditEvent{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	})
+}
+
+// Query lists matching events for admins, newest first.
+func (s *AuditService) Query(ctx context.Context, adminID uuid.UUID, in AuditQueryInput) ([]AuditEventDTO, error) {
+	filter, err := s.filter(ctx, adminID, in)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	events, err := s.events.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AuditEventDTO, 0, len(events))
+	for i := range events {
+		out = append(out, toAuditEventDTO(&events[i]))
+	}
+	return out, nil
+}
+
+// Export writes matching events to w as CSV, newest first. Details are JSON-encoded.
+func (s *AuditService) Export(ctx context.Context, adminID uuid.UUID, in AuditQueryInput, w io.Writer) error {
+	filter, err := s.filter(ctx, adminID, in)
+	if err != nil {
+		return err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = maxAuditExport
+	}
+	events, err := s.events.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "details"}); err != nil {
+		return err
+	}
+	for _, e := range events {
+		details, err := json.Marshal(e.Details)
+		if err != nil {
+			return err
+		}
+		record := []string{
+			e.ID.String(),
+			e.CreatedAt.UTC().Format(time.RFC3339Nano),
+			optionalUUID(e.ActorID),
+			e.Action,
+			e.TargetType,
+			optionalUUID(e.TargetID),
+			string(details),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// filter checks the caller is an admin and converts the query into a repository filter.
+func (s *AuditService) filter(ctx context.Context, adminID uuid.UUID, in AuditQueryInput) (models.AuditFilter, error) {
+	var f models.AuditFilter
+	if err := requireAdmin(ctx, s.users, adminID); err != nil {
+		return f, err
+	}
+	in.Action = strings.TrimSpace(in.Action)
+	in.TargetType = strings.TrimSpace(in.TargetType)
+	if err := validateInput(in); err != nil {
+		return f, err
+	}
+
+	f.Action, f.TargetType, f.Limit = in.Action, in.TargetType, in.Limit
+	details := map[string]string{}
+	for field, raw := range map[string]string{"actor_id": in.ActorID, "target_id": in.TargetID} {
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			details[field] = "must be a valid UUID"
+			continue
+		}
+		if field == "actor_id" {
+			f.ActorID = &id
+		} else {
+			f.TargetID = &id
+		}
+	}
+	for field, raw := range map[string]string{"from": in.From, "to": in.To} {
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			details[field] = "must be an RFC 3339 timestamp"
+			continue
+		}
+		if field == "from" {
+			f.From = &t
+		} else {
+			f.To = &t
+		}
+	}
+	if len(details) > 0 {
+		return f, ValidationError("invalid request", details)
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return f, FieldError("to", "must be after from")
+	}
+	return f, nil
+}
+
+func optionalUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -4,22 +4,47 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
+	"github.com/cameronsralla/culdechat/config"
 	"github.com/cameronsralla/culdechat/models"
 	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
 )
 
-type AuthService struct{}
+type AuthService struct {
+	users        models.UserRepo
+	communities  models.CommunityRepo
+	invites      *InviteService
+	roster       *RosterService
+	exports      *ExportService
+	tokens       *utils.TokenIssuer
+	accounts     *AccountService
+	lockout      config.LockoutPolicy
+	registration config.RegistrationConfig
+}
+
+// NewAuthService returns an AuthService that signs tokens with the given issuer,
+// locks accounts according to lockout and admits residents according to registration.
+func NewAuthService(users models.UserRepo, communities models.CommunityRepo, invites *InviteService, roster *RosterService, exports *ExportService, tokens *utils.TokenIssuer, accounts *AccountService, lockout config.LockoutPolicy, registration config.RegistrationConfig) *AuthService {
+	return &AuthService{users: users, communities: communities, invites: invites, roster: roster, exports: exports, tokens: tokens, accounts: accounts, lockout: lockout, registration: registration}
+}
 
+// RegisterInput creates a resident. InviteCode is required unless registration
+// is open or the resident is the bootstrap admin, and the invite decides the
+// community. Without one, Community is the slug of the property to join,
+// defaulting to the request's community.
 type RegisterInput struct {
-	Email      string `json:"email"`
-	UnitNumber string `json:"unit_number"`
-	Password   string `json:"password"`
+	Email      string `json:"email" validate:"required,email,max=254"`
+	UnitNumber string `json:"unit_number" validate:"required,max=16"`
+	Password   string `json:"password" validate:"required,password"`
+	Community  string `json:"community" validate:"omitempty,slug"`
+	InviteCode string `json:"invite_code" validate:"max=32"`
 }
 
 type LoginInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
 type AuthResponse struct {
@@ -28,29 +53,72 @@ type AuthResponse struct {
 }
 
 type AuthUserDTO struct {
-	ID         string `json:"id"`
-	UnitNumber string `json:"unit_number"`
+	ID           string `json:"id"`
+	CommunityID  string `json:"community_id"`
+	UnitNumber   string `json:"unit_number"`
+	Verification string `json:"verification"`
+}
+
+// issue signs a token for u, scoped to u's community.
+func (s *AuthService) issue(u *models.User) (*AuthResponse, error) {
+	token, err := s.tokens.GenerateAccessToken(u.ID.String(), u.CommunityID.String(), u.UnitNumber, u.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResponse{
+		Token: token,
+		User: AuthUserDTO{
+			ID:           u.ID.String(),
+			CommunityID:  u.CommunityID.String(),
+			UnitNumber:   u.UnitNumber,
+			Verification: u.Verification,
+		},
+	}, nil
 }
 
 func (s *AuthService) Register(ctx context.Context, in RegisterInput) (*AuthResponse, error) {
 	in.Email = strings.TrimSpace(strings.ToLower(in.Email))
 	in.UnitNumber = strings.TrimSpace(in.UnitNumber)
-	if in.Email == "" || in.UnitNumber == "" || in.Password == "" {
-		return nil, errors.New("email, unit_number and password are required")
+	in.Community = strings.TrimSpace(strings.ToLower(in.Community))
+	if err := validateInput(in); err != nil {
+		return nil, err
 	}
-
-	// Ensure table exists
-	if err := models.EnsureUsersTable(ctx); err != nil {
+	bootstrap, err := s.bootstrapping(ctx, in)
+	if err != nil {
 		return nil, err
 	}
+	var invite *models.Invite
+	if in.InviteCode != "" || (s.registration.RequireInvite && !bootstrap) {
+		inv, err := s.invites.check(ctx, in.InviteCode, in.UnitNumber)
+		if err != nil {
+			return nil, err
+		}
+		invite = inv
+	}
+	if in.Community != "" {
+		c, err := s.communities.GetBySlug(ctx, in.Community)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, FieldError("community", "does not exist")
+		}
+		if invite != nil && invite.CommunityID != c.ID {
+			return nil, FieldError("community", "does not match the invite")
+		}
+		ctx = models.WithCommunity(ctx, c.ID)
+	}
+	if invite != nil {
+		ctx = models.WithCommunity(ctx, invite.CommunityID)
+	}
 
-	// Check for existing user by email
-	existing, err := models.GetUserByEmail(ctx, in.Email)
+	// Emails identify logins, so they are unique across communities.
+	existing, err := s.users.GetByEmail(models.AcrossCommunities(ctx), in.Email)
 	if err != nil {
 		return nil, err
 	}
 	if existing != nil {
-		return nil, errors.New("user already exists")
+		return nil, ConflictError("user already exists", map[string]string{"email": "already registered"})
 	}
 
 	hashed, err := utils.HashPassword(in.Password)
@@ -63,64 +131,165 @@ func (s *AuthService) Register(ctx context.Context, in RegisterInput) (*AuthResp
 		Email:            in.Email,
 		HashedPassword:   hashed,
 		IsDirectoryOptIn: false,
-		IsAdmin:          false,
-		Status:           "active",
+		IsAdmin:          bootstrap,
+		Status:           models.UserActive,
+	}
+	if invite != nil {
+		// Co-resident invites also add the new resident to the household.
+		user.HouseholdID = invite.HouseholdID
 	}
-	if err := models.InsertUser(ctx, user); err != nil {
+	if err := s.roster.check(ctx, user); err != nil {
 		return nil, err
 	}
-
-	token, err := utils.GenerateAccessToken(user.ID.String(), user.UnitNumber)
-	if err != nil {
+	if err := s.users.Insert(ctx, user); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, ConflictError("user already exists", map[string]string{"email": "already registered"})
+		}
+		if errors.Is(err, models.ErrInvalidReference) {
+			// The household was dissolved after the invite was checked.
+			return nil, FieldError("invite_code", "is invalid or expired")
+		}
 		return nil, err
 	}
+	// The invite is spent only once the account exists, so a failed
+	// registration leaves it usable. If its last use went to someone else in
+	// the meantime, the new account is removed again.
+	if invite != nil {
+		if err := s.invites.consume(ctx, invite); err != nil {
+			if delErr := s.users.Delete(ctx, user.ID); delErr != nil {
+				utils.Errorf("remove user id=%s after failed invite redemption: %v", user.ID, delErr)
+			}
+			return nil, err
+		}
+	}
 
-	utils.Infof("user registered email=%s unit=%s id=%s", user.Email, user.UnitNumber, user.ID)
-	return &AuthResponse{
-		Token: token,
-		User: AuthUserDTO{
-			ID:         user.ID.String(),
-			UnitNumber: user.UnitNumber,
-		},
-	}, nil
+	if bootstrap {
+		utils.Warnf("bootstrap admin registered email=%s id=%s; unset BOOTSTRAP_ADMIN_EMAIL", user.Email, user.ID)
+	}
+	utils.Infof("user registered email=%s unit=%s id=%s community=%s verification=%s", user.Email, user.UnitNumber, user.ID, user.CommunityID, user.Verification)
+	return s.issue(user)
 }
 
-func (s *AuthService) Login(ctx context.Context, in LoginInput) (*AuthResponse, error) {
-	in.Email = strings.TrimSpace(strings.ToLower(in.Email))
-	if in.Email == "" || in.Password == "" {
-		return nil, errors.New("email and password are required")
+// bootstrapping reports whether in registers the configured bootstrap admin:
+// the email matches, no invite is given, the resident joins the default
+// community and that community has no admin yet. This is how a new deployment
+// gets the first admin, who can then issue invites.
+func (s *AuthService) bootstrapping(ctx context.Context, in RegisterInput) (bool, error) {
+	email := strings.TrimSpace(strings.ToLower(s.registration.BootstrapAdminEmail))
+	if email == "" || in.Email != email || in.InviteCode != "" {
+		return false, nil
+	}
+	if in.Community != "" && in.Community != models.DefaultCommunitySlug {
+		return false, nil
 	}
+	if communityID, _ := models.CommunityScope(ctx); in.Community == "" && communityID != models.DefaultCommunityID {
+		return false, nil
+	}
+	exists, err := s.users.HasAdmin(models.WithCommunity(ctx, models.DefaultCommunityID))
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
 
-	// Ensure table exists
-	if err := models.EnsureUsersTable(ctx); err != nil {
+func (s *AuthService) Login(ctx context.Context, in LoginInput) (*AuthResponse, error) {
+	in.Email = strings.TrimSpace(strings.ToLower(in.Email))
+	if err := validateInput(in); err != nil {
 		return nil, err
 	}
 
-	u, err := models.GetUserByEmail(ctx, in.Email)
+	// Logins are found across communities; everything after acts in the user's own.
+	u, err := s.users.GetByEmail(models.AcrossCommunities(ctx), in.Email)
 	if err != nil {
 		return nil, err
 	}
 	if u == nil {
-		return nil, errors.New("invalid credentials")
+		return nil, UnauthorizedError("invalid credentials")
+	}
+	ctx = models.WithCommunity(ctx, u.CommunityID)
+	// Refuse locked accounts before checking the password so guesses during the lock reveal nothing.
+	if u.LockedUntil != nil && time.Now().Before(*u.LockedUntil) {
+		return nil, RateLimitedError("too many failed login attempts; try again later", time.Until(*u.LockedUntil))
 	}
 	if !utils.CheckPassword(u.HashedPassword, in.Password) {
-		return nil, errors.New("invalid credentials")
+		if err := s.recordLoginFailure(ctx, u); err != nil {
+			return nil, err
+		}
+		return nil, UnauthorizedError("invalid credentials")
 	}
-	if u.Status != "active" {
-		return nil, errors.New("account is not active")
+	if err := s.accounts.liftExpiredSuspension(ctx, u); err != nil {
+		return nil, err
 	}
-
-	token, err := utils.GenerateAccessToken(u.ID.String(), u.UnitNumber)
-	if err != nil {
+	if err := s.accounts.cancelDeletion(ctx, u); err != nil {
 		return nil, err
 	}
+	if err := accountStatusError(u); err != nil {
+		return nil, err
+	}
+	if u.FailedLoginAttempts > 0 || u.LockedUntil != nil {
+		if err := s.users.ResetLoginFailures(ctx, u.ID); err != nil {
+			return nil, err
+		}
+	}
 
 	utils.Infof("user logged in email=%s id=%s", u.Email, u.ID)
-	return &AuthResponse{
-		Token: token,
-		User: AuthUserDTO{
-			ID:         u.ID.String(),
-			UnitNumber: u.UnitNumber,
-		},
+	return s.issue(u)
+}
+
+// recordLoginFailure counts a failed login and, once the lockout threshold is
+// reached, locks the account for a period that doubles with each further failure.
+func (s *AuthService) recordLoginFailure(ctx context.Context, u *models.User) error {
+	attempts, err := s.users.RecordLoginFailure(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if s.lockout.Threshold <= 0 || attempts < s.lockout.Threshold {
+		return nil
+	}
+	d := s.lockout.Base
+	for i := s.lockout.Threshold; i < attempts && d < s.lockout.Max; i++ {
+		d *= 2
+	}
+	d = min(d, s.lockout.Max)
+	utils.Warnf("account locked after failed logins id=%s attempts=%d duration=%s", u.ID, attempts, d)
+	return s.users.LockUntil(ctx, u.ID, time.Now().Add(d))
+}
+
+type MeDTO struct {
+	ID          string `json:"id"`
+	CommunityID string `json:"community_id"`
+	Email       string `json:"email"`
+	UnitNumber  string `json:"unit_number"`
+	Status      string `json:"status"`
+	// Verification is unverified, verified or review (awaiting an admin).
+	Verification string `json:"verification"`
+	// HouseholdID is the household the resident shares their unit with, if any.
+	HouseholdID *string `json:"household_id"`
+	// ExportsReady counts finished data exports not downloaded yet.
+	ExportsReady int `json:"exports_ready"`
+}
+
+// Me returns the account behind an authenticated request.
+func (s *AuthService) Me(ctx context.Context, userID uuid.UUID) (*MeDTO, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	ready, err := s.exports.ReadyCount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &MeDTO{
+		ID:           u.ID.String(),
+		CommunityID:  u.CommunityID.String(),
+		Email:        u.Email,
+		UnitNumber:   u.UnitNumber,
+		Status:       effectiveStatus(u),
+		Verification: u.Verification,
+		HouseholdID:  uuidString(u.HouseholdID),
+		ExportsReady: ready,
 	}, nil
 }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
+)
+
+// BoardAccess decides who may read and write each board. Every post, comment
+// and reaction path goes through it so visibility is enforced in one place.
+type BoardAccess struct {
+	boards  models.BoardRepo
+	members models.BoardMemberRepo
+	users   models.UserRepo
+}
+
+// NewBoardAccess returns a BoardAccess backed by the given repositories.
+func NewBoardAccess(boards models.BoardRepo, members models.BoardMemberRepo, users models.UserRepo) *BoardAccess {
+	return &BoardAccess{boards: boards, members: members, users: users}
+}
+
+// isStaff reports whether u may manage restricted boards.
+func isStaff(u *models.User) bool {
+	return u != nil && (u.IsAdmin || u.IsModerator)
+}
+
+// requireStaff returns Forbidden unless id belongs to an admin or moderator.
+func requireStaff(ctx context.Context, users models.UserRepo, id uuid.UUID) (*models.User, error) {
+	u, err := users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff(u) {
+		return nil, ForbiddenError("moderator access required")
+	}
+	return u, nil
+}
+
+// user loads the caller, returning nil for anonymous requests.
+func (a *BoardAccess) user(ctx context.Context, id *uuid.UUID) (*models.User, error) {
+	if id == nil {
+		return nil, nil
+	}
+	return a.users.GetByID(ctx, *id)
+}
+
+// canView reports whether u may read b. Anonymous callers only see public
+// read boards, members-only boards are limited to staff and approved members
+// and every other board is open to signed-in residents.
+func (a *BoardAccess) canView(ctx context.Context, b *models.Board, u *models.User) (bool, error) {
+	if u == nil {
+		return publicRead(b), nil
+	}
+	if b.Visibility != models.BoardMembers || isStaff(u) {
+		return true, nil
+	}
+	m, err := a.members.Get(ctx, b.ID, u.ID)
+	if err != nil {
+		return false, err
+	}
+	return m != nil && m.Status == models.MemberApproved, nil
+}
+
+// view returns the board when the caller may read it: NotFound for a missing
+// board, Unauthorized for anonymous callers on a board that is not public read
+// and Forbidden for a members-only board the caller has not joined.
+func (a *BoardAccess) view(ctx context.Context, boardID uuid.UUID, viewerID *uuid.UUID) (*models.Board, *models.User, error) {
+	b, err := a.boards.GetByID(ctx, boardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b == nil {
+		return nil, nil, NotFoundError("board not found")
+	}
+	u, err := a.user(ctx, viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ok, err := a.canView(ctx, b, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok && u == nil {
+		return nil, nil, UnauthorizedError("sign in to read this board")
+	}
+	if !ok {
+		return nil, nil, ForbiddenError("board is members-only")
+	}
+	return b, u, nil
+}
+
+// interact checks the caller may comment or react on the board: they must be
+// able to read it and it must not be archived.
+func (a *BoardAccess) interact(ctx context.Context, boardID, userID uuid.UUID) (*models.Board, *models.User, error) {
+	b, u, err := a.view(ctx, boardID, &userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.ArchivedAt != nil {
+		return nil, nil, ConflictError("board is archived", nil)
+	}
+	return b, u, nil
+}
+
+// post checks the caller may start a thread; announcement boards accept posts
+// from staff only.
+func (a *BoardAccess) post(ctx context.Context, boardID, userID uuid.UUID) (*models.Board, *models.User, error) {
+	b, u, err := a.interact(ctx, boardID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.Visibility == models.BoardAnnouncement && !isStaff(u) {
+		return nil, nil, ForbiddenError("only moderators can post on announcement boards")
+	}
+	return b, u, nil
+}
+
+// visibleBoards returns the set of boards the caller may read, for filtering
+// listings that span boards.
+func (a *BoardAccess) visibleBoards(ctx context.Context, viewerID *uuid.UUID) (map[uuid.UUID]bool, error) {
+	boards, err := a.boards.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u, err := a.user(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	approved := map[uuid.UUID]bool{}
+	if u != nil && !isStaff(u) {
+		memberships, err := a.members.ListByUser(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range memberships {
+			approved[m.BoardID] = m.Status == models.MemberApproved
+		}
+	}
+	out := make(map[uuid.UUID]bool, len(boards))
+	for i, b := range boards {
+		if u == nil {
+			out[b.ID] = publicRead(&boards[i])
+			continue
+		}
+		out[b.ID] = b.Visibility != models.BoardMembers || isStaff(u) || approved[b.ID]
+	}
+	return out, nil
+}
+
+// publicRead reports whether anonymous callers may read b.
+func publicRead(b *models.Board) bool {
+	return b.PublicRead && b.Visibility != models.BoardMembers
+}
@@ -3,50 +3,575 @@ package services
 import (
 	"context"
 	"errors"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cameronsralla/culdechat/models"
+	"github.com/google/uuid"
 )
 
-type BoardService struct{}
+type BoardService struct {
+	boards  models.BoardRepo
+	members models.BoardMemberRepo
+	subs    models.SubscriptionRepo
+	posts   models.PostRepo
+	users   models.UserRepo
+	access  *BoardAccess
+	audit   *AuditService
+}
+
+// NewBoardService returns a BoardService backed by the given repositories.
+func NewBoardService(boards models.BoardRepo, members models.BoardMemberRepo, subs models.SubscriptionRepo, posts models.PostRepo, users models.UserRepo, access *BoardAccess, audit *AuditService) *BoardService {
+	return &BoardService{boards: boards, members: members, subs: subs, posts: posts, users: users, access: access, audit: audit}
+}
 
+// CreateBoardInput adds a board. Visibility defaults to public.
 type CreateBoardInput struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description"`
+	Name        string  `json:"name" validate:"required,max=64"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Visibility  string  `json:"visibility" validate:"omitempty,oneof=public members announcement"`
+	PublicRead  bool    `json:"public_read"`
+}
+
+// UpdateBoardInput changes a board; omitted fields are unchanged and an empty
+// description clears it.
+type UpdateBoardInput struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=64"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Visibility  *string `json:"visibility" validate:"omitempty,oneof=public members announcement"`
+	PublicRead  *bool   `json:"public_read"`
+	Archived    *bool   `json:"archived"`
+}
+
+// ReorderBoardsInput lists every board id in the desired display order.
+type ReorderBoardsInput struct {
+	BoardIDs []string `json:"board_ids" validate:"required,min=1,dive,uuid"`
+}
+
+// DeleteBoardInput optionally names a board to receive the deleted board's posts.
+type DeleteBoardInput struct {
+	MoveTo string `form:"move_to" json:"move_to" validate:"omitempty,uuid"`
+}
+
+// BoardMemberInput adds someone to a members-only board. Residents leave
+// UserID empty to ask for access; staff name the resident they are inviting.
+type BoardMemberInput struct {
+	UserID string `json:"user_id" validate:"omitempty,uuid"`
+}
+
+// SubscribeInput sets how much of a board reaches the caller's feed.
+type SubscribeInput struct {
+	Level string `json:"level" validate:"required,oneof=all bulletins muted"`
 }
 
 type BoardDTO struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description *string `json:"description"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	SortOrder   int        `json:"sort_order"`
+	Visibility  string     `json:"visibility"`
+	PublicRead  bool       `json:"public_read"`
+	Archived    bool       `json:"archived"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+	// Membership is the caller's status on a members-only board: "pending"
+	// or "approved". Omitted when the caller has not asked to join.
+	Membership string `json:"membership,omitempty"`
+	// Subscription is the caller's subscription; omitted for anonymous callers
+	// and boards they have not joined or can no longer read.
+	Subscription *SubscriptionDTO `json:"subscription,omitempty"`
+}
+
+type BoardMemberDTO struct {
+	BoardID   string    `json:"board_id"`
+	UserID    string    `json:"user_id"`
+	Status    string    `json:"status"`
+	InvitedBy *string   `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
-func (s *BoardService) Create(ctx context.Context, in CreateBoardInput) (*BoardDTO, error) {
+func toBoardMemberDTO(m *models.BoardMember) *BoardMemberDTO {
+	return &BoardMemberDTO{
+		BoardID:   m.BoardID.String(),
+		UserID:    m.UserID.String(),
+		Status:    m.Status,
+		InvitedBy: uuidString(m.InvitedBy),
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+type SubscriptionDTO struct {
+	BoardID     string    `json:"board_id"`
+	Level       string    `json:"level"`
+	UnreadCount int       `json:"unread_count"`
+	LastReadAt  time.Time `json:"last_read_at"`
+}
+
+func toSubscriptionDTO(s *models.Subscription) *SubscriptionDTO {
+	return &SubscriptionDTO{
+		BoardID:     s.BoardID.String(),
+		Level:       s.Level,
+		UnreadCount: s.UnreadCount,
+		LastReadAt:  s.LastReadAt,
+	}
+}
+
+func toBoardDTO(b *models.Board) BoardDTO {
+	return BoardDTO{
+		ID:          b.ID.String(),
+		Name:        b.Name,
+		Description: b.Description,
+		SortOrder:   b.SortOrder,
+		Visibility:  b.Visibility,
+		PublicRead:  b.PublicRead,
+		Archived:    b.ArchivedAt != nil,
+		ArchivedAt:  b.ArchivedAt,
+	}
+}
+
+// Create adds a board. Only admins may create boards.
+func (s *BoardService) Create(ctx context.Context, creatorID uuid.UUID, in CreateBoardInput) (*BoardDTO, error) {
+	if err := requireAdmin(ctx, s.users, creatorID); err != nil {
+		return nil, err
+	}
 	in.Name = strings.TrimSpace(in.Name)
-	if in.Name == "" {
-		return nil, errors.New("name is required")
+	in.Description = trimToNil(in.Description)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if in.Visibility == "" {
+		in.Visibility = models.BoardPublic
+	}
+	if in.PublicRead && in.Visibility == models.BoardMembers {
+		return nil, membersPublicReadError()
 	}
-	if err := models.EnsureBoardsTable(ctx); err != nil {
+	b := &models.Board{Name: in.Name, Description: in.Description, Visibility: in.Visibility, PublicRead: in.PublicRead}
+	if err := s.boards.Insert(ctx, b); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, boardNameConflict()
+		}
 		return nil, err
 	}
-	b := &models.Board{Name: in.Name, Description: in.Description}
-	if err := models.InsertBoard(ctx, b); err != nil {
+	if err := s.audit.Record(ctx, &creatorID, auditBoardCreate, "board", &b.ID, map[string]string{
+		"name": b.Name, "visibility": b.Visibility, "public_read": strconv.FormatBool(b.PublicRead),
+	}); err != nil {
 		return nil, err
 	}
-	return &BoardDTO{ID: b.ID.String(), Name: b.Name, Description: b.Description}, nil
+	dto := toBoardDTO(b)
+	return &dto, nil
 }
 
-func (s *BoardService) List(ctx context.Context) ([]BoardDTO, error) {
-	if err := models.EnsureBoardsTable(ctx); err != nil {
+// List returns every board, members-only ones included so residents can ask
+// to join. Anonymous callers only see public read boards. When viewerID is set
+// each board carries the viewer's membership, subscription and unread count.
+func (s *BoardService) List(ctx context.Context, viewerID *uuid.UUID) ([]BoardDTO, error) {
+	boards, err := s.boards.List(ctx)
+	if err != nil {
 		return nil, err
 	}
-	boards, err := models.ListBoards(ctx)
+	subs := map[uuid.UUID]*models.Subscription{}
+	memberships := map[uuid.UUID]string{}
+	visible, err := s.access.visibleBoards(ctx, viewerID)
 	if err != nil {
 		return nil, err
 	}
+	if viewerID != nil {
+		list, err := s.subs.ListByUser(ctx, *viewerID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			subs[list[i].BoardID] = &list[i]
+		}
+		members, err := s.members.ListByUser(ctx, *viewerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			memberships[m.BoardID] = m.Status
+		}
+	}
 	out := make([]BoardDTO, 0, len(boards))
+	for i := range boards {
+		if viewerID == nil && !visible[boards[i].ID] {
+			continue
+		}
+		dto := toBoardDTO(&boards[i])
+		dto.Membership = memberships[boards[i].ID]
+		if sub, ok := subs[boards[i].ID]; ok && visible[boards[i].ID] {
+			dto.Subscription = toSubscriptionDTO(sub)
+		}
+		out = append(out, dto)
+	}
+	return out, nil
+}
+
+// Subscribe joins a board or changes the subscription level. Members-only
+// boards require an approved membership first.
+func (s *BoardService) Subscribe(ctx context.Context, userID, boardID uuid.UUID, in SubscribeInput) (*SubscriptionDTO, error) {
+	in.Level = strings.TrimSpace(in.Level)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.access.view(ctx, boardID, &userID); err != nil {
+		return nil, err
+	}
+	sub := &models.Subscription{UserID: userID, BoardID: boardID, Level: in.Level}
+	if err := s.subs.Upsert(ctx, sub); err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return toSubscriptionDTO(sub), nil
+}
+
+// Unsubscribe leaves a board. Leaving a board you never joined is not an error.
+func (s *BoardService) Unsubscribe(ctx context.Context, userID, boardID uuid.UUID) error {
+	if _, err := s.getBoard(ctx, boardID); err != nil {
+		return err
+	}
+	return s.subs.Delete(ctx, userID, boardID)
+}
+
+// MarkRead clears the caller's unread count for a subscribed board.
+func (s *BoardService) MarkRead(ctx context.Context, userID, boardID uuid.UUID) error {
+	if _, err := s.getBoard(ctx, boardID); err != nil {
+		return err
+	}
+	ok, err := s.subs.MarkRead(ctx, userID, boardID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NotFoundError("not subscribed to this board")
+	}
+	return nil
+}
+
+// Update renames, describes, changes the visibility of, archives or restores a board.
+func (s *BoardService) Update(ctx context.Context, adminID, boardID uuid.UUID, in UpdateBoardInput) (*BoardDTO, error) {
+	if err := requireAdmin(ctx, s.users, adminID); err != nil {
+		return nil, err
+	}
+	in.Name = trimOptional(in.Name)
+	in.Description = trimOptional(in.Description)
+	clearDescription := in.Description != nil && *in.Description == ""
+	if clearDescription {
+		in.Description = nil
+	}
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	b, err := s.getBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{}
+	if in.Name != nil && *in.Name != b.Name {
+		details["name"] = *in.Name
+		b.Name = *in.Name
+	}
+	if clearDescription && b.Description != nil {
+		details["description"] = ""
+		b.Description = nil
+	} else if in.Description != nil && (b.Description == nil || *in.Description != *b.Description) {
+		details["description"] = *in.Description
+		b.Description = in.Description
+	}
+	if in.Visibility != nil && *in.Visibility != b.Visibility {
+		details["visibility"] = *in.Visibility
+		b.Visibility = *in.Visibility
+	}
+	if in.PublicRead != nil && *in.PublicRead != b.PublicRead {
+		details["public_read"] = strconv.FormatBool(*in.PublicRead)
+		b.PublicRead = *in.PublicRead
+	}
+	if b.PublicRead && b.Visibility == models.BoardMembers {
+		return nil, membersPublicReadError()
+	}
+	action := auditBoardUpdate
+	if in.Archived != nil && *in.Archived != (b.ArchivedAt != nil) {
+		if *in.Archived {
+			now := time.Now().UTC()
+			b.ArchivedAt = &now
+			action = auditBoardArchive
+		} else {
+			b.ArchivedAt = nil
+			action = auditBoardUnarchive
+		}
+	} else if len(details) == 0 {
+		dto := toBoardDTO(b)
+		return &dto, nil
+	}
+
+	if err := s.boards.Update(ctx, b); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, boardNameConflict()
+		}
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, &adminID, action, "board", &b.ID, details); err != nil {
+		return nil, err
+	}
+	dto := toBoardDTO(b)
+	return &dto, nil
+}
+
+// Reorder sets the display order of the board list. Every board must be listed once.
+func (s *BoardService) Reorder(ctx context.Context, adminID uuid.UUID, in ReorderBoardsInput) ([]BoardDTO, error) {
+	if err := requireAdmin(ctx, s.users, adminID); err != nil {
+		return nil, err
+	}
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	boards, err := s.boards.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uuid.UUID]bool, len(boards))
 	for _, b := range boards {
-		out = append(out, BoardDTO{ID: b.ID.String(), Name: b.Name, Description: b.Description})
+		known[b.ID] = false
+	}
+	ids := make([]uuid.UUID, 0, len(in.BoardIDs))
+	for _, raw := range in.BoardIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, FieldError("board_ids", "must contain valid UUIDs")
+		}
+		seen, ok := known[id]
+		if !ok {
+			return nil, FieldError("board_ids", "contains an unknown board "+raw)
+		}
+		if seen {
+			return nil, FieldError("board_ids", "lists board "+raw+" more than once")
+		}
+		known[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) != len(boards) {
+		return nil, FieldError("board_ids", "must list every board")
+	}
+
+	if err := s.boards.Reorder(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, &adminID, auditBoardReorder, "board", nil, map[string]string{"boards": strconv.Itoa(len(ids))}); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, &adminID)
+}
+
+// Delete removes a board. Its posts move to in.MoveTo when given and are
+// deleted with the board otherwise.
+func (s *BoardService) Delete(ctx context.Context, adminID, boardID uuid.UUID, in DeleteBoardInput) error {
+	if err := requireAdmin(ctx, s.users, adminID); err != nil {
+		return err
+	}
+	if err := validateInput(in); err != nil {
+		return err
+	}
+	b, err := s.getBoard(ctx, boardID)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]string{"name": b.Name}
+	if in.MoveTo != "" {
+		target, err := uuid.Parse(in.MoveTo)
+		if err != nil {
+			return FieldError("move_to", "must be a valid UUID")
+		}
+		if target == boardID {
+			return FieldError("move_to", "must be a different board")
+		}
+		dest, err := s.boards.GetByID(ctx, target)
+		if err != nil {
+			return err
+		}
+		if dest == nil {
+			return FieldError("move_to", "board not found")
+		}
+		if dest.ArchivedAt != nil {
+			return FieldError("move_to", "board is archived")
+		}
+		moved, err := s.posts.MoveBoard(ctx, boardID, target)
+		if err != nil {
+			return err
+		}
+		details["moved_to"] = target.String()
+		details["moved_posts"] = strconv.Itoa(moved)
+	}
+
+	if err := s.boards.Delete(ctx, boardID); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, &adminID, auditBoardDelete, "board", &boardID, details)
+}
+
+// Join adds a membership to a members-only board. A resident acting for
+// themselves creates a pending request; staff inviting anyone, themselves
+// included, approve the membership immediately.
+func (s *BoardService) Join(ctx context.Context, actorID, boardID uuid.UUID, in BoardMemberInput) (*BoardMemberDTO, error) {
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	target := actorID
+	if in.UserID != "" {
+		id, err := uuid.Parse(in.UserID)
+		if err != nil {
+			return nil, FieldError("user_id", "must be a valid UUID")
+		}
+		target = id
+	}
+	b, err := s.getBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Visibility != models.BoardMembers {
+		return nil, ConflictError("board is open to every resident", nil)
+	}
+	actor, err := s.users.GetByID(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	staff := isStaff(actor)
+	if target != actorID && !staff {
+		return nil, ForbiddenError("moderator access required")
+	}
+	if target != actorID {
+		u, err := s.users.GetByID(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	existing, err := s.members.Get(ctx, boardID, target)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && (existing.Status == models.MemberApproved || !staff) {
+		return toBoardMemberDTO(existing), nil
+	}
+	m := &models.BoardMember{BoardID: boardID, UserID: target, Status: models.MemberPending}
+	if staff {
+		m.Status, m.InvitedBy = models.MemberApproved, &actorID
+	}
+	if err := s.members.Upsert(ctx, m); err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			return nil, NotFoundError("user not found")
+		}
+		return nil, err
+	}
+	if staff {
+		if err := s.audit.Record(ctx, &actorID, auditBoardMemberAdd, "board", &boardID, map[string]string{"user_id": target.String()}); err != nil {
+			return nil, err
+		}
+	}
+	return toBoardMemberDTO(m), nil
+}
+
+// Approve accepts a pending request to join a members-only board.
+func (s *BoardService) Approve(ctx context.Context, staffID, boardID, userID uuid.UUID) (*BoardMemberDTO, error) {
+	if _, err := requireStaff(ctx, s.users, staffID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getBoard(ctx, boardID); err != nil {
+		return nil, err
+	}
+	m, err := s.getMember(ctx, boardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == models.MemberApproved {
+		return toBoardMemberDTO(m), nil
+	}
+	m.Status, m.InvitedBy = models.MemberApproved, &staffID
+	if err := s.members.Upsert(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, &staffID, auditBoardMemberApprove, "board", &boardID, map[string]string{"user_id": userID.String()}); err != nil {
+		return nil, err
+	}
+	return toBoardMemberDTO(m), nil
+}
+
+// RemoveMember withdraws a request or membership, along with any
+// subscription to the board. Residents may remove themselves; staff anyone.
+func (s *BoardService) RemoveMember(ctx context.Context, actorID, boardID, userID uuid.UUID) error {
+	if actorID != userID {
+		if _, err := requireStaff(ctx, s.users, actorID); err != nil {
+			return err
+		}
+	}
+	if _, err := s.getBoard(ctx, boardID); err != nil {
+		return err
+	}
+	if _, err := s.getMember(ctx, boardID, userID); err != nil {
+		return err
+	}
+	if err := s.members.Delete(ctx, boardID, userID); err != nil {
+		return err
+	}
+	if err := s.subs.Delete(ctx, userID, boardID); err != nil {
+		return err
+	}
+	if actorID == userID {
+		return nil
+	}
+	return s.audit.Record(ctx, &actorID, auditBoardMemberRemove, "board", &boardID, map[string]string{"user_id": userID.String()})
+}
+
+// ListMembers returns a board's members and pending requests, oldest first.
+func (s *BoardService) ListMembers(ctx context.Context, staffID, boardID uuid.UUID) ([]BoardMemberDTO, error) {
+	if _, err := requireStaff(ctx, s.users, staffID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getBoard(ctx, boardID); err != nil {
+		return nil, err
+	}
+	members, err := s.members.ListByBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BoardMemberDTO, 0, len(members))
+	for i := range members {
+		out = append(out, *toBoardMemberDTO(&members[i]))
 	}
 	return out, nil
 }
+
+func (s *BoardService) getMember(ctx context.Context, boardID, userID uuid.UUID) (*models.BoardMember, error) {
+	m, err := s.members.Get(ctx, boardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, NotFoundError("membership not found")
+	}
+	return m, nil
+}
+
+func (s *BoardService) getBoard(ctx context.Context, id uuid.UUID) (*models.Board, error) {
+	b, err := s.boards.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, NotFoundError("board not found")
+	}
+	return b, nil
+}
+
+func membersPublicReadError() *Error {
+	return FieldError("public_read", "members-only boards cannot be read publicly")
+}
+
+func boardNameConflict() *Error {
+	return ConflictError("board name already exists", map[string]string{"name": "already taken"})
+}
@@ -9,50 +9,110 @@ import (
 	"github.com/google/uuid"
 )
 
-type CommentService struct{}
+type CommentService struct {
+	comments models.CommentRepo
+	posts    models.PostRepo
+	users    models.UserRepo
+	access   *BoardAccess
+}
+
+// NewCommentService returns a CommentService backed by the given repositories.
+func NewCommentService(comments models.CommentRepo, posts models.PostRepo, users models.UserRepo, access *BoardAccess) *CommentService {
+	return &CommentService{comments: comments, posts: posts, users: users, access: access}
+}
 
 type CreateCommentInput struct {
-	PostID  string `json:"post_id"`
-	Content string `json:"content"`
+	PostID  string `json:"post_id" validate:"required,uuid"`
+	Content string `json:"content" validate:"required,max=2000"`
 }
 
 type CommentDTO struct {
-	ID       string `json:"id"`
-	PostID   string `json:"post_id"`
-	AuthorID string `json:"author_id"`
-	Content  string `json:"content"`
+	ID       string     `json:"id"`
+	PostID   string     `json:"post_id"`
+	AuthorID string     `json:"author_id"`
+	Author   *AuthorDTO `json:"author"`
+	Content  string     `json:"content"`
+}
+
+// toCommentDTOs converts comments, loading their authors in one lookup.
+func (s *CommentService) toCommentDTOs(ctx context.Context, comments []models.Comment, viewerID *uuid.UUID) ([]CommentDTO, error) {
+	authorIDs := make([]uuid.UUID, 0, len(comments))
+	for _, c := range comments {
+		authorIDs = append(authorIDs, c.AuthorID)
+	}
+	authors, err := authorSummaries(ctx, s.users, authorIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CommentDTO, 0, len(comments))
+	for _, c := range comments {
+		out = append(out, CommentDTO{
+			ID:       c.ID.String(),
+			PostID:   c.PostID.String(),
+			AuthorID: c.AuthorID.String(),
+			Author:   authors[c.AuthorID],
+			Content:  c.Content,
+		})
+	}
+	return out, nil
+}
+
+// getPost loads a post, returning NotFound when it does not exist or is
+// hidden by moderation.
+func getPost(ctx context.Context, posts models.PostRepo, id uuid.UUID) (*models.Post, error) {
+	post, err := posts.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil || post.HiddenAt != nil {
+		return nil, NotFoundError("post not found")
+	}
+	return post, nil
 }
 
 func (s *CommentService) Create(ctx context.Context, authorID uuid.UUID, in CreateCommentInput) (*CommentDTO, error) {
 	in.Content = strings.TrimSpace(in.Content)
-	if in.PostID == "" || in.Content == "" {
-		return nil, errors.New("post_id and content are required")
+	if err := validateInput(in); err != nil {
+		return nil, err
 	}
 	postUUID, err := uuid.Parse(in.PostID)
 	if err != nil {
-		return nil, errors.New("invalid post_id")
+		return nil, FieldError("post_id", "must be a valid UUID")
 	}
-	if err := models.EnsureCommentsTable(ctx); err != nil {
+	post, err := getPost(ctx, s.posts, postUUID)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := s.access.interact(ctx, post.BoardID, authorID); err != nil {
 		return nil, err
 	}
 	c := &models.Comment{PostID: postUUID, AuthorID: authorID, Content: in.Content}
-	if err := models.InsertComment(ctx, c); err != nil {
+	if err := s.comments.Insert(ctx, c); err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			return nil, NotFoundError("post not found")
+		}
 		return nil, err
 	}
-	return &CommentDTO{ID: c.ID.String(), PostID: c.PostID.String(), AuthorID: c.AuthorID.String(), Content: c.Content}, nil
+	out, err := s.toCommentDTOs(ctx, []models.Comment{*c}, &authorID)
+	if err != nil {
+		return nil, err
+	}
+	return &out[0], nil
 }
 
-func (s *CommentService) ListByPost(ctx context.Context, postID uuid.UUID) ([]CommentDTO, error) {
-	if err := models.EnsureCommentsTable(ctx); err != nil {
+// ListByPost lists a post's visible comments for viewerID, which is nil for
+// anonymous callers.
+func (s *CommentService) ListByPost(ctx context.Context, postID uuid.UUID, viewerID *uuid.UUID) ([]CommentDTO, error) {
+	post, err := getPost(ctx, s.posts, postID)
+	if err != nil {
 		return nil, err
 	}
-	comments, err := models.ListCommentsByPost(ctx, postID)
-	if err != nil {
+	if _, _, err := s.access.view(ctx, post.BoardID, viewerID); err != nil {
 		return nil, err
 	}
-	out := make([]CommentDTO, 0, len(comments))
-	for _, c := range comments {
-		out = append(out, CommentDTO{ID: c.ID.String(), PostID: c.PostID.String(), AuthorID: c.AuthorID.String(), Content: c.Content})
+	comments, err := s.comments.ListByPost(ctx, postID, viewerID)
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+	return s.toCommentDTOs(ctx, comments, viewerID)
 }
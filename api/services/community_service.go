@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// CommunityService resolves the community of a request and lets operators,
+// the admins of the default community, create communities and appoint their
+// admins. Everything else is administered within each community.
+type CommunityService struct {
+	communities models.CommunityRepo
+	users       models.UserRepo
+	accounts    *AccountService
+	invites     *InviteService
+	audit       *AuditService
+}
+
+// NewCommunityService returns a CommunityService backed by the given repositories.
+func NewCommunityService(communities models.CommunityRepo, users models.UserRepo, accounts *AccountService, invites *InviteService, audit *AuditService) *CommunityService {
+	return &CommunityService{communities: communities, users: users, accounts: accounts, invites: invites, audit: audit}
+}
+
+type CreateCommunityInput struct {
+	Slug string `json:"slug" validate:"required,slug"`
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type CommunityDTO struct {
+	ID        string    `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toCommunityDTO(c *models.Community) CommunityDTO {
+	return CommunityDTO{ID: c.ID.String(), Slug: c.Slug, Name: c.Name, CreatedAt: c.CreatedAt}
+}
+
+// ResolveCommunity returns the id of the community with the given slug.
+func (s *CommunityService) ResolveCommunity(ctx context.Context, slug string) (uuid.UUID, error) {
+	c, err := s.communities.GetBySlug(ctx, strings.ToLower(slug))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if c == nil {
+		return uuid.Nil, NotFoundError("community not found")
+	}
+	return c.ID, nil
+}
+
+// Current returns the community the request is scoped to.
+func (s *CommunityService) Current(ctx context.Context) (*CommunityDTO, error) {
+	id, _ := models.CommunityScope(ctx)
+	c, err := s.communities.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, NotFoundError("community not found")
+	}
+	out := toCommunityDTO(c)
+	return &out, nil
+}
+
+// List returns every community to an operator.
+func (s *CommunityService) List(ctx context.Context, actorID uuid.UUID) ([]CommunityDTO, error) {
+	if err := s.requireOperator(ctx, actorID); err != nil {
+		return nil, err
+	}
+	list, err := s.communities.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CommunityDTO, 0, len(list))
+	for i := range list {
+		out = append(out, toCommunityDTO(&list[i]))
+	}
+	return out, nil
+}
+
+// Create adds a community. Its first resident registers with an invite from
+// CreateInvite and is then made its admin with GrantAdmin.
+func (s *CommunityService) Create(ctx context.Context, actorID uuid.UUID, in CreateCommunityInput) (*CommunityDTO, error) {
+	in.Slug = strings.TrimSpace(strings.ToLower(in.Slug))
+	in.Name = strings.TrimSpace(in.Name)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if err := s.requireOperator(ctx, actorID); err != nil {
+		return nil, err
+	}
+	c := &models.Community{Slug: in.Slug, Name: in.Name}
+	if err := s.communities.Insert(ctx, c); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, ConflictError("community already exists", map[string]string{"slug": "already taken"})
+		}
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, &actorID, auditCommunityCreate, "community", &c.ID, map[string]string{"slug": c.Slug}); err != nil {
+		return nil, err
+	}
+	utils.Infof("community created slug=%s id=%s by=%s", c.Slug, c.ID, actorID)
+	out := toCommunityDTO(c)
+	return &out, nil
+}
+
+// GrantAdmin makes a resident of another community that community's admin.
+// The change is audited in the resident's community so its admins see it.
+func (s *CommunityService) GrantAdmin(ctx context.Context, actorID, communityID, userID uuid.UUID) (*AccountDTO, error) {
+	if err := s.requireOperator(ctx, actorID); err != nil {
+		return nil, err
+	}
+	c, err := s.communities.GetByID(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, NotFoundError("community not found")
+	}
+	target := models.WithCommunity(ctx, c.ID)
+	u, err := s.users.GetByID(target, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	if u.IsAdmin {
+		return toAccountDTO(u), nil
+	}
+	u.IsAdmin = true
+	if err := s.users.SetRoles(target, u.ID, u.IsAdmin, u.IsModerator); err != nil {
+		return nil, err
+	}
+	s.accounts.invalidate(u.ID)
+	if err := s.audit.Record(target, &actorID, auditCommunityAdminGrant, "user", &u.ID, map[string]string{"community": c.Slug}); err != nil {
+		return nil, err
+	}
+	utils.Infof("community admin granted community=%s id=%s by=%s", c.Slug, u.ID, actorID)
+	return toAccountDTO(u), nil
+}
+
+// CreateInvite issues an invite into another community, so that a new
+// community's first resident can register before it has an admin.
+func (s *CommunityService) CreateInvite(ctx context.Context, actorID, communityID uuid.UUID, in CreateInviteInput) (*InviteDTO, error) {
+	if err := s.requireOperator(ctx, actorID); err != nil {
+		return nil, err
+	}
+	c, err := s.communities.GetByID(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, NotFoundError("community not found")
+	}
+	return s.invites.create(models.WithCommunity(ctx, c.ID), actorID, in, nil)
+}
+
+func (s *CommunityService) requireOperator(ctx context.Context, id uuid.UUID) error {
+	return requireOperator(ctx, s.users, id)
+}
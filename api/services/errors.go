@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorCode is a stable, machine-readable error category returned to clients.
+type ErrorCode string
+
+const (
+	CodeValidation   ErrorCode = "validation_failed"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	CodeForbidden    ErrorCode = "forbidden"
+	CodeNotFound     ErrorCode = "not_found"
+	CodeConflict     ErrorCode = "conflict"
+	CodeRateLimited  ErrorCode = "rate_limited"
+	CodeInternal     ErrorCode = "internal"
+)
+
+// Error is a domain error safe to show to clients. Anything that is not an
+// *Error is treated as internal and never exposed verbatim.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	// Details maps request fields (by JSON name) to what is wrong with them.
+	Details map[string]string
+	// RetryAfter, when set, tells the client how long to back off.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if len(e.Details) == 0 {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	keys := make([]string, 0, len(e.Details))
+	for k := range e.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+e.Details[k])
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.Code, e.Message, strings.Join(parts, "; "))
+}
+
+// ValidationError reports invalid input; details maps fields to problems and may be nil.
+func ValidationError(message string, details map[string]string) *Error {
+	return &Error{Code: CodeValidation, Message: message, Details: details}
+}
+
+// FieldError reports a single invalid field.
+func FieldError(field, problem string) *Error {
+	return ValidationError("invalid request", map[string]string{field: problem})
+}
+
+// UnauthorizedError reports missing or invalid credentials.
+func UnauthorizedError(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+// ForbiddenError reports an authenticated caller lacking permission.
+func ForbiddenError(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+// RateLimitedError reports a caller that must wait retryAfter before trying again.
+func RateLimitedError(message string, retryAfter time.Duration) *Error {
+	return &Error{Code: CodeRateLimited, Message: message, RetryAfter: retryAfter}
+}
+
+// NotFoundError reports a missing resource.
+func NotFoundError(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+// ConflictError reports a clash with existing state; details may name the conflicting field.
+func ConflictError(message string, details map[string]string) *Error {
+	return &Error{Code: CodeConflict, Message: message, Details: details}
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// ExportService builds ZIP archives of everything stored about a resident.
+// Archives are built in the background and kept for a limited time.
+type ExportService struct {
+	exports       models.DataExportRepo
+	posts         models.PostRepo
+	comments      models.CommentRepo
+	reactions     models.ReactionRepo
+	subscriptions models.SubscriptionRepo
+	members       models.BoardMemberRepo
+	actions       models.AccountActionRepo
+	profiles      *ProfileService
+	// ttl is how long a finished archive can be downloaded; timeout is how
+	// long a build may stay pending before it is presumed lost.
+	ttl     time.Duration
+	timeout time.Duration
+}
+
+// exportTimedOut is recorded on exports that stayed pending past the timeout.
+const exportTimedOut = "preparing your data took too long; please try again"
+
+// NewExportService returns an ExportService backed by the given repositories.
+func NewExportService(exports models.DataExportRepo, posts models.PostRepo, comments models.CommentRepo, reactions models.ReactionRepo, subscriptions models.SubscriptionRepo, members models.BoardMemberRepo, actions models.AccountActionRepo, profiles *ProfileService, ttl, timeout time.Duration) *ExportService {
+	return &ExportService{
+		exports:       exports,
+		posts:         posts,
+		comments:      comments,
+		reactions:     reactions,
+		subscriptions: subscriptions,
+		members:       members,
+		actions:       actions,
+		profiles:      profiles,
+		ttl:           ttl,
+		timeout:       timeout,
+	}
+}
+
+type DataExportDTO struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	// DownloadedAt is when the archive was first downloaded.
+	DownloadedAt *time.Time `json:"downloaded_at"`
+	// DownloadURL is set while a ready archive can still be downloaded.
+	DownloadURL *string `json:"download_url"`
+}
+
+func toDataExportDTO(e *models.DataExport, now time.Time) DataExportDTO {
+	dto := DataExportDTO{
+		ID:           e.ID.String(),
+		Status:       e.Status,
+		Error:        e.Error,
+		CreatedAt:    e.CreatedAt,
+		CompletedAt:  e.CompletedAt,
+		ExpiresAt:    e.ExpiresAt,
+		DownloadedAt: e.DownloadedAt,
+	}
+	if exportDownloadable(e, now) {
+		url := "/api/profile/me/exports/" + dto.ID + "/download"
+		dto.DownloadURL = &url
+	}
+	return dto
+}
+
+func exportInProgress() *Error {
+	return ConflictError("an export is already being prepared", nil)
+}
+
+func exportDownloadable(e *models.DataExport, now time.Time) bool {
+	return e.Status == models.ExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
+
+// Request starts building a new export for the user. Only one export may be
+// in progress at a time, which the database enforces for concurrent
+// requests; one pending past the timeout is marked failed so a lost build
+// cannot block the resident for good.
+func (s *ExportService) Request(ctx context.Context, userID uuid.UUID) (*DataExportDTO, error) {
+	existing, err := s.exports.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stale := time.Now().Add(-s.timeout)
+	for _, e := range existing {
+		if e.Status != models.ExportPending {
+			continue
+		}
+		if e.CreatedAt.After(stale) {
+			return nil, exportInProgress()
+		}
+		if err := s.exports.Fail(ctx, e.ID, exportTimedOut); err != nil {
+			return nil, err
+		}
+		utils.Infof("data export timed out id=%s user=%s", e.ID, userID)
+	}
+	e := &models.DataExport{UserID: userID}
+	if err := s.exports.Insert(ctx, e); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, exportInProgress()
+		}
+		if errors.Is(err, models.ErrInvalidReference) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	// The request context ends with the response; the build must outlive it.
+	go s.build(context.WithoutCancel(ctx), e.ID, userID)
+	dto := toDataExportDTO(e, time.Now())
+	return &dto, nil
+}
+
+// List returns the user's exports, newest first.
+func (s *ExportService) List(ctx context.Context, userID uuid.UUID) ([]DataExportDTO, error) {
+	exports, err := s.exports.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make([]DataExportDTO, 0, len(exports))
+	for i := range exports {
+		out = append(out, toDataExportDTO(&exports[i], now))
+	}
+	return out, nil
+}
+
+// Get returns one of the user's exports.
+func (s *ExportService) Get(ctx context.Context, userID, exportID uuid.UUID) (*DataExportDTO, error) {
+	e, err := s.load(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	dto := toDataExportDTO(e, time.Now())
+	return &dto, nil
+}
+
+// Download returns the ZIP archive of a ready export and a file name for it.
+func (s *ExportService) Download(ctx context.Context, userID, exportID uuid.UUID) ([]byte, string, error) {
+	e, err := s.load(ctx, userID, exportID)
+	if err != nil {
+		return nil, "", err
+	}
+	if e.Status != models.ExportReady {
+		return nil, "", ConflictError("export is not ready", map[string]string{"status": e.Status})
+	}
+	if !exportDownloadable(e, time.Now()) {
+		return nil, "", NotFoundError("export has expired")
+	}
+	archive, err := s.exports.Archive(ctx, exportID)
+	if err != nil {
+		return nil, "", err
+	}
+	if archive == nil {
+		return nil, "", NotFoundError("export has expired")
+	}
+	if err := s.exports.MarkDownloaded(ctx, exportID); err != nil {
+		return nil, "", err
+	}
+	return archive, "culdechat-export-" + e.CreatedAt.UTC().Format(dateLayout) + ".zip", nil
+}
+
+// ReadyCount returns how many of the user's exports are ready to download
+// but have not been downloaded yet; it is how residents learn a build finished.
+func (s *ExportService) ReadyCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	exports, err := s.exports.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	n := 0
+	for i := range exports {
+		if exportDownloadable(&exports[i], now) && exports[i].DownloadedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// load fetches an export, hiding other residents' exports behind NotFound.
+func (s *ExportService) load(ctx context.Context, userID, exportID uuid.UUID) (*models.DataExport, error) {
+	e, err := s.exports.GetByID(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || e.UserID != userID {
+		return nil, NotFoundError("export not found")
+	}
+	return e, nil
+}
+
+// build gathers the user's data into an archive and stores it. The resident
+// learns the outcome from GET /api/auth/me or by checking the export.
+func (s *ExportService) build(ctx context.Context, exportID, userID uuid.UUID) {
+	archive, err := s.archive(ctx, userID)
+	if err != nil {
+		utils.Errorf("data export failed id=%s user=%s: %v", exportID, userID, err)
+		if err := s.exports.Fail(ctx, exportID, "could not gather your data; please try again"); err != nil {
+			utils.Errorf("record data export failure id=%s: %v", exportID, err)
+		}
+		return
+	}
+	if err := s.exports.Complete(ctx, exportID, archive, time.Now().Add(s.ttl)); err != nil {
+		utils.Errorf("store data export id=%s: %v", exportID, err)
+		return
+	}
+	utils.Infof("data export ready id=%s user=%s bytes=%d", exportID, userID, len(archive))
+}
+
+type exportedPost struct {
+	ID         string    `json:"id"`
+	BoardID    string    `json:"board_id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	IsBulletin bool      `json:"is_bulletin"`
+	Hidden     bool      `json:"hidden"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type exportedComment struct {
+	ID        string    `json:"id"`
+	PostID    string    `json:"post_id"`
+	Content   string    `json:"content"`
+	Hidden    bool      `json:"hidden"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportedReaction struct {
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	Type       string    `json:"type"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type exportedBoard struct {
+	BoardID   string    `json:"board_id"`
+	Level     string    `json:"level,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// archive collects every record about the user into a ZIP of JSON files.
+func (s *ExportService) archive(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	files := map[string]any{}
+
+	profile, err := s.profiles.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	files["profile.json"] = profile
+
+	posts, err := s.posts.ListByAuthor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedPosts := make([]exportedPost, 0, len(posts))
+	for _, p := range posts {
+		exportedPosts = append(exportedPosts, exportedPost{
+			ID: p.ID.String(), BoardID: p.BoardID.String(), Title: p.Title, Content: p.Content,
+			IsBulletin: p.IsBulletin, Hidden: p.HiddenAt != nil, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt,
+		})
+	}
+	files["posts.json"] = exportedPosts
+
+	comments, err := s.comments.ListByAuthor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedComments := make([]exportedComment, 0, len(comments))
+	for _, c := range comments {
+		exportedComments = append(exportedComments, exportedComment{
+			ID: c.ID.String(), PostID: c.PostID.String(), Content: c.Content,
+			Hidden: c.HiddenAt != nil, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt,
+		})
+	}
+	files["comments.json"] = exportedComments
+
+	reactions, err := s.reactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedReactions := make([]exportedReaction, 0, len(reactions))
+	for _, rx := range reactions {
+		exportedReactions = append(exportedReactions, exportedReaction{
+			TargetType: rx.TargetType, TargetID: rx.TargetID.String(), Type: rx.Type, CreatedAt: rx.CreatedAt,
+		})
+	}
+	files["reactions.json"] = exportedReactions
+
+	subscriptions, err := s.subscriptions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedSubscriptions := make([]exportedBoard, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		exportedSubscriptions = append(exportedSubscriptions, exportedBoard{BoardID: sub.BoardID.String(), Level: sub.Level, CreatedAt: sub.CreatedAt})
+	}
+	files["subscriptions.json"] = exportedSubscriptions
+
+	memberships, err := s.members.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedMemberships := make([]exportedBoard, 0, len(memberships))
+	for _, m := range memberships {
+		exportedMemberships = append(exportedMemberships, exportedBoard{BoardID: m.BoardID.String(), Status: m.Status, CreatedAt: m.CreatedAt})
+	}
+	files["board_memberships.json"] = exportedMemberships
+
+	if files["blocks.json"], err = s.profiles.Blocks(ctx, userID); err != nil {
+		return nil, err
+	}
+	if files["warnings.json"], err = s.profiles.Warnings(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	actions, err := s.actions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]AccountActionDTO, 0, len(actions))
+	for _, a := range actions {
+		history = append(history, toAccountActionDTO(a))
+	}
+	files["account_history.json"] = history
+
+	return zipJSON(files)
+}
+
+// zipJSON writes each value as an indented JSON file, in name order.
+func zipJSON(files map[string]any) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(files[name]); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// householdInviteTTL is how long a co-resident invite lasts when the
+// resident does not pick an expiry.
+const householdInviteTTL = 7 * 24 * time.Hour
+
+// HouseholdService links the residents who share a unit. Residents start a
+// household for their unit, invite co-residents and choose whether the
+// household shows in the directory; admins move whole households out.
+type HouseholdService struct {
+	households models.HouseholdRepo
+	users      models.UserRepo
+	units      models.UnitRepo
+	invites    *InviteService
+	accounts   *AccountService
+	audit      *AuditService
+}
+
+// NewHouseholdService returns a HouseholdService backed by the given repositories.
+func NewHouseholdService(households models.HouseholdRepo, users models.UserRepo, units models.UnitRepo, invites *InviteService, accounts *AccountService, audit *AuditService) *HouseholdService {
+	return &HouseholdService{households: households, users: users, units: units, invites: invites, accounts: accounts, audit: audit}
+}
+
+// CreateHouseholdInput starts a household for the caller's unit.
+type CreateHouseholdInput struct {
+	Name           *string `json:"name" validate:"omitempty,max=64"`
+	DirectoryOptIn bool    `json:"directory_opt_in"`
+}
+
+// UpdateHouseholdInput renames a household or changes its directory opt-in;
+// omitted fields are unchanged and an empty name clears it.
+type UpdateHouseholdInput struct {
+	Name           *string `json:"name" validate:"omitempty,max=64"`
+	DirectoryOptIn *bool   `json:"directory_opt_in"`
+}
+
+// HouseholdInviteInput invites co-residents. MaxUses defaults to a single
+// use and ExpiresAt to a week from now.
+type HouseholdInviteInput struct {
+	MaxUses   int        `json:"max_uses" validate:"omitempty,min=1,max=10"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// JoinHouseholdInput joins a household with a co-resident's invite code.
+type JoinHouseholdInput struct {
+	InviteCode string `json:"invite_code" validate:"required,max=32"`
+}
+
+// HouseholdDTO is the caller's household as its members see it.
+type HouseholdDTO struct {
+	ID             string               `json:"id"`
+	UnitNumber     string               `json:"unit_number"`
+	Name           *string              `json:"name"`
+	DirectoryOptIn bool                 `json:"directory_opt_in"`
+	Members        []HouseholdMemberDTO `json:"members"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+type HouseholdMemberDTO struct {
+	ID          string  `json:"id"`
+	DisplayName *string `json:"display_name"`
+}
+
+// AdminHouseholdDTO is a household as admins see it, with each member's account.
+type AdminHouseholdDTO struct {
+	ID             string       `json:"id"`
+	UnitNumber     string       `json:"unit_number"`
+	UnitID         *string      `json:"unit_id"`
+	Name           *string      `json:"name"`
+	DirectoryOptIn bool         `json:"directory_opt_in"`
+	Members        []AccountDTO `json:"members"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// MoveOutDTO reports the accounts a move-out closed.
+type MoveOutDTO struct {
+	HouseholdID string       `json:"household_id"`
+	UnitNumber  string       `json:"unit_number"`
+	UnitVacated bool         `json:"unit_vacated"`
+	Members     []AccountDTO `json:"members"`
+}
+
+func toHouseholdDTO(h *models.Household, members []models.User) *HouseholdDTO {
+	out := &HouseholdDTO{
+		ID:             h.ID.String(),
+		UnitNumber:     h.UnitNumber,
+		Name:           h.Name,
+		DirectoryOptIn: h.DirectoryOptIn,
+		Members:        make([]HouseholdMemberDTO, 0, len(members)),
+		CreatedAt:      h.CreatedAt,
+	}
+	for _, m := range members {
+		out.Members = append(out.Members, HouseholdMemberDTO{ID: m.ID.String(), DisplayName: m.DisplayName})
+	}
+	return out
+}
+
+// Mine returns the caller's household.
+func (s *HouseholdService) Mine(ctx context.Context, userID uuid.UUID) (*HouseholdDTO, error) {
+	_, h, err := s.member(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(ctx, h)
+}
+
+// Create starts a household for the caller's unit with the caller as its
+// first member. Each unit has at most one household; residents of a unit
+// that already has one join it with an invite from a member. Where the
+// community keeps a unit registry, only verified residents may start one, so
+// nobody can claim a unit that is not theirs.
+func (s *HouseholdService) Create(ctx context.Context, userID uuid.UUID, in CreateHouseholdInput) (*HouseholdDTO, error) {
+	in.Name = trimToNil(in.Name)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.HouseholdID != nil {
+		return nil, ConflictError("you are already in a household", nil)
+	}
+	if u.Verification != models.VerificationVerified {
+		n, err := s.units.Count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, ForbiddenError("your unit must be verified before you can start a household")
+		}
+	}
+	h := &models.Household{UnitNumber: u.UnitNumber, UnitID: u.UnitID, Name: in.Name, DirectoryOptIn: in.DirectoryOptIn}
+	if err := s.households.Insert(ctx, h); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, ConflictError("your unit already has a household; ask a member for an invite", map[string]string{"unit_number": "already has a household"})
+		}
+		return nil, err
+	}
+	u.HouseholdID = &h.ID
+	if err := s.users.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	utils.Infof("household created id=%s unit=%s by=%s", h.ID, h.UnitNumber, userID)
+	return s.view(ctx, h)
+}
+
+// Update renames the caller's household or changes whether it shows in the directory.
+func (s *HouseholdService) Update(ctx context.Context, userID uuid.UUID, in UpdateHouseholdInput) (*HouseholdDTO, error) {
+	in.Name = trimOptional(in.Name)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	_, h, err := s.member(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Name != nil {
+		h.Name = trimToNil(in.Name)
+	}
+	if in.DirectoryOptIn != nil {
+		h.DirectoryOptIn = *in.DirectoryOptIn
+	}
+	if err := s.households.Update(ctx, h); err != nil {
+		return nil, err
+	}
+	return s.view(ctx, h)
+}
+
+// Leave removes the caller from their household. The last member to leave
+// dissolves it, along with its outstanding invites.
+func (s *HouseholdService) Leave(ctx context.Context, userID uuid.UUID) error {
+	u, h, err := s.member(ctx, userID)
+	if err != nil {
+		return err
+	}
+	u.HouseholdID = nil
+	if err := s.users.Update(ctx, u); err != nil {
+		return err
+	}
+	remaining, err := s.users.ListByHousehold(ctx, h.ID)
+	if err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		if err := s.households.Delete(ctx, h.ID); err != nil {
+			return err
+		}
+		utils.Infof("household dissolved id=%s unit=%s", h.ID, h.UnitNumber)
+	}
+	return nil
+}
+
+// Invite issues an invite for co-residents of the caller's unit. New
+// residents who register with it join the household; residents who already
+// have an account redeem it through Join.
+func (s *HouseholdService) Invite(ctx context.Context, userID uuid.UUID, in HouseholdInviteInput) (*InviteDTO, error) {
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	_, h, err := s.member(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if in.ExpiresAt == nil {
+		expires := time.Now().UTC().Add(householdInviteTTL)
+		in.ExpiresAt = &expires
+	}
+	unit := h.UnitNumber
+	return s.invites.create(ctx, userID, CreateInviteInput{MaxUses: in.MaxUses, UnitNumber: &unit, ExpiresAt: in.ExpiresAt}, &h.ID)
+}
+
+// Join adds the caller to the household a co-resident invited them to. The
+// invite must be for the caller's unit.
+func (s *HouseholdService) Join(ctx context.Context, userID uuid.UUID, in JoinHouseholdInput) (*HouseholdDTO, error) {
+	in.InviteCode = strings.TrimSpace(in.InviteCode)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.HouseholdID != nil {
+		return nil, ConflictError("you are already in a household", nil)
+	}
+	inv, err := s.invites.check(ctx, in.InviteCode, u.UnitNumber)
+	if err != nil {
+		return nil, err
+	}
+	// Invites from other communities are reported like unknown codes.
+	if inv.CommunityID != u.CommunityID {
+		return nil, FieldError("invite_code", "is invalid or expired")
+	}
+	if inv.HouseholdID == nil {
+		return nil, FieldError("invite_code", "is not a household invite")
+	}
+	h, err := s.households.GetByID(ctx, *inv.HouseholdID)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, FieldError("invite_code", "is invalid or expired")
+	}
+	if err := s.invites.consume(ctx, inv); err != nil {
+		return nil, err
+	}
+	u.HouseholdID = &h.ID
+	if err := s.users.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	utils.Infof("household joined id=%s user=%s", h.ID, u.ID)
+	return s.view(ctx, h)
+}
+
+// List returns the community's households with their members' accounts, for admins.
+func (s *HouseholdService) List(ctx context.Context, actorID uuid.UUID) ([]AdminHouseholdDTO, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	list, err := s.households.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AdminHouseholdDTO, 0, len(list))
+	for _, h := range list {
+		members, err := s.users.ListByHousehold(ctx, h.ID)
+		if err != nil {
+			return nil, err
+		}
+		dto := AdminHouseholdDTO{
+			ID:             h.ID.String(),
+			UnitNumber:     h.UnitNumber,
+			UnitID:         uuidString(h.UnitID),
+			Name:           h.Name,
+			DirectoryOptIn: h.DirectoryOptIn,
+			Members:        make([]AccountDTO, 0, len(members)),
+			CreatedAt:      h.CreatedAt,
+		}
+		for i := range members {
+			dto.Members = append(dto.Members, *toAccountDTO(&members[i]))
+		}
+		out = append(out, dto)
+	}
+	return out, nil
+}
+
+// MoveOut closes the accounts of everyone in a household when they leave the
+// property, marks the unit vacant in the registry and dissolves the
+// household. Members who are banned or waiting out their own deletion keep
+// their status and are only unlinked. Households with an admin, including
+// the caller's own, cannot be moved out.
+func (s *HouseholdService) MoveOut(ctx context.Context, actorID, id uuid.UUID, in AccountActionInput) (*MoveOutDTO, error) {
+	in.Reason = strings.TrimSpace(in.Reason)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	h, err := s.households.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, NotFoundError("household not found")
+	}
+	members, err := s.users.ListByHousehold(ctx, h.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ID == actorID {
+			return nil, ForbiddenError("you cannot move out your own household")
+		}
+		if m.IsAdmin {
+			return nil, ForbiddenError("admins must give up their admin role before their household moves out")
+		}
+	}
+
+	out := &MoveOutDTO{HouseholdID: h.ID.String(), UnitNumber: h.UnitNumber, Members: make([]AccountDTO, 0, len(members))}
+	for i := range members {
+		m := &members[i]
+		if m.Status != models.UserBanned && m.Status != models.UserInactive {
+			if err := s.accounts.setStatus(ctx, &actorID, m, actionMoveOut, in.Reason, nil); err != nil {
+				return nil, err
+			}
+		}
+		out.Members = append(out.Members, *toAccountDTO(m))
+	}
+	if h.UnitID != nil {
+		if err := s.units.SetOccupancy(ctx, *h.UnitID, models.OccupancyVacant); err != nil {
+			return nil, err
+		}
+		out.UnitVacated = true
+	}
+	if err := s.households.Delete(ctx, h.ID); err != nil {
+		return nil, err
+	}
+	details := map[string]string{"unit_number": h.UnitNumber, "reason": in.Reason, "members": strconv.Itoa(len(members))}
+	if err := s.audit.Record(ctx, &actorID, auditHouseholdMoveOut, "household", &h.ID, details); err != nil {
+		return nil, err
+	}
+	utils.Infof("household moved out id=%s unit=%s members=%d by=%s", h.ID, h.UnitNumber, len(members), actorID)
+	return out, nil
+}
+
+// member loads the caller and their household.
+func (s *HouseholdService) member(ctx context.Context, userID uuid.UUID) (*models.User, *models.Household, error) {
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.HouseholdID == nil {
+		return nil, nil, NotFoundError("you are not in a household")
+	}
+	h, err := s.households.GetByID(ctx, *u.HouseholdID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h == nil {
+		return nil, nil, NotFoundError("you are not in a household")
+	}
+	return u, h, nil
+}
+
+func (s *HouseholdService) view(ctx context.Context, h *models.Household) (*HouseholdDTO, error) {
+	members, err := s.users.ListByHousehold(ctx, h.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toHouseholdDTO(h, members), nil
+}
+
+func (s *HouseholdService) loadUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	u, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
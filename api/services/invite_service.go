@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Invite codes avoid characters that are easy to misread on a printed notice.
+const (
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 10
+)
+
+// defaultInviteQRSize is the QR code width in pixels when none is requested.
+const defaultInviteQRSize = 512
+
+// InviteService lets admins issue the invite codes residents register with.
+type InviteService struct {
+	invites  models.InviteRepo
+	users    models.UserRepo
+	audit    *AuditService
+	linkBase string
+}
+
+// NewInviteService returns an InviteService whose QR codes encode linkBase
+// followed by the invite code.
+func NewInviteService(invites models.InviteRepo, users models.UserRepo, audit *AuditService, cfg config.RegistrationConfig) *InviteService {
+	return &InviteService{invites: invites, users: users, audit: audit, linkBase: cfg.InviteLinkBase}
+}
+
+// CreateInviteInput issues an invite. MaxUses defaults to a single use; a
+// UnitNumber restricts the invite to residents of that unit.
+type CreateInviteInput struct {
+	MaxUses    int        `json:"max_uses" validate:"omitempty,min=1,max=1000"`
+	UnitNumber *string    `json:"unit_number" validate:"omitempty,max=16"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// InviteQRInput sizes an invite's QR code.
+type InviteQRInput struct {
+	Size int `form:"size" validate:"omitempty,min=128,max=1024"`
+}
+
+// InviteDTO is an invite. QRURL is only set for admin invites; HouseholdID
+// is set for the invites residents send to co-residents.
+type InviteDTO struct {
+	ID          string     `json:"id"`
+	Code        string     `json:"code"`
+	Link        string     `json:"link"`
+	QRURL       string     `json:"qr_url,omitempty"`
+	UnitNumber  *string    `json:"unit_number"`
+	HouseholdID *string    `json:"household_id"`
+	MaxUses     int        `json:"max_uses"`
+	Uses        int        `json:"uses"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (s *InviteService) toDTO(i *models.Invite) InviteDTO {
+	out := InviteDTO{
+		ID:          i.ID.String(),
+		Code:        i.Code,
+		Link:        s.linkBase + i.Code,
+		UnitNumber:  i.UnitNumber,
+		HouseholdID: uuidString(i.HouseholdID),
+		MaxUses:     i.MaxUses,
+		Uses:        i.Uses,
+		Status:      inviteStatus(i, time.Now()),
+		ExpiresAt:   i.ExpiresAt,
+		RevokedAt:   i.RevokedAt,
+		CreatedAt:   i.CreatedAt,
+	}
+	if i.HouseholdID == nil {
+		out.QRURL = "/api/admin/invites/" + i.ID.String() + "/qr"
+	}
+	return out
+}
+
+// inviteStatus is active, revoked, used_up or expired.
+func inviteStatus(i *models.Invite, now time.Time) string {
+	switch {
+	case i.RevokedAt != nil:
+		return "revoked"
+	case i.Uses >= i.MaxUses:
+		return "used_up"
+	case i.ExpiresAt != nil && !now.Before(*i.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// Create issues an invite in the admin's community.
+func (s *InviteService) Create(ctx context.Context, actorID uuid.UUID, in CreateInviteInput) (*InviteDTO, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, actorID, in, nil)
+}
+
+// create issues an invite in ctx's community without checking the actor's
+// role. Redeeming an invite with a householdID joins that household.
+func (s *InviteService) create(ctx context.Context, actorID uuid.UUID, in CreateInviteInput, householdID *uuid.UUID) (*InviteDTO, error) {
+	in.UnitNumber = trimToNil(in.UnitNumber)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if in.MaxUses == 0 {
+		in.MaxUses = 1
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		return nil, FieldError("expires_at", "must be in the future")
+	}
+	inv := &models.Invite{UnitNumber: in.UnitNumber, MaxUses: in.MaxUses, ExpiresAt: in.ExpiresAt, CreatedBy: &actorID, HouseholdID: householdID}
+	// Codes are random, so a collision is rare; retry a few times before giving up.
+	for attempt := 0; ; attempt++ {
+		code, err := newInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		inv.ID, inv.Code = uuid.Nil, code
+		err = s.invites.Insert(ctx, inv)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, models.ErrConflict) || attempt == 2 {
+			return nil, err
+		}
+	}
+	details := map[string]string{"max_uses": strconv.Itoa(inv.MaxUses)}
+	if inv.UnitNumber != nil {
+		details["unit_number"] = *inv.UnitNumber
+	}
+	if householdID != nil {
+		details["household_id"] = householdID.String()
+	}
+	if err := s.audit.Record(ctx, &actorID, auditInviteCreate, "invite", &inv.ID, details); err != nil {
+		return nil, err
+	}
+	utils.Infof("invite created id=%s community=%s by=%s", inv.ID, inv.CommunityID, actorID)
+	out := s.toDTO(inv)
+	return &out, nil
+}
+
+// List returns the community's invites, newest first.
+func (s *InviteService) List(ctx context.Context, actorID uuid.UUID) ([]InviteDTO, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	list, err := s.invites.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]InviteDTO, 0, len(list))
+	for i := range list {
+		out = append(out, s.toDTO(&list[i]))
+	}
+	return out, nil
+}
+
+// Revoke stops an invite from being used. Revoking it again is a no-op.
+func (s *InviteService) Revoke(ctx context.Context, actorID, id uuid.UUID) error {
+	if _, err := s.get(ctx, actorID, id); err != nil {
+		return err
+	}
+	revoked, err := s.invites.Revoke(ctx, id)
+	if err != nil || !revoked {
+		return err
+	}
+	if err := s.audit.Record(ctx, &actorID, auditInviteRevoke, "invite", &id, nil); err != nil {
+		return err
+	}
+	utils.Infof("invite revoked id=%s by=%s", id, actorID)
+	return nil
+}
+
+// QR renders the invite's link as a PNG QR code for posting in the building.
+// It also returns the invite code so callers can name the file.
+func (s *InviteService) QR(ctx context.Context, actorID, id uuid.UUID, in InviteQRInput) ([]byte, string, error) {
+	if err := validateInput(in); err != nil {
+		return nil, "", err
+	}
+	if in.Size == 0 {
+		in.Size = defaultInviteQRSize
+	}
+	inv, err := s.get(ctx, actorID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	png, err := qrcode.Encode(s.linkBase+inv.Code, qrcode.Medium, in.Size)
+	if err != nil {
+		return nil, "", err
+	}
+	return png, inv.Code, nil
+}
+
+func (s *InviteService) get(ctx context.Context, actorID, id uuid.UUID) (*models.Invite, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	inv, err := s.invites.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil {
+		return nil, NotFoundError("invite not found")
+	}
+	return inv, nil
+}
+
+// check finds a usable invite for a resident of unit registering with code.
+// Codes are unique across communities, so the invite also picks the community.
+func (s *InviteService) check(ctx context.Context, code, unit string) (*models.Invite, error) {
+	code = normalizeInviteCode(code)
+	if code == "" {
+		return nil, FieldError("invite_code", "is required")
+	}
+	inv, err := s.invites.GetByCode(models.AcrossCommunities(ctx), code)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || !inv.Usable(time.Now()) {
+		return nil, FieldError("invite_code", "is invalid or expired")
+	}
+	if inv.UnitNumber != nil && !strings.EqualFold(*inv.UnitNumber, unit) {
+		return nil, FieldError("unit_number", "does not match the invite")
+	}
+	return inv, nil
+}
+
+// consume uses up one use of an invite returned by check. It fails if the
+// invite ran out or was revoked in the meantime.
+func (s *InviteService) consume(ctx context.Context, inv *models.Invite) error {
+	ok, err := s.invites.Redeem(models.WithCommunity(ctx, inv.CommunityID), inv.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return FieldError("invite_code", "is invalid or expired")
+	}
+	return nil
+}
+
+// normalizeInviteCode accepts codes typed in lowercase or with spaces and dashes.
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+// newInviteCode returns a random code drawn from inviteCodeAlphabet.
+func newInviteCode() (string, error) {
+	buf := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	// The alphabet has 32 characters, so each byte maps onto it without bias.
+	for i, b := range buf {
+		buf[i] = inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)]
+	}
+	return string(buf), nil
+}
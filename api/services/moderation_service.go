@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// Report resolutions, keyed by the moderator action that produces them.
+var resolutionByAction = map[string]string{
+	"dismiss": "dismissed",
+	"hide":    "hidden",
+	"warn":    "warned",
+	"suspend": "suspended",
+}
+
+// ModerationService handles resident reports and the moderator review queue.
+type ModerationService struct {
+	reports  models.ReportRepo
+	posts    models.PostRepo
+	comments models.CommentRepo
+	users    models.UserRepo
+	warnings models.WarningRepo
+	accounts *AccountService
+	audit    *AuditService
+	cfg      config.ModerationConfig
+}
+
+// NewModerationService returns a ModerationService backed by the given repositories.
+func NewModerationService(reports models.ReportRepo, posts models.PostRepo, comments models.CommentRepo, users models.UserRepo, warnings models.WarningRepo, accounts *AccountService, audit *AuditService, cfg config.ModerationConfig) *ModerationService {
+	return &ModerationService{reports: reports, posts: posts, comments: comments, users: users, warnings: warnings, accounts: accounts, audit: audit, cfg: cfg}
+}
+
+type CreateReportInput struct {
+	TargetType string  `json:"target_type" validate:"required,oneof=post comment profile"`
+	TargetID   string  `json:"target_id" validate:"required,uuid"`
+	Reason     string  `json:"reason" validate:"required,oneof=harassment spam inappropriate other"`
+	Details    *string `json:"details" validate:"omitempty,max=1000"`
+}
+
+// ReportQueueInput filters the moderation queue. Assignee is "me" or "unassigned".
+type ReportQueueInput struct {
+	Status     string `form:"status" json:"status" validate:"omitempty,oneof=open claimed resolved"`
+	TargetType string `form:"target_type" json:"target_type" validate:"omitempty,oneof=post comment profile"`
+	Reason     string `form:"reason" json:"reason" validate:"omitempty,oneof=harassment spam inappropriate other"`
+	Assignee   string `form:"assignee" json:"assignee" validate:"omitempty,oneof=me unassigned"`
+}
+
+// ResolveReportInput closes a report. SuspendDays sets the length of a
+// suspend action and defaults to defaultSuspendDays.
+type ResolveReportInput struct {
+	Action      string  `json:"action" validate:"required,oneof=dismiss hide warn suspend"`
+	Note        *string `json:"note" validate:"omitempty,max=1000"`
+	SuspendDays int     `json:"suspend_days" validate:"omitempty,min=1,max=365"`
+}
+
+const defaultSuspendDays = 7
+
+type ReportDTO struct {
+	ID             string     `json:"id"`
+	TargetType     string     `json:"target_type"`
+	TargetID       string     `json:"target_id"`
+	ReporterID     string     `json:"reporter_id"`
+	Reason         string     `json:"reason"`
+	Details        *string    `json:"details"`
+	Status         string     `json:"status"`
+	ClaimedBy      *string    `json:"claimed_by"`
+	Resolution     *string    `json:"resolution"`
+	ResolutionNote *string    `json:"resolution_note"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func toReportDTO(r *models.Report) ReportDTO {
+	dto := ReportDTO{
+		ID:             r.ID.String(),
+		TargetType:     r.TargetType,
+		TargetID:       r.TargetID.String(),
+		ReporterID:     r.ReporterID.String(),
+		Reason:         r.Reason,
+		Details:        r.Details,
+		Status:         r.Status,
+		Resolution:     r.Resolution,
+		ResolutionNote: r.ResolutionNote,
+		ResolvedAt:     r.ResolvedAt,
+		CreatedAt:      r.CreatedAt,
+	}
+	if r.ClaimedBy != nil {
+		id := r.ClaimedBy.String()
+		dto.ClaimedBy = &id
+	}
+	return dto
+}
+
+// Report files a report against a post, comment or profile. Once enough
+// distinct residents have reported the same target it is hidden pending review.
+func (s *ModerationService) Report(ctx context.Context, reporterID uuid.UUID, in CreateReportInput) (*ReportDTO, error) {
+	in.Details = trimToNil(in.Details)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	targetID, err := uuid.Parse(in.TargetID)
+	if err != nil {
+		return nil, FieldError("target_id", "must be a valid UUID")
+	}
+
+	ownerID, err := s.targetOwner(ctx, in.TargetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == reporterID {
+		return nil, FieldError("target_id", "cannot report your own content")
+	}
+
+	report := &models.Report{
+		TargetType: in.TargetType,
+		TargetID:   targetID,
+		ReporterID: reporterID,
+		Reason:     in.Reason,
+		Details:    in.Details,
+	}
+	if err := s.reports.Insert(ctx, report); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, ConflictError("you have already reported this", nil)
+		}
+		return nil, err
+	}
+
+	if s.cfg.AutoHideThreshold > 0 {
+		n, err := s.reports.CountOpenReporters(ctx, in.TargetType, targetID)
+		if err != nil {
+			return nil, err
+		}
+		if n >= s.cfg.AutoHideThreshold {
+			if err := s.setHidden(ctx, in.TargetType, targetID, true); err != nil {
+				return nil, err
+			}
+			details := map[string]string{"reporters": strconv.Itoa(n)}
+			if err := s.audit.Record(ctx, nil, auditModerationAutoHide, in.TargetType, &targetID, details); err != nil {
+				return nil, err
+			}
+			utils.Infof("moderation auto-hid %s id=%s reporters=%d", in.TargetType, targetID, n)
+		}
+	}
+
+	dto := toReportDTO(report)
+	return &dto, nil
+}
+
+// Queue lists reports for moderators, oldest first.
+func (s *ModerationService) Queue(ctx context.Context, moderatorID uuid.UUID, in ReportQueueInput) ([]ReportDTO, error) {
+	if _, err := s.requireModerator(ctx, moderatorID); err != nil {
+		return nil, err
+	}
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	filter := models.ReportFilter{Status: in.Status, TargetType: in.TargetType, Reason: in.Reason}
+	switch in.Assignee {
+	case "me":
+		filter.ClaimedBy = &moderatorID
+	case "unassigned":
+		filter.Unclaimed = true
+	}
+	reports, err := s.reports.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ReportDTO, 0, len(reports))
+	for i := range reports {
+		out = append(out, toReportDTO(&reports[i]))
+	}
+	return out, nil
+}
+
+// Claim assigns an unresolved report to the calling moderator.
+func (s *ModerationService) Claim(ctx context.Context, moderatorID, reportID uuid.UUID) (*ReportDTO, error) {
+	if _, err := s.requireModerator(ctx, moderatorID); err != nil {
+		return nil, err
+	}
+	ok, err := s.reports.Claim(ctx, reportID, moderatorID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.getReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, claimConflict(report)
+	}
+	if err := s.audit.Record(ctx, &moderatorID, auditReportClaim, "report", &report.ID, nil); err != nil {
+		return nil, err
+	}
+	dto := toReportDTO(report)
+	return &dto, nil
+}
+
+// Resolve applies a moderator action to the report's target and closes every
+// unresolved report on that target with the same outcome.
+func (s *ModerationService) Resolve(ctx context.Context, moderatorID, reportID uuid.UUID, in ResolveReportInput) (*ReportDTO, error) {
+	if _, err := s.requireModerator(ctx, moderatorID); err != nil {
+		return nil, err
+	}
+	in.Note = trimToNil(in.Note)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	report, err := s.getReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status == models.ReportResolved || (report.ClaimedBy != nil && *report.ClaimedBy != moderatorID) {
+		return nil, claimConflict(report)
+	}
+
+	if err := s.apply(ctx, moderatorID, report, in); err != nil {
+		return nil, err
+	}
+	resolution := resolutionByAction[in.Action]
+	if _, err := s.reports.ResolveTarget(ctx, report.TargetType, report.TargetID, resolution, in.Note, moderatorID); err != nil {
+		return nil, err
+	}
+	details := map[string]string{"report_id": report.ID.String(), "resolution": resolution}
+	if in.Note != nil {
+		details["note"] = *in.Note
+	}
+	if err := s.audit.Record(ctx, &moderatorID, auditReportResolve, report.TargetType, &report.TargetID, details); err != nil {
+		return nil, err
+	}
+	utils.Infof("moderation resolved report id=%s target=%s/%s resolution=%s by=%s", report.ID, report.TargetType, report.TargetID, resolution, moderatorID)
+
+	if report, err = s.getReport(ctx, reportID); err != nil {
+		return nil, err
+	}
+	dto := toReportDTO(report)
+	return &dto, nil
+}
+
+// apply carries out a moderator action against the report's target.
+func (s *ModerationService) apply(ctx context.Context, moderatorID uuid.UUID, report *models.Report, in ResolveReportInput) error {
+	switch in.Action {
+	case "dismiss":
+		// Undo an automatic hide, but never a moderator's earlier hide of the
+		// same target.
+		hidden, err := s.reports.HasResolution(ctx, report.TargetType, report.TargetID, resolutionByAction["hide"])
+		if err != nil || hidden {
+			return err
+		}
+		return s.setHidden(ctx, report.TargetType, report.TargetID, false)
+	case "hide":
+		return s.setHidden(ctx, report.TargetType, report.TargetID, true)
+	}
+
+	ownerID, err := s.targetOwner(ctx, report.TargetType, report.TargetID)
+	if err != nil {
+		return err
+	}
+	if in.Action == "warn" {
+		reason := "Your content was reported for " + report.Reason
+		if in.Note != nil {
+			reason = *in.Note
+		}
+		return s.warnings.Insert(ctx, &models.Warning{UserID: ownerID, ReportID: &report.ID, Reason: reason, IssuedBy: &moderatorID})
+	}
+
+	// suspend
+	owner, err := s.users.GetByID(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return ErrUserNotFound
+	}
+	if owner.IsAdmin || owner.IsModerator {
+		return ForbiddenError("moderators and admins cannot be suspended from the report queue")
+	}
+	if owner.Status == models.UserBanned {
+		return ConflictError("account is banned", nil)
+	}
+	if owner.DeletionRequestedAt != nil {
+		return ConflictError("account is pending deletion", nil)
+	}
+	days := in.SuspendDays
+	if days == 0 {
+		days = defaultSuspendDays
+	}
+	reason := "Suspended after a report for " + report.Reason
+	if in.Note != nil {
+		reason = *in.Note
+	}
+	until := time.Now().AddDate(0, 0, days)
+	return s.accounts.setStatus(ctx, &moderatorID, owner, actionSuspend, reason, &until)
+}
+
+// targetOwner returns the user responsible for a report target, or NotFound.
+func (s *ModerationService) targetOwner(ctx context.Context, targetType string, id uuid.UUID) (uuid.UUID, error) {
+	switch targetType {
+	case models.ReportTargetPost:
+		p, err := s.posts.GetByID(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if p == nil {
+			return uuid.Nil, NotFoundError("post not found")
+		}
+		return p.AuthorID, nil
+	case models.ReportTargetComment:
+		c, err := s.comments.GetByID(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if c == nil {
+			return uuid.Nil, NotFoundError("comment not found")
+		}
+		return c.AuthorID, nil
+	default:
+		u, err := s.users.GetByID(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if u == nil {
+			return uuid.Nil, ErrUserNotFound
+		}
+		return u.ID, nil
+	}
+}
+
+// setHidden hides or restores a target. Hiding a profile only flags it, so
+// restoring it brings back the resident's picture and directory listing.
+func (s *ModerationService) setHidden(ctx context.Context, targetType string, id uuid.UUID, hidden bool) error {
+	switch targetType {
+	case models.ReportTargetPost:
+		return s.posts.SetHidden(ctx, id, hidden)
+	case models.ReportTargetComment:
+		return s.comments.SetHidden(ctx, id, hidden)
+	default:
+		return s.users.SetModerationHidden(ctx, id, hidden)
+	}
+}
+
+// UnhideProfile lifts a moderation hide, including one a moderator chose, so
+// the profile is listed again as its opt-in says. Admin only.
+func (s *ModerationService) UnhideProfile(ctx context.Context, adminID, userID uuid.UUID) error {
+	if err := requireAdmin(ctx, s.users, adminID); err != nil {
+		return err
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrUserNotFound
+	}
+	if !u.ModerationHidden {
+		return nil
+	}
+	if err := s.users.SetModerationHidden(ctx, userID, false); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, &adminID, auditProfileUnhide, models.ReportTargetProfile, &userID, nil)
+}
+
+func (s *ModerationService) getReport(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	report, err := s.reports.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, NotFoundError("report not found")
+	}
+	return report, nil
+}
+
+// requireModerator loads the caller and checks they may work the report queue.
+func (s *ModerationService) requireModerator(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	u, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || !(u.IsAdmin || u.IsModerator) {
+		return nil, ForbiddenError("moderator access required")
+	}
+	return u, nil
+}
+
+func claimConflict(report *models.Report) *Error {
+	if report.Status == models.ReportResolved {
+		return ConflictError("report is already resolved", nil)
+	}
+	return ConflictError("report is claimed by another moderator", map[string]string{
+		"claimed_by": report.ClaimedBy.String(),
+	})
+}
@@ -4,48 +4,79 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/cameronsralla/culdechat/models"
 	"github.com/google/uuid"
 )
 
-type PostService struct{}
+type PostService struct {
+	posts     models.PostRepo
+	comments  models.CommentRepo
+	reactions models.ReactionRepo
+	users     models.UserRepo
+	access    *BoardAccess
+	audit     *AuditService
+}
+
+// NewPostService returns a PostService backed by the given repositories.
+func NewPostService(posts models.PostRepo, comments models.CommentRepo, reactions models.ReactionRepo, users models.UserRepo, access *BoardAccess, audit *AuditService) *PostService {
+	return &PostService{posts: posts, comments: comments, reactions: reactions, users: users, access: access, audit: audit}
+}
 
 type CreatePostInput struct {
-	BoardID  string `json:"board_id"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
+	BoardID  string `json:"board_id" validate:"required,uuid"`
+	Title    string `json:"title" validate:"required,max=200"`
+	Content  string `json:"content" validate:"required,max=10000"`
 	Bulletin bool   `json:"bulletin"`
 }
 
+// PostDTO is a post as returned by the API. ReactionCounts maps reaction
+// types to counts and MyReaction is the caller's own reaction, if any.
 type PostDTO struct {
-	ID         string `json:"id"`
-	BoardID    string `json:"board_id"`
-	AuthorID   string `json:"author_id"`
-	Title      string `json:"title"`
-	Content    string `json:"content"`
-	IsBulletin bool   `json:"is_bulletin"`
+	ID             string           `json:"id"`
+	BoardID        string           `json:"board_id"`
+	AuthorID       string           `json:"author_id"`
+	Author         *AuthorDTO       `json:"author"`
+	Title          string           `json:"title"`
+	Content        string           `json:"content"`
+	IsBulletin     bool             `json:"is_bulletin"`
+	ReactionCounts map[string]int64 `json:"reaction_counts"`
+	MyReaction     *string          `json:"my_reaction"`
+	CommentCount   int              `json:"comment_count"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+func toPostDTO(p *models.Post) PostDTO {
+	return PostDTO{
+		ID:             p.ID.String(),
+		BoardID:        p.BoardID.String(),
+		AuthorID:       p.AuthorID.String(),
+		Title:          p.Title,
+		Content:        p.Content,
+		IsBulletin:     p.IsBulletin,
+		ReactionCounts: map[string]int64{},
+		CreatedAt:      p.CreatedAt,
+	}
 }
 
-func (s *PostService) Create(ctx context.Context, authorID uuid.UUID, in CreatePostInput, isAdmin bool) (*PostDTO, error) {
+func (s *PostService) Create(ctx context.Context, authorID uuid.UUID, in CreatePostInput) (*PostDTO, error) {
 	in.Title = strings.TrimSpace(in.Title)
 	in.Content = strings.TrimSpace(in.Content)
-	if in.BoardID == "" || in.Title == "" || in.Content == "" {
-		return nil, errors.New("board_id, title and content are required")
+	if err := validateInput(in); err != nil {
+		return nil, err
 	}
 	boardUUID, err := uuid.Parse(in.BoardID)
 	if err != nil {
-		return nil, errors.New("invalid board_id")
-	}
-	if err := models.EnsureBoardsTable(ctx); err != nil {
-		return nil, err
+		return nil, FieldError("board_id", "must be a valid UUID")
 	}
-	if err := models.EnsurePostsTable(ctx); err != nil {
+	_, author, err := s.access.post(ctx, boardUUID, authorID)
+	if err != nil {
 		return nil, err
 	}
 	// Allow bulletin creation only for admins
-	if in.Bulletin && !isAdmin {
-		return nil, errors.New("only admins can create bulletin posts")
+	if in.Bulletin && (author == nil || !author.IsAdmin) {
+		return nil, ForbiddenError("only admins can create bulletin posts")
 	}
 	post := &models.Post{
 		BoardID:    boardUUID,
@@ -54,59 +85,142 @@ func (s *PostService) Create(ctx context.Context, authorID uuid.UUID, in CreateP
 		Content:    in.Content,
 		IsBulletin: in.Bulletin,
 	}
-	if err := models.InsertPost(ctx, post); err != nil {
+	if err := s.posts.Insert(ctx, post); err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			return nil, NotFoundError("board not found")
+		}
 		return nil, err
 	}
-	return &PostDTO{
-		ID:         post.ID.String(),
-		BoardID:    post.BoardID.String(),
-		AuthorID:   post.AuthorID.String(),
-		Title:      post.Title,
-		Content:    post.Content,
-		IsBulletin: post.IsBulletin,
-	}, nil
+	if post.IsBulletin {
+		if err := s.audit.Record(ctx, &authorID, auditBulletinCreate, "post", &post.ID, map[string]string{"title": post.Title}); err != nil {
+			return nil, err
+		}
+	}
+	authors, err := authorSummaries(ctx, s.users, []uuid.UUID{authorID}, &authorID)
+	if err != nil {
+		return nil, err
+	}
+	dto := toPostDTO(post)
+	dto.Author = authors[authorID]
+	return &dto, nil
 }
 
-func (s *PostService) ListByBoard(ctx context.Context, boardID uuid.UUID) ([]PostDTO, error) {
-	if err := models.EnsurePostsTable(ctx); err != nil {
+// ListByBoard lists a board's visible posts for viewerID, which is nil for
+// anonymous callers.
+func (s *PostService) ListByBoard(ctx context.Context, boardID uuid.UUID, viewerID *uuid.UUID) ([]PostDTO, error) {
+	if _, _, err := s.access.view(ctx, boardID, viewerID); err != nil {
 		return nil, err
 	}
-	posts, err := models.ListPostsByBoard(ctx, boardID)
+	posts, err := s.posts.ListByBoard(ctx, boardID, viewerID)
 	if err != nil {
 		return nil, err
 	}
+	return s.toPostDTOs(ctx, posts, nil, viewerID)
+}
+
+// toPostDTOs converts posts, keeping only those on boards in visible when it
+// is set, and fills in authors and reaction and comment counts with one query
+// each rather than one per post.
+func (s *PostService) toPostDTOs(ctx context.Context, posts []models.Post, visible map[uuid.UUID]bool, viewerID *uuid.UUID) ([]PostDTO, error) {
 	out := make([]PostDTO, 0, len(posts))
-	for _, p := range posts {
-		out = append(out, PostDTO{
-			ID:         p.ID.String(),
-			BoardID:    p.BoardID.String(),
-			AuthorID:   p.AuthorID.String(),
-			Title:      p.Title,
-			Content:    p.Content,
-			IsBulletin: p.IsBulletin,
-		})
+	ids := make([]uuid.UUID, 0, len(posts))
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	for i := range posts {
+		if visible == nil || visible[posts[i].BoardID] {
+			out = append(out, toPostDTO(&posts[i]))
+			ids = append(ids, posts[i].ID)
+			authorIDs = append(authorIDs, posts[i].AuthorID)
+		}
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	counts, err := s.reactions.CountMany(ctx, models.ReactionOnPost, ids)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.comments.CountByPosts(ctx, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := authorSummaries(ctx, s.users, authorIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	mine := map[uuid.UUID]string{}
+	if viewerID != nil {
+		if mine, err = s.reactions.ByUser(ctx, models.ReactionOnPost, ids, *viewerID); err != nil {
+			return nil, err
+		}
+	}
+	for i, id := range ids {
+		for _, c := range counts[id] {
+			out[i].ReactionCounts[c.Type] = c.Count
+		}
+		if t, ok := mine[id]; ok {
+			out[i].MyReaction = &t
+		}
+		out[i].CommentCount = comments[id]
+		out[i].Author = authors[authorIDs[i]]
 	}
 	return out, nil
 }
 
-func (s *PostService) ListBulletins(ctx context.Context) ([]PostDTO, error) {
-	if err := models.EnsurePostsTable(ctx); err != nil {
+// FeedInput pages through the feed: Before and BeforeID are the created_at (an
+// RFC 3339 timestamp) and id of the last post already shown. BeforeID keeps
+// posts created at the same instant from being skipped.
+type FeedInput struct {
+	Before   string `form:"before" json:"before"`
+	BeforeID string `form:"before_id" json:"before_id" validate:"omitempty,uuid"`
+	Limit    int    `form:"limit" json:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+const defaultFeedLimit = 50
+
+// Feed lists recent posts from the caller's subscribed boards: all posts for
+// "all" subscriptions, only bulletins for "bulletins", nothing from muted boards.
+// Posts on members-only boards the caller cannot read are left out.
+func (s *PostService) Feed(ctx context.Context, userID uuid.UUID, in FeedInput) ([]PostDTO, error) {
+	if err := validateInput(in); err != nil {
 		return nil, err
 	}
-	posts, err := models.ListBulletins(ctx)
+	var after *models.FeedCursor
+	if in.Before != "" {
+		t, err := time.Parse(time.RFC3339Nano, in.Before)
+		if err != nil {
+			return nil, FieldError("before", "must be an RFC 3339 timestamp")
+		}
+		after = &models.FeedCursor{CreatedAt: t}
+		if in.BeforeID != "" {
+			id, err := uuid.Parse(in.BeforeID)
+			if err != nil {
+				return nil, FieldError("before_id", "must be a valid UUID")
+			}
+			after.ID = &id
+		}
+	} else if in.BeforeID != "" {
+		return nil, FieldError("before", "is required with before_id")
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultFeedLimit
+	}
+	// ListFeed already leaves out boards the caller cannot read, so pages come back full.
+	posts, err := s.posts.ListFeed(ctx, userID, after, in.Limit)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]PostDTO, 0, len(posts))
-	for _, p := range posts {
-		out = append(out, PostDTO{
-			ID:         p.ID.String(),
-			BoardID:    p.BoardID.String(),
-			AuthorID:   p.AuthorID.String(),
-			Title:      p.Title,
-			Content:    p.Content,
-			IsBulletin: p.IsBulletin,
-		})
+	return s.toPostDTOs(ctx, posts, nil, &userID)
+}
+
+// ListBulletins lists bulletins from every board viewerID may read.
+func (s *PostService) ListBulletins(ctx context.Context, viewerID *uuid.UUID) ([]PostDTO, error) {
+	posts, err := s.posts.ListBulletins(ctx, viewerID)
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+	visible, err := s.access.visibleBoards(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toPostDTOs(ctx, posts, visible, viewerID)
 }
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"github.com/cameronsralla/culdechat/models"
+)
+
+// PrivacyDTO sets or reports the audience for each profile field: "nobody",
+// "floor" (residents on the same floor) or "everyone" (any signed-in resident).
+type PrivacyDTO struct {
+	Name    string `json:"name" validate:"omitempty,oneof=nobody floor everyone"`
+	Unit    string `json:"unit" validate:"omitempty,oneof=nobody floor everyone"`
+	Floor   string `json:"floor" validate:"omitempty,oneof=nobody floor everyone"`
+	Avatar  string `json:"avatar" validate:"omitempty,oneof=nobody floor everyone"`
+	Bio     string `json:"bio" validate:"omitempty,oneof=nobody floor everyone"`
+	Contact string `json:"contact" validate:"omitempty,oneof=nobody floor everyone"`
+}
+
+// mergePrivacy applies the audiences set in in on top of p.
+func mergePrivacy(p models.PrivacySettings, in PrivacyDTO) models.PrivacySettings {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&p.Name, in.Name)
+	set(&p.Unit, in.Unit)
+	set(&p.Floor, in.Floor)
+	set(&p.Avatar, in.Avatar)
+	set(&p.Bio, in.Bio)
+	set(&p.Contact, in.Contact)
+	return p
+}
+
+func toPrivacyDTO(p models.PrivacySettings) PrivacyDTO {
+	p = p.Effective()
+	return PrivacyDTO{Name: p.Name, Unit: p.Unit, Floor: p.Floor, Avatar: p.Avatar, Bio: p.Bio, Contact: p.Contact}
+}
+
+// profileView is the part of a profile one viewer may see.
+type profileView struct {
+	DisplayName       *string
+	UnitNumber        *string
+	Floor             *string
+	ProfilePictureURL *string
+	Bio               *string
+	ContactMethod     *string
+	Building          *string
+	Interests         []string
+}
+
+// viewProfile applies the owner's privacy settings for viewer, which is nil
+// for anonymous callers. Anonymous callers see nothing; residents always see
+// their own profile in full.
+func viewProfile(owner models.DirectoryUser, viewer *models.User) profileView {
+	var v profileView
+	dv := directoryViewer(viewer)
+	sees := func(audience string) bool { return models.CanSee(audience, owner.ID, owner.UnitNumber, dv) }
+	p := owner.Privacy.Effective()
+	if sees(p.Name) {
+		v.DisplayName = owner.DisplayName
+	}
+	if sees(p.Unit) {
+		unit := owner.UnitNumber
+		v.UnitNumber = &unit
+	}
+	if v.UnitNumber != nil || sees(p.Floor) {
+		if floor := models.FloorOf(owner.UnitNumber); floor != "" {
+			v.Floor = &floor
+		}
+		v.Building = owner.Building
+	}
+	if sees(p.Avatar) {
+		v.ProfilePictureURL = owner.ProfilePictureURL
+	}
+	if sees(p.Bio) {
+		v.Bio = owner.Bio
+		v.Interests = owner.Interests
+	}
+	if sees(p.Contact) {
+		v.ContactMethod = owner.ContactMethod
+	}
+	return v
+}
+
+// directoryViewer describes viewer for privacy checks; nil stays nil.
+func directoryViewer(viewer *models.User) *models.DirectoryViewer {
+	if viewer == nil {
+		return nil
+	}
+	return &models.DirectoryViewer{ID: viewer.ID, Floor: models.FloorOf(viewer.UnitNumber)}
+}
+
+// directoryEntry projects a user for viewProfile. A profile hidden by a
+// moderator shows no picture.
+func directoryEntry(u *models.User) models.DirectoryUser {
+	picture := u.ProfilePictureURL
+	if u.ModerationHidden {
+		picture = nil
+	}
+	return models.DirectoryUser{
+		ID:                u.ID,
+		UnitNumber:        u.UnitNumber,
+		DisplayName:       u.DisplayName,
+		ProfilePictureURL: picture,
+		Bio:               u.Bio,
+		ContactMethod:     u.ContactMethod,
+		Building:          u.Building,
+		Interests:         u.Interests,
+		Privacy:           u.Privacy,
+	}
+}
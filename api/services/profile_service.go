@@ -2,78 +2,420 @@ package services
 
 import (
 	"context"
-	"errors"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/cameronsralla/culdechat/models"
 	"github.com/google/uuid"
 )
 
-type ProfileService struct{}
+type ProfileService struct {
+	users    models.UserRepo
+	warnings models.WarningRepo
+	blocks   models.BlockRepo
+}
+
+// NewProfileService returns a ProfileService backed by the given repositories.
+func NewProfileService(users models.UserRepo, warnings models.WarningRepo, blocks models.BlockRepo) *ProfileService {
+	return &ProfileService{users: users, warnings: warnings, blocks: blocks}
+}
 
+// UpdateProfileInput changes the fields that are set. An empty string clears
+// the picture, display name, pronouns, bio, move-in date, contact method or
+// building, and an empty list
+// clears the interests.
 type UpdateProfileInput struct {
-	ProfilePictureURL *string `json:"profile_picture_url"`
-	DirectoryOptIn    *bool   `json:"directory_opt_in"`
+	ProfilePictureURL *string   `json:"profile_picture_url" validate:"omitempty,max=2048,http_url"`
+	DisplayName       *string   `json:"display_name" validate:"omitempty,max=64"`
+	Pronouns          *string   `json:"pronouns" validate:"omitempty,max=32"`
+	Bio               *string   `json:"bio" validate:"omitempty,max=500"`
+	MoveInDate        *string   `json:"move_in_date" validate:"omitempty,datetime=2006-01-02"`
+	Interests         *[]string `json:"interests" validate:"omitempty,max=20,dive,required,max=32"`
+	ContactMethod     *string   `json:"contact_method" validate:"omitempty,max=200"`
+	Building          *string   `json:"building" validate:"omitempty,max=64"`
+	// Privacy changes the audiences that are set and keeps the rest.
+	Privacy        *PrivacyDTO `json:"privacy"`
+	DirectoryOptIn *bool       `json:"directory_opt_in"`
 }
 
 type ProfileDTO struct {
-	ID                string  `json:"id"`
-	Email             string  `json:"email"`
-	UnitNumber        string  `json:"unit_number"`
-	ProfilePictureURL *string `json:"profile_picture_url"`
-	DirectoryOptIn    bool    `json:"directory_opt_in"`
+	ID                string     `json:"id"`
+	Email             string     `json:"email"`
+	UnitNumber        string     `json:"unit_number"`
+	ProfilePictureURL *string    `json:"profile_picture_url"`
+	DisplayName       *string    `json:"display_name"`
+	Pronouns          *string    `json:"pronouns"`
+	Bio               *string    `json:"bio"`
+	MoveInDate        *string    `json:"move_in_date"`
+	Interests         []string   `json:"interests"`
+	ContactMethod     *string    `json:"contact_method"`
+	Building          *string    `json:"building"`
+	Privacy           PrivacyDTO `json:"privacy"`
+	DirectoryOptIn    bool       `json:"directory_opt_in"`
+}
+
+// dateLayout formats calendar dates such as move_in_date.
+const dateLayout = "2006-01-02"
+
+type WarningDTO struct {
+	ID       string    `json:"id"`
+	Reason   string    `json:"reason"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// Warnings lists the moderator warnings issued to the user, newest first.
+func (s *ProfileService) Warnings(ctx context.Context, userID uuid.UUID) ([]WarningDTO, error) {
+	warnings, err := s.warnings.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WarningDTO, 0, len(warnings))
+	for _, w := range warnings {
+		out = append(out, WarningDTO{ID: w.ID.String(), Reason: w.Reason, IssuedAt: w.CreatedAt})
+	}
+	return out, nil
 }
 
 func (s *ProfileService) Get(ctx context.Context, userID uuid.UUID) (*ProfileDTO, error) {
-	u, err := models.GetUserByID(ctx, userID)
+	u, err := s.users.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	if u == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
-	return &ProfileDTO{
+	dto := &ProfileDTO{
 		ID:                u.ID.String(),
 		Email:             u.Email,
 		UnitNumber:        u.UnitNumber,
 		ProfilePictureURL: u.ProfilePictureURL,
+		DisplayName:       u.DisplayName,
+		Pronouns:          u.Pronouns,
+		Bio:               u.Bio,
+		Interests:         u.Interests,
+		ContactMethod:     u.ContactMethod,
+		Building:          u.Building,
+		Privacy:           toPrivacyDTO(u.Privacy),
 		DirectoryOptIn:    u.IsDirectoryOptIn,
-	}, nil
+	}
+	if u.MoveInDate != nil {
+		d := u.MoveInDate.Format(dateLayout)
+		dto.MoveInDate = &d
+	}
+	if dto.Interests == nil {
+		dto.Interests = []string{}
+	}
+	return dto, nil
+}
+
+// clearable trims an optional string and reports whether it was sent empty,
+// which clears the field. Empty values are set to nil so omitempty skips them.
+func clearable(s **string) bool {
+	*s = trimOptional(*s)
+	if *s != nil && **s == "" {
+		*s = nil
+		return true
+	}
+	return false
+}
+
+// normalizeInterests lowercases and trims interest tags, dropping duplicates.
+func normalizeInterests(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
 }
 
 func (s *ProfileService) Update(ctx context.Context, userID uuid.UUID, in UpdateProfileInput) (*ProfileDTO, error) {
-	u, err := models.GetUserByID(ctx, userID)
+	// An empty string clears a field; omitempty only skips nil pointers, so
+	// take cleared fields out of validation explicitly.
+	clearPicture := clearable(&in.ProfilePictureURL)
+	clearName := clearable(&in.DisplayName)
+	clearPronouns := clearable(&in.Pronouns)
+	clearBio := clearable(&in.Bio)
+	clearMoveIn := clearable(&in.MoveInDate)
+	clearContact := clearable(&in.ContactMethod)
+	clearBuilding := clearable(&in.Building)
+	if in.Interests != nil {
+		tags := normalizeInterests(*in.Interests)
+		in.Interests = &tags
+	}
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	u, err := s.users.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	if u == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
-	if in.ProfilePictureURL != nil {
+	if clearPicture {
+		u.ProfilePictureURL = nil
+	} else if in.ProfilePictureURL != nil {
 		u.ProfilePictureURL = in.ProfilePictureURL
 	}
+	setClearable(&u.DisplayName, in.DisplayName, clearName)
+	setClearable(&u.Pronouns, in.Pronouns, clearPronouns)
+	setClearable(&u.Bio, in.Bio, clearBio)
+	setClearable(&u.ContactMethod, in.ContactMethod, clearContact)
+	setClearable(&u.Building, in.Building, clearBuilding)
+	if in.Privacy != nil {
+		u.Privacy = mergePrivacy(u.Privacy, *in.Privacy)
+	}
+	if clearMoveIn {
+		u.MoveInDate = nil
+	} else if in.MoveInDate != nil {
+		d, err := time.Parse(dateLayout, *in.MoveInDate)
+		if err != nil {
+			return nil, FieldError("move_in_date", "must be a date (YYYY-MM-DD)")
+		}
+		u.MoveInDate = &d
+	}
+	if in.Interests != nil {
+		u.Interests = *in.Interests
+	}
 	if in.DirectoryOptIn != nil {
 		u.IsDirectoryOptIn = *in.DirectoryOptIn
 	}
-	if err := models.UpdateUser(ctx, u); err != nil {
+	if err := s.users.Update(ctx, u); err != nil {
 		return nil, err
 	}
 	return s.Get(ctx, userID)
 }
 
-type DirectoryUserDTO struct {
+// setClearable applies an optional string update to dst.
+func setClearable(dst **string, v *string, clear bool) {
+	if clear {
+		*dst = nil
+	} else if v != nil {
+		*dst = v
+	}
+}
+
+// AuthorDTO summarizes who wrote a post or comment, limited to the fields the
+// author shares with the viewer.
+type AuthorDTO struct {
 	ID                string  `json:"id"`
-	UnitNumber        string  `json:"unit_number"`
+	DisplayName       *string `json:"display_name"`
 	ProfilePictureURL *string `json:"profile_picture_url"`
+	UnitNumber        *string `json:"unit_number,omitempty"`
+	Floor             *string `json:"floor,omitempty"`
 }
 
-func (s *ProfileService) ListDirectory(ctx context.Context) ([]DirectoryUserDTO, error) {
-	users, err := models.ListDirectoryUsers(ctx)
+// authorSummaries loads the authors for ids, and the viewer, in one lookup,
+// keyed by user id. viewerID is nil for anonymous callers.
+func authorSummaries(ctx context.Context, users models.UserRepo, ids []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID]*AuthorDTO, error) {
+	lookup := ids
+	if viewerID != nil {
+		lookup = append(slices.Clip(ids), *viewerID)
+	}
+	found, err := users.ListByIDs(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+	var viewer *models.User
+	for i := range found {
+		if viewerID != nil && found[i].ID == *viewerID {
+			viewer = &found[i]
+		}
+	}
+	out := make(map[uuid.UUID]*AuthorDTO, len(found))
+	for i := range found {
+		v := viewProfile(directoryEntry(&found[i]), viewer)
+		out[found[i].ID] = &AuthorDTO{
+			ID:                found[i].ID.String(),
+			DisplayName:       v.DisplayName,
+			ProfilePictureURL: v.ProfilePictureURL,
+			UnitNumber:        v.UnitNumber,
+			Floor:             v.Floor,
+		}
+	}
+	return out, nil
+}
+
+// DirectoryUserDTO is a directory entry; fields the resident does not share
+// with the caller are null.
+type DirectoryUserDTO struct {
+	ID                string   `json:"id"`
+	DisplayName       *string  `json:"display_name"`
+	UnitNumber        *string  `json:"unit_number"`
+	Floor             *string  `json:"floor"`
+	ProfilePictureURL *string  `json:"profile_picture_url"`
+	Bio               *string  `json:"bio"`
+	ContactMethod     *string  `json:"contact_method"`
+	Building          *string  `json:"building"`
+	Interests         []string `json:"interests"`
+	// Household is the shared entry of a household that opted in to the directory.
+	Household *DirectoryHouseholdDTO `json:"household"`
+}
+
+// DirectoryHouseholdDTO names the household a directory entry belongs to.
+type DirectoryHouseholdDTO struct {
+	ID   string  `json:"id"`
+	Name *string `json:"name"`
+}
+
+// DirectoryInput searches and pages through the directory. Q matches the
+// start of a unit number or of a word in a name, or a similar name.
+// Household lists the members of one household.
+type DirectoryInput struct {
+	Q         string `form:"q" validate:"max=64"`
+	Floor     string `form:"floor" validate:"max=16"`
+	Building  string `form:"building" validate:"max=64"`
+	Interest  string `form:"interest" validate:"max=32"`
+	Household string `form:"household"`
+	Limit     int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	Offset    int    `form:"offset" validate:"min=0"`
+}
+
+const defaultDirectoryLimit = 50
+
+// ListDirectory lists opted-in residents as viewerID may see them. Filters
+// only match details the resident shares with the caller. viewerID is nil
+// when the directory is on the public allow-list and the caller is signed
+// out; they only get ids and cannot filter.
+func (s *ProfileService) ListDirectory(ctx context.Context, viewerID *uuid.UUID, in DirectoryInput) ([]DirectoryUserDTO, error) {
+	in.Q = strings.TrimSpace(in.Q)
+	in.Floor = strings.TrimSpace(in.Floor)
+	in.Building = strings.TrimSpace(in.Building)
+	in.Interest = strings.TrimSpace(in.Interest)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultDirectoryLimit
+	}
+	var viewer *models.User
+	if viewerID != nil {
+		u, err := s.users.GetByID(ctx, *viewerID)
+		if err != nil {
+			return nil, err
+		}
+		viewer = u
+	}
+	f := models.DirectoryFilter{
+		Viewer:   directoryViewer(viewer),
+		Query:    in.Q,
+		Floor:    in.Floor,
+		Building: in.Building,
+		Interest: in.Interest,
+		Limit:    in.Limit,
+		Offset:   in.Offset,
+	}
+	if in.Household != "" {
+		id, err := uuid.Parse(in.Household)
+		if err != nil {
+			return nil, FieldError("household", "must be a valid UUID")
+		}
+		f.HouseholdID = &id
+	}
+	users, err := s.users.ListDirectory(ctx, f)
 	if err != nil {
 		return nil, err
 	}
 	out := make([]DirectoryUserDTO, 0, len(users))
 	for _, u := range users {
-		out = append(out, DirectoryUserDTO{ID: u.ID, UnitNumber: u.UnitNumber, ProfilePictureURL: u.ProfilePictureURL})
+		v := viewProfile(u, viewer)
+		entry := DirectoryUserDTO{
+			ID:                u.ID.String(),
+			DisplayName:       v.DisplayName,
+			UnitNumber:        v.UnitNumber,
+			Floor:             v.Floor,
+			ProfilePictureURL: v.ProfilePictureURL,
+			Bio:               v.Bio,
+			ContactMethod:     v.ContactMethod,
+			Building:          v.Building,
+			Interests:         v.Interests,
+		}
+		if u.HouseholdID != nil && viewer != nil {
+			entry.Household = &DirectoryHouseholdDTO{ID: u.HouseholdID.String(), Name: u.HouseholdName}
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// BlockDTO is a resident the caller blocked or muted.
+type BlockDTO struct {
+	UserID    string    `json:"user_id"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Blocks lists the residents the caller blocked or muted, newest first.
+func (s *ProfileService) Blocks(ctx context.Context, userID uuid.UUID) ([]BlockDTO, error) {
+	blocks, err := s.blocks.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BlockDTO, 0, len(blocks))
+	for _, b := range blocks {
+		out = append(out, BlockDTO{UserID: b.TargetID.String(), Kind: b.Kind, CreatedAt: b.CreatedAt})
 	}
 	return out, nil
 }
+
+// Block hides targetID and the caller from each other. Moderators and admins
+// cannot be blocked so announcements and moderation stay visible; they can
+// be muted instead.
+func (s *ProfileService) Block(ctx context.Context, userID, targetID uuid.UUID) error {
+	target, err := s.blockTarget(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+	if isStaff(target) {
+		return ForbiddenError("moderators and admins cannot be blocked")
+	}
+	return s.blocks.Upsert(ctx, &models.Block{UserID: userID, TargetID: targetID, Kind: models.BlockKindBlock})
+}
+
+// Mute hides targetID's posts from the caller's feed and board listings.
+func (s *ProfileService) Mute(ctx context.Context, userID, targetID uuid.UUID) error {
+	if _, err := s.blockTarget(ctx, userID, targetID); err != nil {
+		return err
+	}
+	existing, err := s.blocks.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, b := range existing {
+		if b.TargetID == targetID && b.Kind == models.BlockKindBlock {
+			return ConflictError("resident is blocked; unblock them first", nil)
+		}
+	}
+	return s.blocks.Upsert(ctx, &models.Block{UserID: userID, TargetID: targetID, Kind: models.BlockKindMute})
+}
+
+// Unblock removes a block. Removing one that does not exist is not an error.
+func (s *ProfileService) Unblock(ctx context.Context, userID, targetID uuid.UUID) error {
+	_, err := s.blocks.Delete(ctx, userID, targetID, models.BlockKindBlock)
+	return err
+}
+
+// Unmute removes a mute. Removing one that does not exist is not an error.
+func (s *ProfileService) Unmute(ctx context.Context, userID, targetID uuid.UUID) error {
+	_, err := s.blocks.Delete(ctx, userID, targetID, models.BlockKindMute)
+	return err
+}
+
+// blockTarget loads the resident to block or mute.
+func (s *ProfileService) blockTarget(ctx context.Context, userID, targetID uuid.UUID) (*models.User, error) {
+	if userID == targetID {
+		return nil, FieldError("user_id", "cannot block or mute yourself")
+	}
+	target, err := s.users.GetByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+	return target, nil
+}
@@ -3,17 +3,45 @@ package services
 import (
 	"context"
 	"errors"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/cameronsralla/culdechat/models"
 	"github.com/google/uuid"
 )
 
-type ReactionService struct{}
+type ReactionService struct {
+	reactions models.ReactionRepo
+	types     models.ReactionTypeRepo
+	posts     models.PostRepo
+	comments  models.CommentRepo
+	users     models.UserRepo
+	access    *BoardAccess
+	audit     *AuditService
+}
+
+// NewReactionService returns a ReactionService backed by the given repositories.
+func NewReactionService(reactions models.ReactionRepo, types models.ReactionTypeRepo, posts models.PostRepo, comments models.CommentRepo, users models.UserRepo, access *BoardAccess, audit *AuditService) *ReactionService {
+	return &ReactionService{reactions: reactions, types: types, posts: posts, comments: comments, users: users, access: access, audit: audit}
+}
 
+// ReactInput reacts to exactly one post or comment with a shortcode from the catalog.
 type ReactInput struct {
-	PostID string `json:"post_id"`
-	Type   string `json:"type"`
+	PostID    string `json:"post_id" validate:"omitempty,uuid"`
+	CommentID string `json:"comment_id" validate:"omitempty,uuid"`
+	Type      string `json:"type" validate:"required,max=32"`
+}
+
+// ListReactionsInput optionally narrows a reaction list to one type.
+type ListReactionsInput struct {
+	Type string `form:"type" json:"type" validate:"omitempty,max=32"`
+}
+
+// ReactionTypeInput adds an entry to the reaction catalog.
+type ReactionTypeInput struct {
+	Shortcode string `json:"shortcode" validate:"required,shortcode"`
+	Emoji     string `json:"emoji" validate:"required,max=32"`
 }
 
 type ReactionCountDTO struct {
@@ -21,38 +49,92 @@ type ReactionCountDTO struct {
 	Count int64  `json:"count"`
 }
 
+// ReactionDTO records who reacted with what.
+type ReactionDTO struct {
+	UserID    string    `json:"user_id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReactionTypeDTO struct {
+	Shortcode string `json:"shortcode"`
+	Emoji     string `json:"emoji"`
+}
+
 func (s *ReactionService) Upsert(ctx context.Context, userID uuid.UUID, in ReactInput) error {
 	in.Type = strings.TrimSpace(strings.ToLower(in.Type))
-	if in.PostID == "" || in.Type == "" {
-		return errors.New("post_id and type are required")
+	if err := validateInput(in); err != nil {
+		return err
+	}
+	targetType, raw, field := models.ReactionOnPost, in.PostID, "post_id"
+	switch {
+	case in.PostID != "" && in.CommentID != "":
+		return FieldError("comment_id", "cannot be combined with post_id")
+	case in.CommentID != "":
+		targetType, raw, field = models.ReactionOnComment, in.CommentID, "comment_id"
+	case in.PostID == "":
+		return FieldError("post_id", "post_id or comment_id is required")
 	}
-	postUUID, err := uuid.Parse(in.PostID)
+	targetID, err := uuid.Parse(raw)
 	if err != nil {
-		return errors.New("invalid post_id")
+		return FieldError(field, "must be a valid UUID")
+	}
+	if err := s.checkType(ctx, in.Type); err != nil {
+		return err
 	}
-	if err := models.EnsureReactionsTable(ctx); err != nil {
+	boardID, err := s.targetBoard(ctx, targetType, targetID)
+	if err != nil {
 		return err
 	}
-	r := &models.Reaction{PostID: postUUID, UserID: userID, Type: in.Type}
-	return models.UpsertReaction(ctx, r)
+	if _, _, err := s.access.interact(ctx, boardID, userID); err != nil {
+		return err
+	}
+	r := &models.Reaction{TargetType: targetType, TargetID: targetID, UserID: userID, Type: in.Type}
+	if err := s.reactions.Upsert(ctx, r); err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			return NotFoundError(targetType + " not found")
+		}
+		return err
+	}
+	return nil
 }
 
+// Remove deletes the caller's reaction on a post.
 func (s *ReactionService) Remove(ctx context.Context, userID uuid.UUID, postIDStr string) error {
-	postUUID, err := uuid.Parse(postIDStr)
+	return s.remove(ctx, userID, models.ReactionOnPost, postIDStr, "post_id")
+}
+
+// RemoveFromComment deletes the caller's reaction on a comment.
+func (s *ReactionService) RemoveFromComment(ctx context.Context, userID uuid.UUID, commentIDStr string) error {
+	return s.remove(ctx, userID, models.ReactionOnComment, commentIDStr, "comment_id")
+}
+
+func (s *ReactionService) remove(ctx context.Context, userID uuid.UUID, targetType, raw, field string) error {
+	targetID, err := uuid.Parse(raw)
+	if err != nil {
+		return FieldError(field, "must be a valid UUID")
+	}
+	boardID, err := s.targetBoard(ctx, targetType, targetID)
 	if err != nil {
-		return errors.New("invalid post_id")
+		return err
 	}
-	if err := models.EnsureReactionsTable(ctx); err != nil {
+	if _, _, err := s.access.view(ctx, boardID, &userID); err != nil {
 		return err
 	}
-	return models.RemoveReaction(ctx, postUUID, userID)
+	return s.reactions.Remove(ctx, targetType, targetID, userID)
 }
 
-func (s *ReactionService) CountByPost(ctx context.Context, postID uuid.UUID) ([]ReactionCountDTO, error) {
-	if err := models.EnsureReactionsTable(ctx); err != nil {
+// CountByPost tallies a post's reactions for viewerID, which is nil for
+// anonymous callers.
+func (s *ReactionService) CountByPost(ctx context.Context, postID uuid.UUID, viewerID *uuid.UUID) ([]ReactionCountDTO, error) {
+	post, err := getPost(ctx, s.posts, postID)
+	if err != nil {
 		return nil, err
 	}
-	counts, err := models.CountReactionsByPost(ctx, postID)
+	if _, _, err := s.access.view(ctx, post.BoardID, viewerID); err != nil {
+		return nil, err
+	}
+	counts, err := s.reactions.Count(ctx, models.ReactionOnPost, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -62,3 +144,130 @@ func (s *ReactionService) CountByPost(ctx context.Context, postID uuid.UUID) ([]
 	}
 	return out, nil
 }
+
+// ListByPost returns who reacted to a post with what, oldest first.
+func (s *ReactionService) ListByPost(ctx context.Context, postID uuid.UUID, viewerID *uuid.UUID, in ListReactionsInput) ([]ReactionDTO, error) {
+	return s.list(ctx, models.ReactionOnPost, postID, viewerID, in)
+}
+
+// ListByComment returns who reacted to a comment with what, oldest first.
+func (s *ReactionService) ListByComment(ctx context.Context, commentID uuid.UUID, viewerID *uuid.UUID, in ListReactionsInput) ([]ReactionDTO, error) {
+	return s.list(ctx, models.ReactionOnComment, commentID, viewerID, in)
+}
+
+func (s *ReactionService) list(ctx context.Context, targetType string, targetID uuid.UUID, viewerID *uuid.UUID, in ListReactionsInput) ([]ReactionDTO, error) {
+	in.Type = strings.TrimSpace(strings.ToLower(in.Type))
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	boardID, err := s.targetBoard(ctx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := s.access.view(ctx, boardID, viewerID); err != nil {
+		return nil, err
+	}
+	reactions, err := s.reactions.List(ctx, targetType, targetID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ReactionDTO, 0, len(reactions))
+	for _, r := range reactions {
+		if in.Type == "" || r.Type == in.Type {
+			out = append(out, ReactionDTO{UserID: r.UserID.String(), Type: r.Type, CreatedAt: r.CreatedAt})
+		}
+	}
+	return out, nil
+}
+
+// targetBoard loads a post or comment and returns the board it belongs to.
+// Hidden posts and comments are treated as missing.
+func (s *ReactionService) targetBoard(ctx context.Context, targetType string, id uuid.UUID) (uuid.UUID, error) {
+	if targetType == models.ReactionOnComment {
+		c, err := s.comments.GetByID(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if c == nil || c.HiddenAt != nil {
+			return uuid.Nil, NotFoundError("comment not found")
+		}
+		id = c.PostID
+	}
+	post, err := getPost(ctx, s.posts, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return post.BoardID, nil
+}
+
+// checkType rejects shortcodes missing from the catalog, listing the allowed ones.
+func (s *ReactionService) checkType(ctx context.Context, shortcode string) error {
+	types, err := s.types.List(ctx)
+	if err != nil {
+		return err
+	}
+	allowed := make([]string, 0, len(types))
+	for _, t := range types {
+		if t.Shortcode == shortcode {
+			return nil
+		}
+		allowed = append(allowed, t.Shortcode)
+	}
+	sort.Strings(allowed)
+	return FieldError("type", "must be one of: "+strings.Join(allowed, ", "))
+}
+
+// Catalog lists the reactions residents may use.
+func (s *ReactionService) Catalog(ctx context.Context) ([]ReactionTypeDTO, error) {
+	types, err := s.types.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ReactionTypeDTO, 0, len(types))
+	for _, t := range types {
+		out = append(out, ReactionTypeDTO{Shortcode: t.Shortcode, Emoji: t.Emoji})
+	}
+	return out, nil
+}
+
+// AddType adds a reaction to the catalog. The catalog is shared by every
+// community, so only operators may change it.
+func (s *ReactionService) AddType(ctx context.Context, adminID uuid.UUID, in ReactionTypeInput) (*ReactionTypeDTO, error) {
+	if err := requireOperator(ctx, s.users, adminID); err != nil {
+		return nil, err
+	}
+	in.Shortcode = strings.TrimSpace(strings.ToLower(in.Shortcode))
+	in.Emoji = strings.TrimSpace(in.Emoji)
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	rt := &models.ReactionType{Shortcode: in.Shortcode, Emoji: in.Emoji}
+	if err := s.types.Insert(ctx, rt); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, ConflictError("reaction already exists", map[string]string{"shortcode": "already taken"})
+		}
+		return nil, err
+	}
+	details := map[string]string{"shortcode": rt.Shortcode, "emoji": rt.Emoji}
+	if err := s.audit.Record(ctx, &adminID, auditReactionTypeCreate, "reaction_type", nil, details); err != nil {
+		return nil, err
+	}
+	return &ReactionTypeDTO{Shortcode: rt.Shortcode, Emoji: rt.Emoji}, nil
+}
+
+// RemoveType retires a reaction from the catalog. Existing reactions of that
+// type are kept and still counted, but it can no longer be used.
+func (s *ReactionService) RemoveType(ctx context.Context, adminID uuid.UUID, shortcode string) error {
+	if err := requireOperator(ctx, s.users, adminID); err != nil {
+		return err
+	}
+	shortcode = strings.ToLower(shortcode)
+	ok, err := s.types.Delete(ctx, shortcode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NotFoundError("reaction not found")
+	}
+	return s.audit.Record(ctx, &adminID, auditReactionTypeDelete, "reaction_type", nil, map[string]string{"shortcode": shortcode})
+}
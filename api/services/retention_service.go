@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+)
+
+// RetentionService purges accounts whose deletion grace period has passed
+// and data exports that can no longer be downloaded, and fails exports whose
+// build was lost.
+type RetentionService struct {
+	users   models.UserRepo
+	exports models.DataExportRepo
+	audit   *AuditService
+	cfg     config.RetentionConfig
+}
+
+// NewRetentionService returns a RetentionService backed by the given repositories.
+func NewRetentionService(users models.UserRepo, exports models.DataExportRepo, audit *AuditService, cfg config.RetentionConfig) *RetentionService {
+	return &RetentionService{users: users, exports: exports, audit: audit, cfg: cfg}
+}
+
+// Run purges due accounts and expired exports every configured interval
+// until ctx is cancelled. Failures are logged and retried on the next tick.
+func (s *RetentionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Purge(ctx, time.Now()); err != nil {
+			utils.Errorf("retention purge failed after %d accounts: %v", n, err)
+		}
+		if n, err := s.exports.FailStale(ctx, time.Now().Add(-s.cfg.ExportTimeout), exportTimedOut); err != nil {
+			utils.Errorf("failing stale data exports failed: %v", err)
+		} else if n > 0 {
+			utils.Infof("failed %d stale data exports", n)
+		}
+		if n, err := s.exports.DeleteExpired(ctx, time.Now()); err != nil {
+			utils.Errorf("pruning data exports failed: %v", err)
+		} else if n > 0 {
+			utils.Infof("pruned %d expired data exports", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge anonymizes or deletes, depending on the configured mode, every
+// account whose deletion was requested more than the grace period before now,
+// and returns how many it purged.
+// Accounts of every community are purged; each is audited in its own community.
+func (s *RetentionService) Purge(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.users.ListDeletionDue(models.AcrossCommunities(ctx), now.Add(-s.cfg.DeletionGrace))
+	if err != nil {
+		return 0, err
+	}
+	for i, u := range due {
+		userCtx := models.WithCommunity(ctx, u.CommunityID)
+		if s.cfg.Mode == config.RetentionDelete {
+			err = s.users.Delete(userCtx, u.ID)
+		} else {
+			err = s.users.Anonymize(userCtx, u.ID)
+		}
+		if err != nil {
+			return i, err
+		}
+		if err := s.audit.Record(userCtx, nil, auditAccountPurge, "user", &u.ID, map[string]string{"mode": s.cfg.Mode}); err != nil {
+			return i + 1, err
+		}
+		utils.Infof("account purged id=%s mode=%s", u.ID, s.cfg.Mode)
+	}
+	return len(due), nil
+}
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// maxRosterRows bounds a single roster import.
+const maxRosterRows = 5000
+
+// rosterColumns are the CSV columns a roster may have, in export order.
+var rosterColumns = []string{"building", "floor", "unit_number", "occupancy", "email", "auto_verify"}
+
+// RosterService keeps a community's unit registry and the roster of residents
+// admins have pre-approved, and verifies registrations against them.
+type RosterService struct {
+	units models.UnitRepo
+	users models.UserRepo
+	audit *AuditService
+}
+
+// NewRosterService returns a RosterService backed by the given repositories.
+func NewRosterService(units models.UnitRepo, users models.UserRepo, audit *AuditService) *RosterService {
+	return &RosterService{units: units, users: users, audit: audit}
+}
+
+// RosterImportDTO summarizes a roster import.
+type RosterImportDTO struct {
+	Rows         int `json:"rows"`
+	UnitsCreated int `json:"units_created"`
+	UnitsUpdated int `json:"units_updated"`
+	EmailsAdded  int `json:"emails_added"`
+}
+
+type UnitDTO struct {
+	ID           string   `json:"id"`
+	Building     string   `json:"building"`
+	Floor        *string  `json:"floor"`
+	UnitNumber   string   `json:"unit_number"`
+	Occupancy    string   `json:"occupancy"`
+	AutoVerify   bool     `json:"auto_verify"`
+	RosterEmails []string `json:"roster_emails"`
+}
+
+// VerifyResidentInput approves a flagged registration, optionally linking
+// the resident to a unit in the registry.
+type VerifyResidentInput struct {
+	UnitID string `json:"unit_id" validate:"omitempty,uuid"`
+}
+
+// rosterRow is one validated line of a roster CSV. autoVerify is nil when
+// the line leaves the unit's setting unchanged.
+type rosterRow struct {
+	unit       models.Unit
+	email      string
+	autoVerify *bool
+}
+
+// Import adds the units and emails in a roster CSV to the registry. Rows for
+// units already registered update their occupancy and any floor given, and emails
+// already on the roster move to the unit listed. Every row is validated before
+// anything is written, so a bad file changes nothing. Rows are then applied in
+// order without a transaction: if storage fails part way, the rows before it
+// stay applied and the error says how many, so re-importing the same file
+// finishes the job.
+func (s *RosterService) Import(ctx context.Context, actorID uuid.UUID, r io.Reader) (*RosterImportDTO, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	rows, err := parseRoster(r)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RosterImportDTO{Rows: len(rows)}
+	seen := map[string]bool{}
+	for i, row := range rows {
+		if err := s.importRow(ctx, row, seen, out); err != nil {
+			utils.Errorf("roster import stopped at line %d after %d of %d rows by=%s: %v", i+2, i, len(rows), actorID, err)
+			details := map[string]string{
+				"rows":          strconv.Itoa(out.Rows),
+				"rows_applied":  strconv.Itoa(i),
+				"units_created": strconv.Itoa(out.UnitsCreated),
+				"emails_added":  strconv.Itoa(out.EmailsAdded),
+			}
+			if err := s.audit.Record(ctx, &actorID, auditRosterImport, "roster", nil, details); err != nil {
+				return nil, err
+			}
+			return nil, &Error{
+				Code:    CodeInternal,
+				Message: fmt.Sprintf("roster import stopped after %d of %d rows; import the file again to finish", i, len(rows)),
+				Details: map[string]string{"rows_applied": strconv.Itoa(i), "line " + strconv.Itoa(i+2): "not applied"},
+			}
+		}
+	}
+
+	details := map[string]string{
+		"rows":          strconv.Itoa(out.Rows),
+		"units_created": strconv.Itoa(out.UnitsCreated),
+		"emails_added":  strconv.Itoa(out.EmailsAdded),
+	}
+	if err := s.audit.Record(ctx, &actorID, auditRosterImport, "roster", nil, details); err != nil {
+		return nil, err
+	}
+	utils.Infof("roster imported rows=%d units_created=%d emails_added=%d by=%s", out.Rows, out.UnitsCreated, out.EmailsAdded, actorID)
+	return out, nil
+}
+
+// importRow upserts one row's unit and roster email, counting the changes in
+// out. seen tracks units already counted by earlier rows.
+func (s *RosterService) importRow(ctx context.Context, row rosterRow, seen map[string]bool, out *RosterImportDTO) error {
+	key := row.unit.Building + "\x00" + strings.ToLower(row.unit.UnitNumber)
+	unit := row.unit
+	created, err := s.units.Upsert(ctx, &unit)
+	if err != nil {
+		return err
+	}
+	if !seen[key] {
+		if created {
+			out.UnitsCreated++
+		} else {
+			out.UnitsUpdated++
+		}
+	}
+	seen[key] = true
+	if row.autoVerify != nil && *row.autoVerify != unit.AutoVerify {
+		if err := s.units.SetAutoVerify(ctx, unit.ID, *row.autoVerify); err != nil {
+			return err
+		}
+	}
+	if row.email == "" {
+		return nil
+	}
+	added, err := s.units.AddRosterEmail(ctx, &models.RosterEntry{UnitID: unit.ID, Email: row.email})
+	if err != nil {
+		return err
+	}
+	if added {
+		out.EmailsAdded++
+	}
+	return nil
+}
+
+// parseRoster reads and validates a roster CSV. Problems are reported per
+// line, e.g. {"line 3": "unit_number is required"}.
+func parseRoster(r io.Reader) ([]rosterRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ValidationError("roster is empty", nil)
+	}
+	if err != nil {
+		return nil, ValidationError("roster is not valid CSV", map[string]string{"line 1": err.Error()})
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, col := range rosterColumns {
+			known = known || name == col
+		}
+		if !known {
+			return nil, ValidationError("roster has an unknown column", map[string]string{"line 1": fmt.Sprintf("unknown column %q", name)})
+		}
+		index[name] = i
+	}
+	if _, ok := index["unit_number"]; !ok {
+		return nil, ValidationError("roster needs a unit_number column", map[string]string{"line 1": "unit_number column is required"})
+	}
+
+	var rows []rosterRow
+	details := map[string]string{}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ValidationError("roster is not valid CSV", map[string]string{"line " + strconv.Itoa(line): err.Error()})
+		}
+		if len(rows) == maxRosterRows {
+			return nil, ValidationError(fmt.Sprintf("roster has more than %d rows", maxRosterRows), nil)
+		}
+		field := func(name string) string {
+			if i, ok := index[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row, problem := rosterRowFrom(field)
+		if problem != "" {
+			details["line "+strconv.Itoa(line)] = problem
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(details) > 0 {
+		return nil, ValidationError("roster has invalid rows", details)
+	}
+	if len(rows) == 0 {
+		return nil, ValidationError("roster is empty", nil)
+	}
+	return rows, nil
+}
+
+// rosterRowFrom validates one roster line and describes the first problem.
+func rosterRowFrom(field func(string) string) (rosterRow, string) {
+	row := rosterRow{
+		unit: models.Unit{
+			Building:   field("building"),
+			UnitNumber: field("unit_number"),
+			Occupancy:  strings.ToLower(field("occupancy")),
+		},
+		email: strings.ToLower(field("email")),
+	}
+	if floor := field("floor"); floor != "" {
+		row.unit.Floor = &floor
+	}
+	if row.unit.Occupancy == "" {
+		row.unit.Occupancy = models.OccupancyOccupied
+	}
+	if v := field("auto_verify"); v != "" {
+		b, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return row, "auto_verify must be true or false"
+		}
+		row.autoVerify = &b
+	}
+	switch {
+	case row.unit.UnitNumber == "":
+		return row, "unit_number is required"
+	case len(row.unit.UnitNumber) > 16:
+		return row, "unit_number must be at most 16 characters"
+	case len(row.unit.Building) > 64:
+		return row, "building must be at most 64 characters"
+	case row.unit.Floor != nil && len(*row.unit.Floor) > 16:
+		return row, "floor must be at most 16 characters"
+	case row.unit.Occupancy != models.OccupancyOccupied && row.unit.Occupancy != models.OccupancyVacant:
+		return row, "occupancy must be one of: occupied, vacant"
+	case row.email != "" && validate.Var(row.email, "email,max=254") != nil:
+		return row, "email must be a valid email address"
+	case row.email != "" && row.unit.Occupancy == models.OccupancyVacant:
+		return row, "a vacant unit cannot list a resident"
+	case row.autoVerify != nil && *row.autoVerify && row.unit.Occupancy == models.OccupancyVacant:
+		return row, "a vacant unit cannot auto-verify residents"
+	}
+	return row, ""
+}
+
+// Export writes the registry as a roster CSV that Import accepts: one row per
+// pre-approved email, plus one row for each unit without any. Every row
+// carries the unit's auto_verify setting.
+func (s *RosterService) Export(ctx context.Context, actorID uuid.UUID, w io.Writer) error {
+	units, err := s.ListUnits(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(rosterColumns); err != nil {
+		return err
+	}
+	for _, u := range units {
+		floor := ""
+		if u.Floor != nil {
+			floor = *u.Floor
+		}
+		emails := u.RosterEmails
+		if len(emails) == 0 {
+			emails = []string{""}
+		}
+		for _, email := range emails {
+			if err := cw.Write([]string{u.Building, floor, u.UnitNumber, u.Occupancy, email, strconv.FormatBool(u.AutoVerify)}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ListUnits returns the registry with each unit's pre-approved emails.
+func (s *RosterService) ListUnits(ctx context.Context, actorID uuid.UUID) ([]UnitDTO, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	units, err := s.units.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roster, err := s.units.ListRoster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	emails := map[uuid.UUID][]string{}
+	for _, e := range roster {
+		emails[e.UnitID] = append(emails[e.UnitID], e.Email)
+	}
+	out := make([]UnitDTO, 0, len(units))
+	for _, u := range units {
+		list := emails[u.ID]
+		if list == nil {
+			list = []string{}
+		}
+		out = append(out, UnitDTO{
+			ID:           u.ID.String(),
+			Building:     u.Building,
+			Floor:        u.Floor,
+			UnitNumber:   u.UnitNumber,
+			Occupancy:    u.Occupancy,
+			AutoVerify:   u.AutoVerify,
+			RosterEmails: list,
+		})
+	}
+	return out, nil
+}
+
+// ListForReview returns the registrations that did not match the roster, oldest first.
+func (s *RosterService) ListForReview(ctx context.Context, actorID uuid.UUID) ([]AccountDTO, error) {
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	users, err := s.users.ListForReview(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AccountDTO, 0, len(users))
+	for i := range users {
+		out = append(out, *toAccountDTO(&users[i]))
+	}
+	return out, nil
+}
+
+// Verify marks a resident as verified. Without a unit_id the resident is
+// linked to the registry unit matching their unit number, if exactly one does.
+func (s *RosterService) Verify(ctx context.Context, actorID, userID uuid.UUID, in VerifyResidentInput) (*AccountDTO, error) {
+	if err := validateInput(in); err != nil {
+		return nil, err
+	}
+	if err := requireAdmin(ctx, s.users, actorID); err != nil {
+		return nil, err
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.Status == models.UserDeleted {
+		return nil, ErrUserNotFound
+	}
+	if in.UnitID != "" {
+		unit, err := s.units.GetByID(ctx, uuid.MustParse(in.UnitID))
+		if err != nil {
+			return nil, err
+		}
+		if unit == nil {
+			return nil, FieldError("unit_id", "does not exist")
+		}
+		u.UnitID = &unit.ID
+	} else if u.UnitID == nil {
+		units, err := s.units.ListByNumber(ctx, u.UnitNumber)
+		if err != nil {
+			return nil, err
+		}
+		if len(units) == 1 {
+			u.UnitID = &units[0].ID
+		}
+	}
+	u.Verification, u.VerificationNote = models.VerificationVerified, nil
+	if err := s.users.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	details := map[string]string{}
+	if u.UnitID != nil {
+		details["unit_id"] = u.UnitID.String()
+	}
+	if err := s.audit.Record(ctx, &actorID, auditUserVerify, "user", &u.ID, details); err != nil {
+		return nil, err
+	}
+	utils.Infof("resident verified id=%s by=%s", u.ID, actorID)
+	return toAccountDTO(u), nil
+}
+
+// check decides a new registration's verification from the roster. A
+// community without a registry leaves residents unverified. Otherwise a
+// resident is verified when the roster lists their email for their unit, or
+// when their unit number matches a single occupied unit with no roster emails
+// that the admins marked auto_verify. Anything else is flagged for review
+// with a note for the admins.
+func (s *RosterService) check(ctx context.Context, u *models.User) error {
+	n, err := s.units.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		u.Verification = models.VerificationUnverified
+		return nil
+	}
+	verified := func(unitID uuid.UUID) {
+		u.Verification, u.VerificationNote, u.UnitID = models.VerificationVerified, nil, &unitID
+	}
+	flag := func(note string) {
+		u.Verification, u.VerificationNote = models.VerificationReview, &note
+	}
+
+	entry, err := s.units.GetRosterEntry(ctx, u.Email)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		unit, err := s.units.GetByID(ctx, entry.UnitID)
+		if err != nil {
+			return err
+		}
+		if unit != nil && strings.EqualFold(unit.UnitNumber, u.UnitNumber) {
+			verified(unit.ID)
+		} else if unit != nil {
+			flag("the roster lists this email for unit " + unit.UnitNumber)
+		} else {
+			flag("the roster entry for this email has no unit")
+		}
+		return nil
+	}
+
+	units, err := s.units.ListByNumber(ctx, u.UnitNumber)
+	if err != nil {
+		return err
+	}
+	if len(units) == 0 {
+		flag("unit is not in the registry")
+		return nil
+	}
+	roster, err := s.units.ListRoster(ctx)
+	if err != nil {
+		return err
+	}
+	listed := map[uuid.UUID]bool{}
+	for _, e := range roster {
+		listed[e.UnitID] = true
+	}
+	var open []models.Unit
+	occupied := false
+	for _, unit := range units {
+		if unit.Occupancy != models.OccupancyOccupied {
+			continue
+		}
+		occupied = true
+		if !listed[unit.ID] {
+			open = append(open, unit)
+		}
+	}
+	switch {
+	case len(open) > 1:
+		flag("unit number matches units in several buildings")
+	case len(open) == 1 && open[0].AutoVerify:
+		verified(open[0].ID)
+	case len(open) == 1:
+		flag("the roster lists no emails for this unit")
+	case occupied:
+		flag("email is not on the roster for this unit")
+	default:
+		flag("unit is listed as vacant")
+	}
+	return nil
+}
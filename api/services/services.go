@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/google/uuid"
+)
+
+// ErrUserNotFound is returned when an authenticated user no longer exists.
+var ErrUserNotFound = NotFoundError("user not found")
+
+// trimOptional trims an optional string in place, preserving nil.
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	return &v
+}
+
+// trimToNil trims an optional string and treats blank values as absent.
+func trimToNil(s *string) *string {
+	s = trimOptional(s)
+	if s != nil && *s == "" {
+		return nil
+	}
+	return s
+}
+
+// requireAdmin returns Forbidden unless id belongs to an admin.
+func requireAdmin(ctx context.Context, users models.UserRepo, id uuid.UUID) error {
+	u, err := users.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil || !u.IsAdmin {
+		return ForbiddenError("admin access required")
+	}
+	return nil
+}
+
+// requireOperator returns Forbidden unless id is an admin of the default
+// community. Operators manage what every community shares.
+func requireOperator(ctx context.Context, users models.UserRepo, id uuid.UUID) error {
+	if communityID, _ := models.CommunityScope(ctx); communityID != models.DefaultCommunityID {
+		return ForbiddenError("operator access required")
+	}
+	return requireAdmin(ctx, users, id)
+}
+
+// Services bundles every service used by the HTTP layer.
+type Services struct {
+	Auth        *AuthService
+	Communities *CommunityService
+	Invites     *InviteService
+	Roster      *RosterService
+	Households  *HouseholdService
+	Boards      *BoardService
+	Posts       *PostService
+	Comments    *CommentService
+	Reactions   *ReactionService
+	Profiles    *ProfileService
+	Moderation  *ModerationService
+	Accounts    *AccountService
+	Audit       *AuditService
+	Retention   *RetentionService
+	Exports     *ExportService
+}
+
+// New wires all services against the given repositories.
+func New(repos *models.Repos, tokens *utils.TokenIssuer, cfg *config.Config) *Services {
+	audit := NewAuditService(repos.Audit, repos.Users)
+	accounts := NewAccountService(repos.Users, repos.Accounts, audit, cfg.Retention.DeletionGrace)
+	access := NewBoardAccess(repos.Boards, repos.Members, repos.Users)
+	profiles := NewProfileService(repos.Users, repos.Warnings, repos.Blocks)
+	invites := NewInviteService(repos.Invites, repos.Users, audit, cfg.Registration)
+	roster := NewRosterService(repos.Units, repos.Users, audit)
+	communities := NewCommunityService(repos.Communities, repos.Users, accounts, invites, audit)
+	exports := NewExportService(repos.Exports, repos.Posts, repos.Comments, repos.Reactions, repos.Subscriptions, repos.Members, repos.Accounts, profiles, cfg.Retention.ExportTTL, cfg.Retention.ExportTimeout)
+	return &Services{
+		Auth:        NewAuthService(repos.Users, repos.Communities, invites, roster, exports, tokens, accounts, cfg.Lockout, cfg.Registration),
+		Communities: communities,
+		Invites:     invites,
+		Roster:      roster,
+		Households:  NewHouseholdService(repos.Households, repos.Users, repos.Units, invites, accounts, audit),
+		Boards:      NewBoardService(repos.Boards, repos.Members, repos.Subscriptions, repos.Posts, repos.Users, access, audit),
+		Posts:       NewPostService(repos.Posts, repos.Comments, repos.Reactions, repos.Users, access, audit),
+		Comments:    NewCommentService(repos.Comments, repos.Posts, repos.Users, access),
+		Reactions:   NewReactionService(repos.Reactions, repos.ReactionTypes, repos.Posts, repos.Comments, repos.Users, access, audit),
+		Profiles:    profiles,
+		Moderation:  NewModerationService(repos.Reports, repos.Posts, repos.Comments, repos.Users, repos.Warnings, accounts, audit, cfg.Moderation),
+		Accounts:    accounts,
+		Audit:       audit,
+		Retention:   NewRetentionService(repos.Users, repos.Exports, audit, cfg.Retention),
+		Exports:     exports,
+	}
+}
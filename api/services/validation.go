@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Password rules. bcrypt ignores input beyond 72 bytes, so longer passwords are rejected.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// validate checks the `validate` struct tags declared on service inputs.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by their JSON names so details match the request body.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	if err := v.RegisterValidation("password", validatePassword); err != nil {
+		panic(err)
+	}
+	if err := v.RegisterValidation("shortcode", validateShortcode); err != nil {
+		panic(err)
+	}
+	if err := v.RegisterValidation("slug", validateSlug); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// maxShortcodeLength bounds reaction shortcodes such as "thumbs_up".
+const maxShortcodeLength = 32
+
+// validateShortcode allows lowercase letters, digits and underscores.
+func validateShortcode(fl validator.FieldLevel) bool {
+	code := fl.Field().String()
+	if code == "" || len(code) > maxShortcodeLength {
+		return false
+	}
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// maxSlugLength bounds community slugs such as "maple-court".
+const maxSlugLength = 64
+
+// validateSlug allows lowercase letters, digits and inner hyphens.
+func validateSlug(fl validator.FieldLevel) bool {
+	slug := fl.Field().String()
+	if slug == "" || len(slug) > maxSlugLength || slug[0] == '-' || slug[len(slug)-1] == '-' {
+		return false
+	}
+	for _, r := range slug {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// validatePassword requires a length within bcrypt's limits and at least one letter and one digit.
+func validatePassword(fl validator.FieldLevel) bool {
+	pw := fl.Field().String()
+	if len(pw) < minPasswordLength || len(pw) > maxPasswordLength {
+		return false
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
+// validateInput runs declarative validation on an input struct and returns a
+// ValidationError with one entry per offending field.
+func validateInput(in any) error {
+	err := validate.Struct(in)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	details := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		field := fieldPath(fe)
+		if _, seen := details[field]; !seen {
+			details[field] = describeFieldError(fe)
+		}
+	}
+	return ValidationError("request validation failed", details)
+}
+
+// fieldPath names the field by its JSON path without the input struct, e.g.
+// "privacy.unit" for a nested field.
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func describeFieldError(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		switch fe.Kind() {
+		case reflect.Slice:
+			return fmt.Sprintf("must have at most %s items", fe.Param())
+		case reflect.Int:
+			return fmt.Sprintf("must be at most %s", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "min":
+		switch fe.Kind() {
+		case reflect.Slice:
+			return fmt.Sprintf("must have at least %s items", fe.Param())
+		case reflect.Int:
+			return fmt.Sprintf("must be at least %s", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "uuid":
+		return "must be a valid UUID"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "datetime":
+		return "must be a date (YYYY-MM-DD)"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "shortcode":
+		return fmt.Sprintf("must be 1-%d lowercase letters, digits or underscores", maxShortcodeLength)
+	case "slug":
+		return fmt.Sprintf("must be 1-%d lowercase letters, digits or inner hyphens", maxSlugLength)
+	case "password":
+		return fmt.Sprintf("must be %d-%d characters and include a letter and a number", minPasswordLength, maxPasswordLength)
+	default:
+		return "is invalid"
+	}
+}
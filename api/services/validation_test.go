@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateInputReportsFieldsByJSONName(t *testing.T) {
+	err := validateInput(CreatePostInput{BoardID: "nope", Title: strings.Repeat("x", 201)})
+	var verr *Error
+	if !errors.As(err, &verr) || verr.Code != CodeValidation {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	want := map[string]string{
+		"board_id": "must be a valid UUID",
+		"title":    "must be at most 200 characters",
+		"content":  "is required",
+	}
+	for field, msg := range want {
+		if verr.Details[field] != msg {
+			t.Errorf("details[%q] = %q, want %q", field, verr.Details[field], msg)
+		}
+	}
+}
+
+func TestPasswordRule(t *testing.T) {
+	cases := map[string]bool{
+		"abc12345":               true,
+		"pässwörd1":              true,
+		"short1":                 false,
+		"lettersonly":            false,
+		"123456789":              false,
+		strings.Repeat("a1", 37): false, // 74 bytes exceeds bcrypt's limit
+	}
+	for pw, ok := range cases {
+		err := validateInput(RegisterInput{Email: "a@example.com", UnitNumber: "1", Password: pw})
+		if (err == nil) != ok {
+			t.Errorf("password %q: err = %v, want valid=%v", pw, err, ok)
+		}
+	}
+}
+
+func TestShortcodeRule(t *testing.T) {
+	cases := map[string]bool{
+		"like":                  true,
+		"thumbs_up":             true,
+		"party2":                true,
+		"Thumbs":                false,
+		"thumbs-up":             false,
+		"":                      false,
+		strings.Repeat("x", 33): false,
+		"🎉":                     false,
+	}
+	for code, ok := range cases {
+		err := validateInput(ReactionTypeInput{Shortcode: code, Emoji: "🎉"})
+		if (err == nil) != ok {
+			t.Errorf("shortcode %q: err = %v, want valid=%v", code, err, ok)
+		}
+	}
+}
+
+func TestSlugRule(t *testing.T) {
+	cases := map[string]bool{
+		"maple-court":           true,
+		"tower2":                true,
+		"Maple":                 false,
+		"-maple":                false,
+		"maple-":                false,
+		"maple_court":           false,
+		strings.Repeat("x", 65): false,
+	}
+	for slug, ok := range cases {
+		err := validateInput(CreateCommunityInput{Slug: slug, Name: "Maple Court"})
+		if (err == nil) != ok {
+			t.Errorf("slug %q: err = %v, want valid=%v", slug, err, ok)
+		}
+	}
+}
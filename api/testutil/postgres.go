@@ -0,0 +1,150 @@
+// Package testutil provides the integration test harness: a disposable Postgres
+// database and an httptest server wired exactly like cmd/main.
+package testutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewDatabase returns a pool connected to a freshly created, fully migrated
+// database that is dropped when the test finishes.
+//
+// The database comes from, in order of preference:
+//   - TEST_DATABASE_URL: a server the test may create and drop databases on;
+//   - a throwaway cluster started with initdb/pg_ctl found in PG_BIN or PATH.
+//
+// The test is skipped when neither is available.
+func NewDatabase(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	serverURL := os.Getenv("TEST_DATABASE_URL")
+	if serverURL == "" {
+		serverURL = startCluster(t)
+	}
+
+	name := "culdechat_test_" + randomSuffix(t)
+	admin, err := pgx.Connect(ctx, serverURL)
+	if err != nil {
+		t.Fatalf("connect to test server: %v", err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		t.Fatalf("create test database: %v", err)
+	}
+
+	dbURL, err := withDatabase(serverURL, name)
+	if err != nil {
+		t.Fatalf("build test database url: %v", err)
+	}
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		conn, err := pgx.Connect(context.Background(), serverURL)
+		if err != nil {
+			t.Logf("drop test database: %v", err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)"); err != nil {
+			t.Logf("drop test database: %v", err)
+		}
+	})
+
+	if err := models.EnsureSchema(ctx, pool); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return pool
+}
+
+// startCluster initialises and starts a private Postgres cluster in a temp
+// directory and returns a URL for its superuser.
+func startCluster(t testing.TB) string {
+	t.Helper()
+	initdb, pgctl := lookupPgBinary("initdb"), lookupPgBinary("pg_ctl")
+	if initdb == "" || pgctl == "" {
+		t.Skip("no Postgres available: set TEST_DATABASE_URL or put initdb/pg_ctl on PATH (or PG_BIN)")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("initdb refuses to run as root: set TEST_DATABASE_URL to run integration tests")
+	}
+
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput()
+	if err != nil {
+		t.Fatalf("initdb: %v\n%s", err, out)
+	}
+
+	port := freePort(t)
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -F", port, dir)
+	logFile := filepath.Join(dir, "postgres.log")
+	out, err = exec.Command(pgctl, "-D", dataDir, "-o", opts, "-l", logFile, "-w", "-t", "30", "start").CombinedOutput()
+	if err != nil {
+		logs, _ := os.ReadFile(logFile)
+		t.Fatalf("pg_ctl start: %v\n%s\n%s", err, out, logs)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command(pgctl, "-D", dataDir, "-m", "immediate", "-w", "stop").Run()
+	})
+
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable&connect_timeout=10", port)
+}
+
+func lookupPgBinary(name string) string {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		candidate := filepath.Join(dir, name)
+		if fi, err := os.Stat(candidate); err == nil && !fi.IsDir() {
+			return candidate
+		}
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func freePort(t testing.TB) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func randomSuffix(t testing.TB) string {
+	t.Helper()
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("random suffix: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func withDatabase(serverURL, name string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
@@ -0,0 +1,221 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cameronsralla/culdechat/config"
+	"github.com/cameronsralla/culdechat/middleware"
+	"github.com/cameronsralla/culdechat/models"
+	"github.com/cameronsralla/culdechat/routes"
+	"github.com/cameronsralla/culdechat/services"
+	"github.com/cameronsralla/culdechat/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Server is a running API backed by the given repositories.
+type Server struct {
+	*httptest.Server
+	Config   *config.Config
+	Repos    *models.Repos
+	Services *services.Services
+
+	adminToken string
+	inviteCode string
+}
+
+// adminEmail is the shared admin used by AdminToken. Servers built on the same
+// repositories reuse the account.
+const adminEmail = "testutil.admin@example.com"
+
+// Response is a decoded API response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// JSON decodes the response body into v, failing the test on error.
+func (r *Response) JSON(t testing.TB, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decode response %s: %v", r.Body, err)
+	}
+}
+
+// Error decodes the response as an error envelope, failing the test unless
+// it carries the expected code and a request id.
+func (r *Response) Error(t testing.TB, code services.ErrorCode) middleware.ErrorResponse {
+	t.Helper()
+	var out middleware.ErrorResponse
+	r.JSON(t, &out)
+	if out.Code != code {
+		t.Fatalf("error code = %q, want %q: %s", out.Code, code, r.Body)
+	}
+	if out.RequestID == "" || out.Message == "" {
+		t.Fatalf("error envelope missing message or request_id: %s", r.Body)
+	}
+	return out
+}
+
+// Config returns a development configuration suitable for tests. Rate limits
+// are generous because every test request comes from the same address.
+func Config() *config.Config {
+	cfg := config.Default()
+	cfg.Env = config.EnvDevelopment
+	cfg.JWT.Secret = "test-secret-test-secret-test-secret"
+	roomy := config.RateLimitPolicy{Requests: 1000, Window: time.Minute}
+	cfg.RateLimit.Login, cfg.RateLimit.Register = roomy, roomy
+	cfg.RateLimit.Posts, cfg.RateLimit.Comments = roomy, roomy
+	return &cfg
+}
+
+// NewServer starts an httptest server wired like cmd/main against repos.
+func NewServer(t testing.TB, repos *models.Repos) *Server {
+	t.Helper()
+	return NewServerWithConfig(t, repos, Config())
+}
+
+// NewServerWithConfig is NewServer with a caller-supplied configuration.
+func NewServerWithConfig(t testing.TB, repos *models.Repos, cfg *config.Config) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	tokens := utils.NewTokenIssuer(cfg.JWT)
+	svcs := services.New(repos, tokens, cfg)
+	router := routes.NewRouter(routes.Deps{
+		Config:      cfg,
+		Tokens:      tokens,
+		Services:    svcs,
+		RateLimiter: middleware.NewRateLimiter(repos.RateLimits, cfg.RateLimit.Enabled),
+	})
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return &Server{Server: srv, Config: cfg, Repos: repos, Services: svcs}
+}
+
+// Do sends a request to path (relative to the server root) with an optional
+// bearer token and JSON body.
+func (s *Server) Do(t testing.TB, method, path, token string, body any) *Response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// Expect is Do plus a status assertion.
+func (s *Server) Expect(t testing.TB, status int, method, path, token string, body any) *Response {
+	t.Helper()
+	resp := s.Do(t, method, path, token, body)
+	if resp.Status != status {
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.Status, status, resp.Body)
+	}
+	return resp
+}
+
+// Register creates a resident of the default community through the API and
+// returns its id and token.
+func (s *Server) Register(t testing.TB, email, unit, password string) (id, token string) {
+	t.Helper()
+	var out services.AuthResponse
+	s.Expect(t, http.StatusCreated, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email": email, "unit_number": unit, "password": password, "invite_code": s.InviteCode(t),
+	}).JSON(t, &out)
+	return out.User.ID, out.Token
+}
+
+// InviteCode returns a code for a shared, practically unlimited invite into
+// the default community, creating it directly through the repositories on first use.
+func (s *Server) InviteCode(t testing.TB) string {
+	t.Helper()
+	if s.inviteCode != "" {
+		return s.inviteCode
+	}
+	invite := &models.Invite{Code: "TESTUTIL" + strings.ToUpper(randomSuffix(t)), MaxUses: 1 << 30}
+	if err := s.Repos.Invites.Insert(context.Background(), invite); err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+	s.inviteCode = invite.Code
+	return s.inviteCode
+}
+
+// AdminToken returns a token for a shared admin account, creating it on first use.
+func (s *Server) AdminToken(t testing.TB) string {
+	t.Helper()
+	if s.adminToken != "" {
+		return s.adminToken
+	}
+	existing, err := s.Repos.Users.GetByEmail(context.Background(), adminEmail)
+	if err != nil {
+		t.Fatalf("load admin: %v", err)
+	}
+	if existing == nil {
+		_, s.adminToken = s.Register(t, adminEmail, "000", "correct-horse-1")
+		s.MakeAdmin(t, adminEmail)
+		return s.adminToken
+	}
+	var out services.AuthResponse
+	s.Expect(t, http.StatusOK, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": adminEmail, "password": "correct-horse-1",
+	}).JSON(t, &out)
+	s.adminToken = out.Token
+	return s.adminToken
+}
+
+// MakeAdmin promotes an existing user directly through the repositories.
+func (s *Server) MakeAdmin(t testing.TB, email string) {
+	t.Helper()
+	s.setRoles(t, email, func(u *models.User) { u.IsAdmin = true })
+}
+
+// MakeModerator grants an existing user access to the report queue.
+func (s *Server) MakeModerator(t testing.TB, email string) {
+	t.Helper()
+	s.setRoles(t, email, func(u *models.User) { u.IsModerator = true })
+}
+
+func (s *Server) setRoles(t testing.TB, email string, change func(*models.User)) {
+	t.Helper()
+	ctx := context.Background()
+	u, err := s.Repos.Users.GetByEmail(ctx, email)
+	if err != nil || u == nil {
+		t.Fatalf("load user %s: %v", email, err)
+	}
+	change(u)
+	if err := s.Repos.Users.SetRoles(ctx, u.ID, u.IsAdmin, u.IsModerator); err != nil {
+		t.Fatalf("update user %s: %v", email, err)
+	}
+}
@@ -2,77 +2,66 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTConfig holds env-driven settings for JWTs.
+// JWTConfig holds settings for JWTs.
 type JWTConfig struct {
-	Secret         string
-	Issuer         string
-	AccessTokenTTL time.Duration
-}
-
-func getJWTConfig() JWTConfig {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// For local dev fallback, but warn via logs.
-		Warnf("JWT_SECRET not set; using insecure default for development")
-		secret = "dev-insecure-secret-change-me"
-	}
-	issuer := os.Getenv("JWT_ISSUER")
-	if issuer == "" {
-		issuer = "culdechat"
-	}
-	ttlSeconds := 3600 // 1 hour default
-	if v := os.Getenv("JWT_ACCESS_TTL_SECONDS"); v != "" {
-		// ignore parse errors silently, just use default
-		if d, err := time.ParseDuration(v + "s"); err == nil {
-			ttlSeconds = int(d.Seconds())
-		}
-	}
-	return JWTConfig{
-		Secret:         secret,
-		Issuer:         issuer,
-		AccessTokenTTL: time.Duration(ttlSeconds) * time.Second,
-	}
+	Secret         string        `yaml:"secret"`
+	Issuer         string        `yaml:"issuer"`
+	AccessTokenTTL time.Duration `yaml:"access_ttl"`
 }
 
 // Claims represents our JWT claims.
 type Claims struct {
 	UserID string `json:"uid"`
-	Unit   string `json:"unit"`
+	// CommunityID scopes every request made with the token; tokens issued
+	// before communities existed carry none and belong to the default one.
+	CommunityID string `json:"cid,omitempty"`
+	Unit        string `json:"unit"`
+	// Version must match the user's token_version; bumping it revokes outstanding tokens.
+	Version int `json:"ver"`
 	jwt.RegisteredClaims
 }
 
-// GenerateAccessToken creates a signed JWT for the given user ID and unit number.
-func GenerateAccessToken(userID string, unit string) (string, error) {
-	cfg := getJWTConfig()
+// TokenIssuer signs and validates access tokens with a fixed configuration.
+type TokenIssuer struct {
+	cfg JWTConfig
+}
+
+// NewTokenIssuer returns a TokenIssuer for the given configuration.
+func NewTokenIssuer(cfg JWTConfig) *TokenIssuer {
+	return &TokenIssuer{cfg: cfg}
+}
+
+// GenerateAccessToken creates a signed JWT for the given user ID, community ID, unit number and token version.
+func (t *TokenIssuer) GenerateAccessToken(userID, communityID, unit string, version int) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Unit:   unit,
+		UserID:      userID,
+		CommunityID: communityID,
+		Unit:        unit,
+		Version:     version,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    cfg.Issuer,
+			Issuer:    t.cfg.Issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.AccessTokenTTL)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.cfg.AccessTokenTTL)),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+	return token.SignedString([]byte(t.cfg.Secret))
 }
 
 // ParseAndValidateToken parses and validates a JWT string.
-func ParseAndValidateToken(tokenString string) (*Claims, error) {
-	cfg := getJWTConfig()
+func (t *TokenIssuer) ParseAndValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(cfg.Secret), nil
-	})
+		return []byte(t.cfg.Secret), nil
+	}, jwt.WithIssuer(t.cfg.Issuer))
 	if err != nil {
 		return nil, err
 	}
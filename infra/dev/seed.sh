@@ -2,6 +2,8 @@
 set -euo pipefail
 
 BASE=${BASE:-http://localhost:8080/api}
+# On a fresh database EMAIL must match the API's BOOTSTRAP_ADMIN_EMAIL, which
+# lets it register without an invite and become the first admin.
 EMAIL=${EMAIL:-seeduser@example.com}
 PASS=${PASS:-changeme123}
 UNIT=${UNIT:-101}
@@ -49,11 +51,28 @@ fi
 
 echo "Token acquired (len=${#token})"
 
+# Only admins may create boards, so make sure the seed user is one.
+admin_status=$(curl -sS -o /dev/null -w '%{http_code}' -H "Authorization: Bearer $token" "$BASE/admin/invites")
+if [ "$admin_status" != "200" ]; then
+  echo "ERROR: $EMAIL is not an admin (status $admin_status). Start the API with" >&2
+  echo "BOOTSTRAP_ADMIN_EMAIL=$EMAIL on a database without an admin, or run as an existing admin." >&2
+  exit 1
+fi
+
+create_board() {
+  local status
+  status=$(curl -sS -o /dev/null -w '%{http_code}' -X POST -H 'Content-Type: application/json' \
+    -H "Authorization: Bearer $token" "$BASE/boards" -d "{\"name\":\"$1\"}")
+  # 409 means the board already exists from an earlier run.
+  if [ "$status" != "201" ] && [ "$status" != "409" ]; then
+    echo "ERROR: Creating board $1 failed with status $status" >&2
+    exit 1
+  fi
+}
+
 echo "Creating boards (idempotent)..."
-curl -sS -X POST -H 'Content-Type: application/json' -H "Authorization: Bearer $token" \
-  "$BASE/boards" -d '{"name":"General"}' >/dev/null || true
-curl -sS -X POST -H 'Content-Type: application/json' -H "Authorization: Bearer $token" \
-  "$BASE/boards" -d '{"name":"For Sale"}' >/dev/null || true
+create_board "General"
+create_board "For Sale"
 
 boards=$(curl -sS "$BASE/boards")
 gen_id=$(printf '%s' "$boards" | python3 - <<'PY'